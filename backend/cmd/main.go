@@ -3,12 +3,15 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"raborimet-crm/backend/config"
 	"raborimet-crm/backend/routes"
+	"raborimet-crm/backend/services"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -17,6 +20,54 @@ func main() {
 	// Inicializar base de datos
 	config.InitDB()
 
+	// Crear los códigos de impuesto por defecto (IVA, exento, retenciones)
+	if err := services.NewTaxService().EnsureDefaultTaxCodes(config.DB); err != nil {
+		log.Printf("Error al crear códigos de impuesto por defecto: %v", err)
+	}
+
+	// Pasar una sola vez al costo real calculado a partir de materiales, horas y gastos
+	var recalculated int64
+	applied, err := config.RunDataMigration("project_actual_cost_rollup", func(tx *gorm.DB) error {
+		var err error
+		recalculated, err = services.NewProjectCostService().MigrateLegacyCosts(tx)
+		return err
+	})
+	if err != nil {
+		log.Printf("Error al recalcular el costo real de los proyectos: %v", err)
+	} else if applied {
+		log.Printf("Costo real recalculado para %d proyectos", recalculated)
+	}
+
+	// Iniciar tareas programadas
+	invoiceService := services.NewInvoiceService()
+	recurringInvoiceService := services.NewRecurringInvoiceService()
+	quoteService := services.NewQuoteService()
+	scheduler := services.NewScheduler()
+	scheduler.AddJob("facturas vencidas", services.GetDurationEnv("OVERDUE_CHECK_INTERVAL", time.Hour), func() error {
+		count, err := invoiceService.MarkOverdueInvoices()
+		if count > 0 {
+			log.Printf("%d facturas marcadas como vencidas", count)
+		}
+		return err
+	})
+	scheduler.AddJob("facturas recurrentes", services.GetDurationEnv("RECURRING_INVOICE_INTERVAL", time.Hour), func() error {
+		count, err := recurringInvoiceService.GenerateDueInvoices(time.Now())
+		if count > 0 {
+			log.Printf("%d facturas recurrentes generadas", count)
+		}
+		return err
+	})
+	scheduler.AddJob("cotizaciones vencidas", services.GetDurationEnv("QUOTE_EXPIRY_INTERVAL", time.Hour), func() error {
+		count, err := quoteService.ExpireQuotes(time.Now())
+		if count > 0 {
+			log.Printf("%d cotizaciones marcadas como vencidas", count)
+		}
+		return err
+	})
+
+	scheduler.Start()
+	defer scheduler.Stop()
+
 	// Configurar Gin
 	if os.Getenv("GIN_MODE") == "production" {
 		gin.SetMode(gin.ReleaseMode)
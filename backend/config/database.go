@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -36,16 +37,120 @@ func runMigrations() {
 		&models.Project{},
 		&models.Quote{},
 		&models.QuoteItem{},
+		&models.QuoteVersion{},
+		&models.QuoteApproval{},
+		&models.QuoteTemplate{},
+		&models.QuoteTemplateItem{},
 		&models.Invoice{},
 		&models.InvoiceItem{},
+		&models.Payment{},
+		&models.CreditNote{},
+		&models.CreditNoteItem{},
+		&models.RecurringInvoice{},
+		&models.RecurringInvoiceItem{},
 		&models.Material{},
+		&models.LaborRate{},
+		&models.TaxCode{},
+		&models.ExchangeRate{},
 		&models.ProjectMaterial{},
+		&models.StockMovement{},
 		&models.WorkLog{},
+		&models.ProjectExpense{},
+		&models.DocumentSequence{},
+		&models.DataMigration{},
 	)
 	if err != nil {
 		log.Fatal("Error en las migraciones:", err)
 	}
 	log.Println("Migraciones ejecutadas correctamente")
+
+	backfillStockMovements()
+	backfillUsedMaterialCosts()
+	backfillFixedDiscounts()
+}
+
+// backfillStockMovements registra el saldo inicial de los materiales que tienen stock
+// pero aún no tienen movimientos, para que el historial cuadre con la existencia
+func backfillStockMovements() {
+	result := DB.Exec(`
+		INSERT INTO stock_movements (material_id, type, quantity, unit_cost, stock_after, reason, created_at)
+		SELECT m.id, ?, m.stock, m.unit_price, m.stock, ?, NOW()
+		FROM materials m
+		WHERE m.deleted_at IS NULL AND m.stock <> 0
+		AND NOT EXISTS (SELECT 1 FROM stock_movements sm WHERE sm.material_id = m.id)`,
+		models.StockMovementAdjust, "Saldo inicial")
+	if result.Error != nil {
+		log.Fatal("Error al registrar el saldo inicial de stock:", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Saldo inicial de stock registrado para %d materiales", result.RowsAffected)
+	}
+}
+
+// backfillUsedMaterialCosts recalcula el costo total de los materiales ya usados con la
+// cantidad usada; antes se guardaba siempre con la cantidad planificada. Los usados sin
+// cantidad registrada (anteriores a que se pudiera cargar) toman la planificada, como
+// al marcarlos usados
+func backfillUsedMaterialCosts() {
+	result := DB.Exec(`
+		UPDATE project_materials SET quantity_used = quantity_planned
+		WHERE status = ? AND quantity_used = 0 AND quantity_planned > 0`, "used")
+	if result.Error != nil {
+		log.Fatal("Error al completar la cantidad usada de los materiales:", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Cantidad usada completada para %d materiales usados", result.RowsAffected)
+	}
+
+	result = DB.Exec(`
+		UPDATE project_materials SET total_cost = ROUND(quantity_used * unit_price, 2)
+		WHERE status = ? AND quantity_used > 0 AND total_cost <> ROUND(quantity_used * unit_price, 2)`, "used")
+	if result.Error != nil {
+		log.Fatal("Error al recalcular el costo de los materiales usados:", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		log.Printf("Costo recalculado para %d materiales usados", result.RowsAffected)
+	}
+}
+
+// backfillFixedDiscounts copia el descuento fijo de los documentos y líneas anteriores a
+// guardar aparte el importe pedido (discount_fixed) del aplicado (discount)
+func backfillFixedDiscounts() {
+	for _, table := range []string{"quotes", "quote_items", "invoices", "invoice_items"} {
+		result := DB.Exec(`UPDATE `+table+` SET discount_fixed = discount
+			WHERE discount_type = ? AND discount_fixed = 0 AND discount <> 0`, models.DiscountTypeFixed)
+		if result.Error != nil {
+			log.Fatal("Error al completar el descuento fijo pedido:", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			log.Printf("Descuento fijo pedido completado en %d registros de %s", result.RowsAffected, table)
+		}
+	}
+}
+
+// RunDataMigration ejecuta fn una sola vez, dentro de una transacción que además registra
+// la migración con su nombre. Devuelve false si ya se había aplicado
+func RunDataMigration(name string, fn func(tx *gorm.DB) error) (bool, error) {
+	applied := false
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.DataMigration{}).Where("name = ?", name).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return nil
+		}
+		// El índice único sobre el nombre evita que dos instancias la apliquen a la vez
+		if err := tx.Create(&models.DataMigration{Name: name, AppliedAt: time.Now()}).Error; err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
 }
 
 func getEnv(key, defaultValue string) string {
@@ -0,0 +1,424 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type CreditNoteController struct {
+	numberingService *services.NumberingService
+	pricingService   *services.PricingService
+}
+
+func NewCreditNoteController() *CreditNoteController {
+	return &CreditNoteController{
+		numberingService: services.NewNumberingService(),
+		pricingService:   services.NewPricingService(),
+	}
+}
+
+// @Summary Obtener todas las notas de crédito
+// @Description Obtener lista de notas de crédito con paginación y filtros
+// @Tags credit-notes
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Param client_id query int false "Filtrar por cliente"
+// @Param invoice_id query int false "Filtrar por factura"
+// @Success 200 {object} map[string]interface{}
+// @Router /credit-notes [get]
+func (cnc *CreditNoteController) GetCreditNotes(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	clientID := c.Query("client_id")
+	invoiceID := c.Query("invoice_id")
+
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.CreditNote{}).Preload("Client").Preload("Invoice")
+
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	if invoiceID != "" {
+		query = query.Where("invoice_id = ?", invoiceID)
+	}
+
+	var creditNotes []models.CreditNote
+	var total int64
+
+	query.Count(&total)
+	query.Offset(offset).Limit(limit).Order("issue_date DESC, id DESC").Find(&creditNotes)
+
+	c.JSON(http.StatusOK, gin.H{
+		"credit_notes": creditNotes,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+	})
+}
+
+// @Summary Obtener nota de crédito por ID
+// @Description Obtener detalles de una nota de crédito específica
+// @Tags credit-notes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la nota de crédito"
+// @Success 200 {object} models.CreditNote
+// @Failure 404 {object} map[string]string
+// @Router /credit-notes/{id} [get]
+func (cnc *CreditNoteController) GetCreditNote(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var creditNote models.CreditNote
+	if err := config.DB.Preload("Client").Preload("Invoice").Preload("CreatedBy").Preload("Items").First(&creditNote, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Nota de crédito no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, creditNote)
+}
+
+// @Summary Emitir nota de crédito
+// @Description Emitir una nota de crédito que revierte total o parcialmente los items de una factura
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Param credit_note body models.CreateCreditNoteRequest true "Motivo e items a revertir (vacío para revertir todo)"
+// @Success 201 {object} models.CreditNote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/credit-notes [post]
+func (cnc *CreditNoteController) CreateCreditNote(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req models.CreateCreditNoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invoice models.Invoice
+	var creditNote *models.CreditNote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&invoice, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Factura no encontrada")
+		}
+
+		switch invoice.Status {
+		case "draft":
+			status = http.StatusBadRequest
+			return errors.New("Las facturas en borrador no admiten notas de crédito")
+		case "cancelled":
+			status = http.StatusBadRequest
+			return errors.New("La factura ya está cancelada")
+		}
+
+		var err error
+		creditNote, status, err = cnc.issueCreditNote(tx, &invoice, req.Reason, req.Items, userID.(uint), false)
+		if err != nil {
+			return err
+		}
+
+		if err := recalculateInvoiceBalance(tx, &invoice); err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al actualizar saldo de la factura")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Items").First(creditNote, creditNote.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Nota de crédito emitida exitosamente",
+		"credit_note": creditNote,
+		"invoice":     invoice,
+	})
+}
+
+// @Summary Obtener notas de crédito de una factura
+// @Description Obtener las notas de crédito emitidas sobre una factura
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Success 200 {object} map[string]interface{}
+// @Router /invoices/{id}/credit-notes [get]
+func (cnc *CreditNoteController) GetInvoiceCreditNotes(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var creditNotes []models.CreditNote
+	config.DB.Preload("Items").Preload("CreatedBy").Where("invoice_id = ?", uint(id)).Order("issue_date ASC, id ASC").Find(&creditNotes)
+
+	c.JSON(http.StatusOK, gin.H{"credit_notes": creditNotes})
+}
+
+// @Summary Cancelar factura
+// @Description Cancelar una factura emitida. Se emite una nota de crédito por el importe pendiente de acreditar
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Param cancel body models.CancelInvoiceRequest true "Motivo de la cancelación"
+// @Success 200 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/cancel [post]
+func (cnc *CreditNoteController) CancelInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req models.CancelInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invoice models.Invoice
+	var creditNote *models.CreditNote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&invoice, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Factura no encontrada")
+		}
+
+		switch invoice.Status {
+		case "draft":
+			status = http.StatusBadRequest
+			return errors.New("Las facturas en borrador se eliminan, no se cancelan")
+		case "cancelled":
+			status = http.StatusBadRequest
+			return errors.New("La factura ya está cancelada")
+		}
+
+		// Revertir todo lo que aún no haya sido acreditado. Si ya estaba todo
+		// acreditado, la factura se cancela sin emitir otra nota
+		var err error
+		creditNote, status, err = cnc.issueCreditNote(tx, &invoice, req.Reason, nil, userID.(uint), true)
+		if err != nil && status != http.StatusUnprocessableEntity {
+			return err
+		}
+
+		invoice.Status = "cancelled"
+		if err := tx.Model(&invoice).Update("status", "cancelled").Error; err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al cancelar factura")
+		}
+
+		if err := recalculateInvoiceBalance(tx, &invoice); err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al actualizar saldo de la factura")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Items").Preload("CreditNotes").First(&invoice, invoice.ID)
+
+	response := gin.H{
+		"message": "Factura cancelada exitosamente",
+		"invoice": invoice,
+	}
+	if creditNote != nil {
+		response["credit_note"] = creditNote
+	}
+	if invoice.Balance < 0 {
+		response["refund_due"] = -invoice.Balance
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// issueCreditNote emite una nota de crédito sobre la factura (bloqueada y con items cargados).
+// Si lines está vacío se acredita todo lo pendiente. Devuelve el código HTTP a usar en caso de error;
+// http.StatusUnprocessableEntity indica que no queda nada por acreditar.
+func (cnc *CreditNoteController) issueCreditNote(tx *gorm.DB, invoice *models.Invoice, reason string, lines []models.CreateCreditNoteItemRequest, userID uint, isCancellation bool) (*models.CreditNote, int, error) {
+	// Cantidades ya acreditadas por item
+	var credited []struct {
+		InvoiceItemID uint
+		Quantity      float64
+	}
+	err := tx.Table("credit_note_items").
+		Select("credit_note_items.invoice_item_id, COALESCE(SUM(credit_note_items.quantity), 0) AS quantity").
+		Joins("JOIN credit_notes ON credit_notes.id = credit_note_items.credit_note_id").
+		Where("credit_notes.invoice_id = ?", invoice.ID).
+		Group("credit_note_items.invoice_item_id").
+		Scan(&credited).Error
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Error al consultar notas de crédito previas")
+	}
+
+	remaining := make(map[uint]float64, len(invoice.Items))
+	itemsByID := make(map[uint]models.InvoiceItem, len(invoice.Items))
+	for _, item := range invoice.Items {
+		remaining[item.ID] = item.Quantity
+		itemsByID[item.ID] = item
+	}
+	for _, cr := range credited {
+		remaining[cr.InvoiceItemID] -= cr.Quantity
+	}
+
+	if len(lines) == 0 {
+		for _, item := range invoice.Items {
+			if remaining[item.ID] > 0.001 {
+				lines = append(lines, models.CreateCreditNoteItemRequest{InvoiceItemID: item.ID, Quantity: remaining[item.ID]})
+			}
+		}
+		if len(lines) == 0 {
+			return nil, http.StatusUnprocessableEntity, errors.New("La factura no tiene importes pendientes de acreditar")
+		}
+	}
+
+	items := make([]models.CreditNoteItem, 0, len(lines))
+	creditedByItem := make(map[uint]models.Money, len(lines))
+	var subtotal models.Money
+	for _, line := range lines {
+		invoiceItem, ok := itemsByID[line.InvoiceItemID]
+		if !ok {
+			return nil, http.StatusBadRequest, fmt.Errorf("El item %d no pertenece a la factura", line.InvoiceItemID)
+		}
+		if line.Quantity > remaining[line.InvoiceItemID]+0.001 {
+			return nil, http.StatusBadRequest, fmt.Errorf("La cantidad a acreditar de '%s' excede la cantidad pendiente (%.2f)", invoiceItem.Description, remaining[line.InvoiceItemID])
+		}
+		remaining[line.InvoiceItemID] -= line.Quantity
+
+		// Importe neto de la línea, con su descuento prorrateado por la cantidad acreditada
+		lineTotal := cnc.pricingService.CreditedLine(invoiceItem, line.Quantity)
+		items = append(items, models.CreditNoteItem{
+			InvoiceItemID: invoiceItem.ID,
+			Description:   invoiceItem.Description,
+			Quantity:      line.Quantity,
+			Unit:          invoiceItem.Unit,
+			UnitPrice:     invoiceItem.UnitPrice,
+			Total:         lineTotal,
+		})
+		creditedByItem[invoiceItem.ID] += lineTotal
+		subtotal += lineTotal
+	}
+
+	// Parte proporcional del descuento de la factura y de los impuestos facturados (del
+	// desglose guardado en la factura, no de los códigos vigentes)
+	invoiceTaxes := documentTaxLines(invoice.TaxBreakdown, invoice.TaxRate, invoice.Subtotal-invoice.Discount, invoice.TaxAmount)
+	discountAmount := cnc.pricingService.DiscountAmount(subtotal, models.DiscountTypeFixed, 0, invoice.Discount.MulRatio(subtotal, invoice.Subtotal))
+	breakdown := cnc.pricingService.CreditTaxes(tx, invoice, invoiceTaxes, creditedByItem)
+
+	// Si esta nota agota la factura, ajustar al remanente exacto para evitar diferencias de redondeo
+	fullyCredited := true
+	for _, qty := range remaining {
+		if qty > 0.001 {
+			fullyCredited = false
+			break
+		}
+	}
+	var taxAmount, withholdingAmount, total models.Money
+	if fullyCredited {
+		var previous []models.CreditNote
+		if err := tx.Select("subtotal", "tax_amount", "withholding_amount", "tax_breakdown", "discount", "total").
+			Where("invoice_id = ?", invoice.ID).Find(&previous).Error; err != nil {
+			return nil, http.StatusInternalServerError, errors.New("Error al consultar notas de crédito previas")
+		}
+
+		subtotal, discountAmount = invoice.Subtotal, invoice.Discount
+		taxAmount, withholdingAmount, total = invoice.TaxAmount, invoice.WithholdingAmount, invoice.Total
+		breakdown = invoiceTaxes
+		for _, note := range previous {
+			subtotal -= note.Subtotal
+			discountAmount -= note.Discount
+			taxAmount -= note.TaxAmount
+			withholdingAmount -= note.WithholdingAmount
+			total -= note.Total
+			breakdown = services.SubtractTaxBreakdown(breakdown, documentTaxLines(note.TaxBreakdown, invoice.TaxRate, note.Subtotal-note.Discount, note.TaxAmount))
+		}
+	} else {
+		for _, line := range breakdown {
+			switch line.Type {
+			case models.TaxTransferred:
+				taxAmount += line.Amount
+			case models.TaxWithheld:
+				withholdingAmount += line.Amount
+			}
+		}
+		total = subtotal - discountAmount + taxAmount - withholdingAmount
+	}
+
+	number, err := cnc.numberingService.Next(tx, services.SeriesCreditNote)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Error al generar número de nota de crédito")
+	}
+
+	creditNote := models.CreditNote{
+		CreditNoteNumber:  number,
+		InvoiceID:         invoice.ID,
+		ClientID:          invoice.ClientID,
+		Reason:            reason,
+		IssueDate:         time.Now(),
+		Subtotal:          subtotal,
+		TaxAmount:         taxAmount,
+		WithholdingAmount: withholdingAmount,
+		TaxBreakdown:      breakdown,
+		Discount:          discountAmount,
+		Total:             total,
+		IsCancellation:    isCancellation,
+		CreatedByID:       userID,
+		Items:             items,
+	}
+	if err := tx.Create(&creditNote).Error; err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Error al emitir nota de crédito")
+	}
+
+	return &creditNote, http.StatusCreated, nil
+}
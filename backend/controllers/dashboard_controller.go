@@ -9,12 +9,17 @@ import (
 	"github.com/gin-gonic/gin"
 	"raborimet-crm/backend/config"
 	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
 )
 
-type DashboardController struct{}
+type DashboardController struct {
+	exchangeRateService *services.ExchangeRateService
+}
 
 func NewDashboardController() *DashboardController {
-	return &DashboardController{}
+	return &DashboardController{
+		exchangeRateService: services.NewExchangeRateService(),
+	}
 }
 
 // @Summary Obtener estadísticas del dashboard
@@ -51,12 +56,12 @@ func (dc *DashboardController) GetDashboardStats(c *gin.Context) {
 
 	// Estadísticas de cotizaciones
 	var totalQuotes, pendingQuotes, acceptedQuotes int64
-	var quotesValue, acceptedValue float64
+	converter := dc.exchangeRateService.NewConverter(config.DB)
 	config.DB.Model(&models.Quote{}).Count(&totalQuotes)
 	config.DB.Model(&models.Quote{}).Where("status IN ?", []string{"draft", "sent"}).Count(&pendingQuotes)
 	config.DB.Model(&models.Quote{}).Where("status = ?", "accepted").Count(&acceptedQuotes)
-	config.DB.Model(&models.Quote{}).Select("COALESCE(SUM(total), 0)").Scan(&quotesValue)
-	config.DB.Model(&models.Quote{}).Where("status = ?", "accepted").Select("COALESCE(SUM(total), 0)").Scan(&acceptedValue)
+	quotesValue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{})).Float64()
+	acceptedValue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status = ?", "accepted")).Float64()
 
 	stats["quotes"] = map[string]interface{}{
 		"total":          totalQuotes,
@@ -64,13 +69,15 @@ func (dc *DashboardController) GetDashboardStats(c *gin.Context) {
 		"accepted":       acceptedQuotes,
 		"total_value":    quotesValue,
 		"accepted_value": acceptedValue,
+		"currency":       services.BaseCurrency(),
+		"missing_rates":  converter.Missing(),
 	}
 
 	// Estadísticas de materiales
 	var totalMaterials, lowStockMaterials int64
 	var inventoryValue float64
 	config.DB.Model(&models.Material{}).Where("is_active = true").Count(&totalMaterials)
-	config.DB.Model(&models.Material{}).Where("stock <= min_stock AND is_active = true").Count(&lowStockMaterials)
+	config.DB.Model(&models.Material{}).Where("stock - reserved_stock <= min_stock AND is_active = true").Count(&lowStockMaterials)
 	config.DB.Model(&models.Material{}).Select("COALESCE(SUM(stock * price), 0)").Scan(&inventoryValue)
 
 	stats["materials"] = map[string]interface{}{
@@ -164,7 +171,7 @@ func (dc *DashboardController) GetRecentActivity(c *gin.Context) {
 
 	// Materiales con stock bajo
 	var lowStockMaterials []models.Material
-	config.DB.Where("stock <= min_stock AND is_active = true").Limit(limit/4 + 1).Find(&lowStockMaterials)
+	config.DB.Where("stock - reserved_stock <= min_stock AND is_active = true").Limit(limit/4 + 1).Find(&lowStockMaterials)
 	for _, material := range lowStockMaterials {
 		activities = append(activities, map[string]interface{}{
 			"type":        "material",
@@ -213,7 +220,7 @@ func (dc *DashboardController) GetProjectsByStatus(c *gin.Context) {
 }
 
 // @Summary Obtener gráfico de ingresos mensuales
-// @Description Obtener datos de ingresos de los últimos 12 meses
+// @Description Obtener datos de ingresos de los últimos 12 meses en la moneda base. Las cotizaciones en otra moneda se convierten con el tipo de cambio vigente en su fecha; las que no tienen tipo de cambio se omiten y se informan en missing_rates
 // @Tags dashboard
 // @Produce json
 // @Security BearerAuth
@@ -222,14 +229,14 @@ func (dc *DashboardController) GetProjectsByStatus(c *gin.Context) {
 func (dc *DashboardController) GetMonthlyRevenue(c *gin.Context) {
 	now := time.Now()
 	monthlyData := []map[string]interface{}{}
+	converter := dc.exchangeRateService.NewConverter(config.DB)
 
 	for i := 11; i >= 0; i-- {
 		month := now.AddDate(0, -i, 0)
 		startOfMonth := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, month.Location())
 		endOfMonth := startOfMonth.AddDate(0, 1, -1)
 
-		var revenue float64
-		config.DB.Model(&models.Quote{}).Where("status = ? AND created_at BETWEEN ? AND ?", "accepted", startOfMonth, endOfMonth).Select("COALESCE(SUM(total), 0)").Scan(&revenue)
+		revenue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status = ? AND created_at BETWEEN ? AND ?", "accepted", startOfMonth, endOfMonth))
 
 		monthlyData = append(monthlyData, map[string]interface{}{
 			"month":   month.Format("2006-01"),
@@ -238,7 +245,9 @@ func (dc *DashboardController) GetMonthlyRevenue(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": monthlyData,
+		"currency":      services.BaseCurrency(),
+		"data":          monthlyData,
+		"missing_rates": converter.Missing(),
 	})
 }
 
@@ -298,13 +307,13 @@ func (dc *DashboardController) GetFinancialSummary(c *gin.Context) {
 	startOfLastMonth := startOfMonth.AddDate(0, -1, 0)
 	endOfLastMonth := startOfMonth.AddDate(0, 0, -1)
 
+	converter := dc.exchangeRateService.NewConverter(config.DB)
+
 	// Ingresos del mes actual
-	var currentMonthRevenue float64
-	config.DB.Model(&models.Quote{}).Where("status = ? AND created_at >= ?", "accepted", startOfMonth).Select("COALESCE(SUM(total), 0)").Scan(&currentMonthRevenue)
+	currentMonthRevenue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status = ? AND created_at >= ?", "accepted", startOfMonth)).Float64()
 
 	// Ingresos del mes pasado
-	var lastMonthRevenue float64
-	config.DB.Model(&models.Quote{}).Where("status = ? AND created_at BETWEEN ? AND ?", "accepted", startOfLastMonth, endOfLastMonth).Select("COALESCE(SUM(total), 0)").Scan(&lastMonthRevenue)
+	lastMonthRevenue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status = ? AND created_at BETWEEN ? AND ?", "accepted", startOfLastMonth, endOfLastMonth)).Float64()
 
 	// Calcular crecimiento
 	var growth float64
@@ -313,8 +322,7 @@ func (dc *DashboardController) GetFinancialSummary(c *gin.Context) {
 	}
 
 	// Cotizaciones pendientes
-	var pendingQuotesValue float64
-	config.DB.Model(&models.Quote{}).Where("status IN ?", []string{"draft", "sent"}).Select("COALESCE(SUM(total), 0)").Scan(&pendingQuotesValue)
+	pendingQuotesValue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status IN ?", []string{"draft", "sent"})).Float64()
 
 	// Valor del inventario
 	var inventoryValue float64
@@ -326,5 +334,7 @@ func (dc *DashboardController) GetFinancialSummary(c *gin.Context) {
 		"growth_percentage":     growth,
 		"pending_quotes_value":  pendingQuotesValue,
 		"inventory_value":       inventoryValue,
+		"currency":              services.BaseCurrency(),
+		"missing_rates":         converter.Missing(),
 	})
 }
\ No newline at end of file
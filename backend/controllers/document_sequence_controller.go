@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type DocumentSequenceController struct {
+	numberingService *services.NumberingService
+}
+
+func NewDocumentSequenceController() *DocumentSequenceController {
+	return &DocumentSequenceController{
+		numberingService: services.NewNumberingService(),
+	}
+}
+
+// @Summary Obtener series de numeración
+// @Description Obtener la configuración y el contador de cada serie de numeración de documentos
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/document-sequences [get]
+func (dc *DocumentSequenceController) GetDocumentSequences(c *gin.Context) {
+	if err := dc.numberingService.EnsureDefaultSeries(config.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener series de numeración"})
+		return
+	}
+
+	var sequences []models.DocumentSequence
+	config.DB.Order("code ASC").Find(&sequences)
+
+	c.JSON(http.StatusOK, gin.H{"document_sequences": sequences})
+}
+
+// @Summary Actualizar serie de numeración
+// @Description Actualizar prefijo, formato, relleno o periodo de reinicio de una serie. El contador no se modifica. El formato debe terminar en {SEQ} e incluir el año (y el mes) si la serie se reinicia cada año (o cada mes)
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Código de la serie (quote, project, invoice, credit_note)"
+// @Param sequence body models.UpdateDocumentSequenceRequest true "Configuración de la serie"
+// @Success 200 {object} models.DocumentSequence
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /admin/document-sequences/{code} [put]
+func (dc *DocumentSequenceController) UpdateDocumentSequence(c *gin.Context) {
+	var req models.UpdateDocumentSequenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := dc.numberingService.EnsureDefaultSeries(config.DB); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener series de numeración"})
+		return
+	}
+
+	var sequence models.DocumentSequence
+	if err := config.DB.Where("code = ?", c.Param("code")).First(&sequence).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Serie de numeración no encontrada"})
+		return
+	}
+
+	// Actualizar campos
+	if req.Name != nil {
+		sequence.Name = *req.Name
+	}
+	if req.Prefix != nil {
+		sequence.Prefix = *req.Prefix
+	}
+	if req.Format != nil {
+		sequence.Format = *req.Format
+	}
+	if req.Padding != nil {
+		sequence.Padding = *req.Padding
+	}
+	if req.ResetPeriod != nil && *req.ResetPeriod != sequence.ResetPeriod {
+		sequence.ResetPeriod = *req.ResetPeriod
+		// Forzar que el próximo número recalcule el periodo con la nueva regla
+		sequence.CurrentPeriod = ""
+	}
+	if req.Format != nil || req.ResetPeriod != nil {
+		if err := services.ValidateDocumentFormat(sequence.Format, sequence.ResetPeriod); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := config.DB.Model(&sequence).Select("name", "prefix", "format", "padding", "reset_period", "current_period").Updates(&sequence).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar serie de numeración"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Serie de numeración actualizada exitosamente",
+		"document_sequence": sequence,
+		"preview":           services.FormatDocumentNumber(&sequence, time.Now(), sequence.LastNumber+1),
+	})
+}
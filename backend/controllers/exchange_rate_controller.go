@@ -0,0 +1,254 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type ExchangeRateController struct {
+	exchangeRateService *services.ExchangeRateService
+}
+
+func NewExchangeRateController() *ExchangeRateController {
+	return &ExchangeRateController{
+		exchangeRateService: services.NewExchangeRateService(),
+	}
+}
+
+// @Summary Obtener tipos de cambio
+// @Description Obtener los tipos de cambio registrados a la moneda base, del más reciente al más antiguo
+// @Tags exchange-rates
+// @Produce json
+// @Security BearerAuth
+// @Param currency query string false "Filtrar por moneda (USD)"
+// @Param start_date query string false "Fecha desde (YYYY-MM-DD)"
+// @Param end_date query string false "Fecha hasta (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Router /exchange-rates [get]
+func (erc *ExchangeRateController) GetExchangeRates(c *gin.Context) {
+	query := config.DB.Model(&models.ExchangeRate{})
+	if currency := c.Query("currency"); currency != "" {
+		query = query.Where("currency = ?", services.NormalizeCurrency(currency))
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		if parsedDate, err := time.Parse("2006-01-02", startDate); err == nil {
+			query = query.Where("date >= ?", parsedDate)
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if parsedDate, err := time.Parse("2006-01-02", endDate); err == nil {
+			query = query.Where("date <= ?", parsedDate)
+		}
+	}
+
+	var rates []models.ExchangeRate
+	if err := query.Order("date DESC, currency ASC").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener tipos de cambio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"base_currency": services.BaseCurrency(),
+		"rates":         rates,
+	})
+}
+
+// @Summary Consultar tipo de cambio vigente
+// @Description Obtener el tipo de cambio de una moneda a la moneda base vigente en una fecha (el último registrado en o antes de ese día)
+// @Tags exchange-rates
+// @Produce json
+// @Security BearerAuth
+// @Param currency query string true "Moneda (USD)"
+// @Param date query string false "Fecha (YYYY-MM-DD), por defecto hoy"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /exchange-rates/effective [get]
+func (erc *ExchangeRateController) GetEffectiveRate(c *gin.Context) {
+	currency := services.NormalizeCurrency(c.Query("currency"))
+	date := time.Now()
+	if dateStr := c.Query("date"); dateStr != "" {
+		parsedDate, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha inválida"})
+			return
+		}
+		date = parsedDate
+	}
+
+	rate, err := erc.exchangeRateService.RateOn(config.DB, currency, date)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"currency":      currency,
+		"base_currency": services.BaseCurrency(),
+		"date":          date.Format("2006-01-02"),
+		"rate":          rate,
+	})
+}
+
+// @Summary Registrar tipo de cambio
+// @Description Registrar manualmente el tipo de cambio de una moneda a la moneda base para una fecha
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rate body models.CreateExchangeRateRequest true "Moneda, fecha y tipo de cambio"
+// @Success 201 {object} models.ExchangeRate
+// @Failure 400 {object} map[string]string
+// @Router /exchange-rates [post]
+func (erc *ExchangeRateController) CreateExchangeRate(c *gin.Context) {
+	var req models.CreateExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	currency := services.NormalizeCurrency(req.Currency)
+	if currency == services.BaseCurrency() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La moneda base no requiere tipo de cambio"})
+		return
+	}
+	date := time.Date(req.Date.Year(), req.Date.Month(), req.Date.Day(), 0, 0, 0, 0, time.UTC)
+
+	var existing models.ExchangeRate
+	if err := config.DB.Where("currency = ? AND date = ?", currency, date).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe un tipo de cambio para esta moneda y fecha"})
+		return
+	}
+
+	rate := models.ExchangeRate{
+		Currency: currency,
+		Date:     date,
+		Rate:     req.Rate,
+		Source:   "manual",
+	}
+
+	if err := config.DB.Create(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al registrar tipo de cambio"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tipo de cambio registrado exitosamente",
+		"rate":    rate,
+	})
+}
+
+// @Summary Importar tipos de cambio
+// @Description Importar tipos de cambio desde un archivo CSV con las columnas fecha (YYYY-MM-DD o DD/MM/YYYY), moneda y tipo de cambio. La primera fila puede ser un encabezado. Los tipos de cambio existentes para la misma moneda y fecha se actualizan
+// @Tags exchange-rates
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Archivo CSV"
+// @Success 200 {object} models.ExchangeRateImportResult
+// @Failure 400 {object} map[string]string
+// @Router /exchange-rates/import [post]
+func (erc *ExchangeRateController) ImportExchangeRates(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Debe adjuntar el archivo CSV en el campo file"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se pudo leer el archivo"})
+		return
+	}
+	defer file.Close()
+
+	result, err := erc.exchangeRateService.ImportCSV(config.DB, file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tipos de cambio importados",
+		"result":  result,
+	})
+}
+
+// @Summary Actualizar tipo de cambio
+// @Description Corregir el valor de un tipo de cambio registrado
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del tipo de cambio"
+// @Param rate body models.UpdateExchangeRateRequest true "Tipo de cambio"
+// @Success 200 {object} models.ExchangeRate
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /exchange-rates/{id} [put]
+func (erc *ExchangeRateController) UpdateExchangeRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rate models.ExchangeRate
+	if err := config.DB.First(&rate, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tipo de cambio no encontrado"})
+		return
+	}
+
+	rate.Rate = req.Rate
+	rate.Source = "manual"
+	if err := config.DB.Save(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar tipo de cambio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tipo de cambio actualizado exitosamente",
+		"rate":    rate,
+	})
+}
+
+// @Summary Eliminar tipo de cambio
+// @Description Eliminar un tipo de cambio registrado
+// @Tags exchange-rates
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del tipo de cambio"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /exchange-rates/{id} [delete]
+func (erc *ExchangeRateController) DeleteExchangeRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var rate models.ExchangeRate
+	if err := config.DB.First(&rate, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tipo de cambio no encontrado"})
+		return
+	}
+
+	if err := config.DB.Delete(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar tipo de cambio"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tipo de cambio eliminado exitosamente"})
+}
@@ -0,0 +1,809 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type InvoiceController struct {
+	numberingService    *services.NumberingService
+	pdfService          *services.PDFService
+	taxService          *services.TaxService
+	pricingService      *services.PricingService
+	exchangeRateService *services.ExchangeRateService
+}
+
+func NewInvoiceController() *InvoiceController {
+	return &InvoiceController{
+		numberingService:    services.NewNumberingService(),
+		pdfService:          services.NewPDFService(),
+		taxService:          services.NewTaxService(),
+		pricingService:      services.NewPricingService(),
+		exchangeRateService: services.NewExchangeRateService(),
+	}
+}
+
+// @Summary Obtener todas las facturas
+// @Description Obtener lista de facturas con paginación y filtros
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Param status query string false "Filtrar por estado"
+// @Param client_id query int false "Filtrar por cliente"
+// @Param project_id query int false "Filtrar por proyecto"
+// @Param start_date query string false "Fecha de emisión desde (YYYY-MM-DD)"
+// @Param end_date query string false "Fecha de emisión hasta (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]string
+// @Router /invoices [get]
+func (ic *InvoiceController) GetInvoices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	status := c.Query("status")
+	clientID := c.Query("client_id")
+	projectID := c.Query("project_id")
+	startDate := c.Query("start_date")
+	endDate := c.Query("end_date")
+
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.Invoice{}).Preload("Client").Preload("Project").Preload("Items")
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	if projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	if startDate != "" {
+		if parsedDate, err := time.Parse("2006-01-02", startDate); err == nil {
+			query = query.Where("issue_date >= ?", parsedDate)
+		}
+	}
+	if endDate != "" {
+		if parsedDate, err := time.Parse("2006-01-02", endDate); err == nil {
+			query = query.Where("issue_date < ?", parsedDate.AddDate(0, 0, 1))
+		}
+	}
+
+	var invoices []models.Invoice
+	var total int64
+
+	query.Count(&total)
+	if err := query.Offset(offset).Limit(limit).Order("issue_date DESC, id DESC").Find(&invoices).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener facturas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invoices": invoices,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+		"pages":    (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// @Summary Obtener factura por ID
+// @Description Obtener detalles de una factura específica
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Success 200 {object} models.Invoice
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id} [get]
+func (ic *InvoiceController) GetInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Preload("Client").Preload("Project").Preload("Quote").Preload("Items").Preload("Payments").Preload("CreditNotes").First(&invoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, invoice)
+}
+
+// @Summary Descargar factura en PDF
+// @Description Generar el PDF de una factura con los datos de la empresa, el cliente, los conceptos y los totales
+// @Tags invoices
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/pdf [get]
+func (ic *InvoiceController) GetInvoicePDF(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.Preload("Client").Preload("Items").First(&invoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura no encontrada"})
+		return
+	}
+
+	content, err := ic.pdfService.RenderInvoice(&invoice)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al generar PDF de la factura"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.pdf\"", invoice.InvoiceNumber))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// @Summary Crear nueva factura
+// @Description Crear una nueva factura con sus items. La factura y cada item admiten un descuento fijo (discount) o porcentual (discount_type percent y discount_percent), que se resta antes de impuestos
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param invoice body models.CreateInvoiceRequest true "Datos de la factura"
+// @Success 201 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Router /invoices [post]
+func (ic *InvoiceController) CreateInvoice(c *gin.Context) {
+	var req models.CreateInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Verificar que el cliente existe
+	var client models.Client
+	if err := config.DB.First(&client, req.ClientID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cliente no encontrado"})
+		return
+	}
+	if err := validateClientProject(config.DB, client.ID, req.ProjectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	issueDate := time.Now()
+	if req.IssueDate != nil {
+		issueDate = *req.IssueDate
+	}
+	if req.DueDate.Before(issueDate.Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha de vencimiento no puede ser anterior a la fecha de emisión"})
+		return
+	}
+
+	if err := validateInvoiceTaxCodes(ic.taxService, req.TaxCode, req.Items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Calcular totales
+	items := make([]models.InvoiceItem, 0, len(req.Items))
+	for _, itemReq := range req.Items {
+		unit := itemReq.Unit
+		if unit == "" {
+			unit = "pcs"
+		}
+		item := models.InvoiceItem{
+			Description:     itemReq.Description,
+			Quantity:        itemReq.Quantity,
+			Unit:            unit,
+			UnitPrice:       itemReq.UnitPrice,
+			DiscountType:    itemReq.DiscountType,
+			DiscountPercent: itemReq.DiscountPercent,
+			DiscountFixed:   itemReq.Discount,
+			TaxCode:         itemReq.TaxCode,
+			Notes:           itemReq.Notes,
+		}
+		ic.pricingService.PriceInvoiceItem(&item)
+		items = append(items, item)
+	}
+
+	invoice := models.Invoice{
+		ClientID:        req.ClientID,
+		ProjectID:       req.ProjectID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Status:          "draft",
+		IssueDate:       issueDate,
+		DueDate:         req.DueDate,
+		Currency:        services.NormalizeCurrency(req.Currency),
+		TaxRate:         req.TaxRate,
+		TaxCode:         req.TaxCode,
+		DiscountType:    req.DiscountType,
+		DiscountPercent: req.DiscountPercent,
+		DiscountFixed:   req.Discount,
+		PaidAmount:      0,
+		Notes:           req.Notes,
+		Terms:           req.Terms,
+		Items:           items,
+	}
+	if err := ic.pricingService.PriceInvoice(config.DB, &invoice, items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	invoice.Balance = invoice.Total
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		number, err := ic.numberingService.Next(tx, services.SeriesInvoice)
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = number
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear factura"})
+		return
+	}
+
+	// Cargar la factura completa
+	config.DB.Preload("Client").Preload("Project").Preload("Items").First(&invoice, invoice.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Factura creada exitosamente",
+		"invoice": invoice,
+	})
+}
+
+// @Summary Facturar cotización
+// @Description Crear una factura a partir de una cotización aceptada, copiando sus items, descuentos y condiciones
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param invoice body models.CreateInvoiceFromQuoteRequest false "Datos opcionales de la factura"
+// @Success 201 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /quotes/{id}/invoice [post]
+func (ic *InvoiceController) CreateInvoiceFromQuote(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.CreateInvoiceFromQuoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var quote models.Quote
+	if err := config.DB.Preload("Items", orderedQuoteItems).First(&quote, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	}
+
+	if quote.Status != "accepted" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Solo se pueden facturar cotizaciones aceptadas"})
+		return
+	}
+
+	// Verificar que la cotización no haya sido facturada
+	var existing models.Invoice
+	if err := config.DB.Where("quote_id = ? AND status != ?", quote.ID, "cancelled").First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "La cotización ya fue facturada",
+			"invoice_id": existing.ID,
+		})
+		return
+	}
+
+	issueDate := time.Now()
+	if req.IssueDate != nil {
+		issueDate = *req.IssueDate
+	}
+	dueDate := issueDate.AddDate(0, 0, 30)
+	if req.DueDate != nil {
+		dueDate = *req.DueDate
+	}
+	if dueDate.Before(issueDate.Truncate(24 * time.Hour)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha de vencimiento no puede ser anterior a la fecha de emisión"})
+		return
+	}
+
+	title := quote.Title
+	if req.Title != nil && *req.Title != "" {
+		title = *req.Title
+	}
+
+	items := make([]models.InvoiceItem, 0, len(quote.Items))
+	for _, quoteItem := range quote.Items {
+		item := models.InvoiceItem{
+			Description:     quoteItem.Description,
+			Quantity:        quoteItem.Quantity,
+			Unit:            quoteItem.Unit,
+			UnitPrice:       quoteItem.UnitPrice,
+			DiscountType:    quoteItem.DiscountType,
+			DiscountPercent: quoteItem.DiscountPercent,
+			DiscountFixed:   quoteItem.DiscountFixed,
+			TaxCode:         quoteItem.TaxCode,
+			Notes:           quoteItem.Notes,
+		}
+		ic.pricingService.PriceInvoiceItem(&item)
+		items = append(items, item)
+	}
+
+	quoteID := quote.ID
+	invoice := models.Invoice{
+		ClientID:        quote.ClientID,
+		ProjectID:       quote.ProjectID,
+		QuoteID:         &quoteID,
+		Title:           title,
+		Description:     quote.Description,
+		Status:          "draft",
+		IssueDate:       issueDate,
+		DueDate:         dueDate,
+		Currency:        quote.Currency,
+		TaxRate:         quote.TaxRate,
+		TaxCode:         quote.TaxCode,
+		DiscountType:    quote.DiscountType,
+		DiscountPercent: quote.DiscountPercent,
+		DiscountFixed:   quote.DiscountFixed,
+		PaidAmount:      0,
+		Notes:           quote.Notes,
+		Terms:           quote.Terms,
+		Items:           items,
+	}
+	if err := ic.pricingService.PriceInvoice(config.DB, &invoice, items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al calcular totales de la factura"})
+		return
+	}
+	invoice.Balance = invoice.Total
+
+	// La cotización se bloquea y se vuelve a verificar dentro de la transacción para que dos
+	// solicitudes simultáneas no la facturen dos veces
+	status := http.StatusInternalServerError
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Quote{}, quote.ID).Error; err != nil {
+			return errors.New("Error al crear factura")
+		}
+		if err := tx.Where("quote_id = ? AND status != ?", quote.ID, "cancelled").First(&existing).Error; err == nil {
+			status = http.StatusConflict
+			return errors.New("La cotización ya fue facturada")
+		}
+		number, err := ic.numberingService.Next(tx, services.SeriesInvoice)
+		if err != nil {
+			return errors.New("Error al crear factura")
+		}
+		invoice.InvoiceNumber = number
+		if err := tx.Create(&invoice).Error; err != nil {
+			return errors.New("Error al crear factura")
+		}
+		return nil
+	})
+	if err != nil {
+		response := gin.H{"error": err.Error()}
+		if status == http.StatusConflict {
+			response["invoice_id"] = existing.ID
+		}
+		c.JSON(status, response)
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Quote").Preload("Items").First(&invoice, invoice.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Factura creada a partir de la cotización " + quote.QuoteNumber,
+		"invoice": invoice,
+	})
+}
+
+// @Summary Duplicar factura
+// @Description Crear una copia en borrador de una factura con sus items y un número nuevo. La copia se emite hoy, conserva el plazo de pago de la original y se valoriza de nuevo con los impuestos vigentes; no copia pagos ni notas de crédito. Opcionalmente se asigna a otro cliente y/o proyecto
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Param invoice body models.DuplicateInvoiceRequest false "Cliente y proyecto de la copia"
+// @Success 201 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/duplicate [post]
+func (ic *InvoiceController) DuplicateInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.DuplicateInvoiceRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var original models.Invoice
+	if err := config.DB.Preload("Items").First(&original, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura no encontrada"})
+		return
+	}
+
+	clientID, projectID, err := resolveDocumentTarget(config.DB, original.ClientID, original.ProjectID, req.ClientID, req.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// La copia se valoriza de nuevo con los impuestos vigentes
+	issueDate := time.Now()
+	items := make([]models.InvoiceItem, 0, len(original.Items))
+	for _, originalItem := range original.Items {
+		item := models.InvoiceItem{
+			Description:     originalItem.Description,
+			Quantity:        originalItem.Quantity,
+			Unit:            originalItem.Unit,
+			UnitPrice:       originalItem.UnitPrice,
+			DiscountType:    originalItem.DiscountType,
+			DiscountPercent: originalItem.DiscountPercent,
+			DiscountFixed:   originalItem.DiscountFixed,
+			TaxCode:         originalItem.TaxCode,
+			Notes:           originalItem.Notes,
+		}
+		ic.pricingService.PriceInvoiceItem(&item)
+		items = append(items, item)
+	}
+
+	invoice := models.Invoice{
+		ClientID:        clientID,
+		ProjectID:       projectID,
+		Title:           original.Title,
+		Description:     original.Description,
+		Status:          "draft",
+		IssueDate:       issueDate,
+		DueDate:         issueDate.Add(original.DueDate.Sub(original.IssueDate)),
+		Currency:        original.Currency,
+		TaxRate:         original.TaxRate,
+		TaxCode:         original.TaxCode,
+		DiscountType:    original.DiscountType,
+		DiscountPercent: original.DiscountPercent,
+		DiscountFixed:   original.DiscountFixed,
+		PaidAmount:      0,
+		Notes:           original.Notes,
+		Terms:           original.Terms,
+		Items:           items,
+	}
+	if err := ic.pricingService.PriceInvoice(config.DB, &invoice, items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al calcular totales de la factura"})
+		return
+	}
+	invoice.Balance = invoice.Total
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		number, err := ic.numberingService.Next(tx, services.SeriesInvoice)
+		if err != nil {
+			return err
+		}
+		invoice.InvoiceNumber = number
+		return tx.Create(&invoice).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al duplicar factura"})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items").First(&invoice, invoice.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Factura duplicada exitosamente",
+		"invoice": invoice,
+	})
+}
+
+// @Summary Actualizar factura
+// @Description Actualizar información de una factura. A mano solo se puede emitir un borrador (draft → sent); los estados pagada y vencida se asignan automáticamente y la cancelación se hace con nota de crédito. Los impuestos, descuentos y moneda solo se cambian en borradores
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Param invoice body models.UpdateInvoiceRequest true "Datos actualizados de la factura"
+// @Success 200 {object} models.Invoice
+// @Failure 400 {object} map[string]string
+// @Router /invoices/{id} [put]
+func (ic *InvoiceController) UpdateInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.First(&invoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura no encontrada"})
+		return
+	}
+
+	if invoice.Status == "cancelled" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No se puede modificar una factura cancelada"})
+		return
+	}
+
+	if req.Status != nil && !isValidInvoiceStatus(*req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado inválido"})
+		return
+	}
+
+	// El estado pagado se deriva de los pagos registrados
+	if req.Status != nil && *req.Status == "paid" && invoice.Status != "paid" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El estado pagado se asigna automáticamente al registrar pagos"})
+		return
+	}
+
+	// La cancelación debe emitir una nota de crédito
+	if req.Status != nil && *req.Status == "cancelled" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use la cancelación de factura para anularla con nota de crédito"})
+		return
+	}
+
+	// A mano solo se emite un borrador; vencida se asigna automáticamente
+	if req.Status != nil && *req.Status != invoice.Status && !services.CanTransitionInvoice(invoice.Status, *req.Status) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("No se puede cambiar una factura de %s a %s", invoice.Status, *req.Status)})
+		return
+	}
+
+	// La moneda solo puede cambiar mientras la factura no se ha emitido
+	if req.Currency != nil && services.NormalizeCurrency(*req.Currency) != invoice.Currency && invoice.Status != "draft" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Solo se puede cambiar la moneda de facturas en borrador"})
+		return
+	}
+
+	// Tampoco se recalcula el total de una factura emitida: sus pagos y notas de crédito
+	// se registraron contra ese total
+	reprice := invoiceRepricingChanged(invoice, req)
+	if reprice && invoice.Status != "draft" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Solo se pueden cambiar impuestos y descuentos de facturas en borrador"})
+		return
+	}
+
+	// Actualizar campos
+	if req.Title != nil {
+		invoice.Title = *req.Title
+	}
+	if req.Description != nil {
+		invoice.Description = *req.Description
+	}
+	if req.Status != nil {
+		invoice.Status = *req.Status
+	}
+	if req.DueDate != nil {
+		if req.DueDate.Before(invoice.IssueDate.Truncate(24 * time.Hour)) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "La fecha de vencimiento no puede ser anterior a la fecha de emisión"})
+			return
+		}
+		invoice.DueDate = *req.DueDate
+	}
+	if req.Currency != nil {
+		invoice.Currency = services.NormalizeCurrency(*req.Currency)
+	}
+	if req.TaxRate != nil {
+		invoice.TaxRate = *req.TaxRate
+	}
+	if req.TaxCode != nil {
+		if err := ic.taxService.ValidateCode(config.DB, *req.TaxCode); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		invoice.TaxCode = *req.TaxCode
+	}
+	if req.DiscountType != nil {
+		invoice.DiscountType = *req.DiscountType
+	}
+	if req.DiscountPercent != nil {
+		invoice.DiscountPercent = *req.DiscountPercent
+	}
+	if req.Discount != nil {
+		invoice.DiscountFixed = *req.Discount
+	}
+	if req.Notes != nil {
+		invoice.Notes = *req.Notes
+	}
+	if req.Terms != nil {
+		invoice.Terms = *req.Terms
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Recalcular totales
+		if reprice {
+			var items []models.InvoiceItem
+			if err := tx.Where("invoice_id = ?", invoice.ID).Find(&items).Error; err != nil {
+				return err
+			}
+			if err := ic.pricingService.PriceInvoice(tx, &invoice, items); err != nil {
+				return err
+			}
+		}
+		if err := tx.Save(&invoice).Error; err != nil {
+			return err
+		}
+		// Recalcular saldo y estado de pago con los nuevos totales
+		if err := recalculateInvoiceBalance(tx, &invoice); err != nil {
+			return err
+		}
+		// Con un nuevo vencimiento, una factura pendiente vuelve a estar enviada o pasa a vencida
+		if req.DueDate != nil && (invoice.Status == "sent" || invoice.Status == "overdue") && invoice.Balance > 0 {
+			status := "sent"
+			if invoice.DueDate.Before(time.Now()) {
+				status = "overdue"
+			}
+			if status != invoice.Status {
+				invoice.Status = status
+				return tx.Model(&invoice).Update("status", status).Error
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar factura"})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items").First(&invoice, invoice.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Factura actualizada exitosamente",
+		"invoice": invoice,
+	})
+}
+
+// @Summary Eliminar factura
+// @Description Eliminar una factura en borrador (soft delete). Las facturas emitidas se cancelan con nota de crédito
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id} [delete]
+func (ic *InvoiceController) DeleteInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.First(&invoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura no encontrada"})
+		return
+	}
+
+	// Las facturas emitidas no se eliminan: se cancelan con una nota de crédito
+	if invoice.Status != "draft" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Solo se pueden eliminar facturas en borrador; las facturas emitidas deben cancelarse"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("invoice_id = ?", invoice.ID).Delete(&models.InvoiceItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&invoice).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar factura"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Factura eliminada exitosamente"})
+}
+
+// @Summary Obtener estadísticas de facturas
+// @Description Obtener estadísticas generales de facturación. Los importes se convierten a la moneda base con el tipo de cambio de la fecha de emisión
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.InvoiceStats
+// @Router /invoices/stats [get]
+func (ic *InvoiceController) GetInvoiceStats(c *gin.Context) {
+	var stats models.InvoiceStats
+
+	config.DB.Model(&models.Invoice{}).Count(&stats.TotalInvoices)
+	config.DB.Model(&models.Invoice{}).Where("status = ?", "draft").Count(&stats.DraftInvoices)
+	config.DB.Model(&models.Invoice{}).Where("status = ?", "sent").Count(&stats.SentInvoices)
+	config.DB.Model(&models.Invoice{}).Where("status = ?", "paid").Count(&stats.PaidInvoices)
+	config.DB.Model(&models.Invoice{}).Where("status = ?", "overdue").Count(&stats.OverdueInvoices)
+
+	var invoices []models.Invoice
+	config.DB.Select("status, total, paid_amount, balance, currency, issue_date").Where("status != ?", "cancelled").Find(&invoices)
+
+	// Las facturas sin tipo de cambio quedan fuera de los importes y se informan en MissingRates
+	converter := ic.exchangeRateService.NewConverter(config.DB)
+	for _, invoice := range invoices {
+		if _, ok := converter.ToBase(0, invoice.Currency, invoice.IssueDate); !ok {
+			continue
+		}
+		toBase := func(amount models.Money) models.Money {
+			converted, _ := converter.ToBase(amount, invoice.Currency, invoice.IssueDate)
+			return converted
+		}
+
+		// Valor total facturado (sin borradores ni canceladas)
+		if invoice.Status != "draft" {
+			stats.TotalValue += toBase(invoice.Total)
+		}
+		// Valor cobrado
+		stats.PaidValue += toBase(invoice.PaidAmount)
+		// Saldo pendiente de cobro
+		if invoice.Status == "sent" || invoice.Status == "overdue" {
+			stats.OutstandingValue += toBase(invoice.Balance)
+		}
+	}
+	stats.Currency = services.BaseCurrency()
+	stats.MissingRates = converter.Missing()
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// invoiceRepricingChanged indica si la solicitud cambia algún dato que recalcula el total
+// de la factura (tasa, código de impuesto o descuento)
+func invoiceRepricingChanged(invoice models.Invoice, req models.UpdateInvoiceRequest) bool {
+	return (req.TaxRate != nil && *req.TaxRate != invoice.TaxRate) ||
+		(req.TaxCode != nil && *req.TaxCode != invoice.TaxCode) ||
+		(req.DiscountType != nil && services.NormalizeDiscountType(*req.DiscountType) != services.NormalizeDiscountType(invoice.DiscountType)) ||
+		(req.DiscountPercent != nil && *req.DiscountPercent != invoice.DiscountPercent) ||
+		(req.Discount != nil && *req.Discount != invoice.DiscountFixed)
+}
+
+// Función auxiliar para validar el estado de una factura
+func isValidInvoiceStatus(status string) bool {
+	validStatuses := []string{"draft", "sent", "paid", "overdue", "cancelled"}
+	for _, s := range validStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validateInvoiceTaxCodes verifica el código de impuesto del documento y los de sus items
+func validateInvoiceTaxCodes(taxService *services.TaxService, taxCode string, items []models.CreateInvoiceItemRequest) error {
+	if err := taxService.ValidateCode(config.DB, taxCode); err != nil {
+		return err
+	}
+	for i, item := range items {
+		if err := taxService.ValidateCode(config.DB, item.TaxCode); err != nil {
+			return fmt.Errorf("Item %d: %s", i+1, err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+)
+
+type LaborRateController struct{}
+
+func NewLaborRateController() *LaborRateController {
+	return &LaborRateController{}
+}
+
+// @Summary Obtener tarifas de mano de obra
+// @Description Obtener el catálogo de tarifas de costo de mano de obra por tipo de trabajo
+// @Tags labor-rates
+// @Produce json
+// @Security BearerAuth
+// @Param active query bool false "Solo tarifas activas"
+// @Success 200 {array} models.LaborRate
+// @Router /labor-rates [get]
+func (lrc *LaborRateController) GetLaborRates(c *gin.Context) {
+	query := config.DB.Model(&models.LaborRate{})
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var rates []models.LaborRate
+	if err := query.Order("work_type ASC").Find(&rates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener tarifas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// @Summary Crear tarifa de mano de obra
+// @Description Registrar el costo por hora de un tipo de trabajo
+// @Tags labor-rates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param rate body models.CreateLaborRateRequest true "Datos de la tarifa"
+// @Success 201 {object} models.LaborRate
+// @Failure 400 {object} map[string]string
+// @Router /labor-rates [post]
+func (lrc *LaborRateController) CreateLaborRate(c *gin.Context) {
+	var req models.CreateLaborRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.LaborRate
+	if err := config.DB.Where("work_type = ?", req.WorkType).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe una tarifa para este tipo de trabajo"})
+		return
+	}
+
+	unit := req.Unit
+	if unit == "" {
+		unit = "h"
+	}
+
+	rate := models.LaborRate{
+		WorkType:   req.WorkType,
+		Name:       req.Name,
+		Unit:       unit,
+		HourlyCost: req.HourlyCost,
+		IsActive:   true,
+		Notes:      req.Notes,
+	}
+
+	if err := config.DB.Create(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear tarifa"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Tarifa creada exitosamente",
+		"rate":    rate,
+	})
+}
+
+// @Summary Actualizar tarifa de mano de obra
+// @Description Actualizar una tarifa de mano de obra. Los cambios no afectan a cotizaciones ya creadas
+// @Tags labor-rates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la tarifa"
+// @Param rate body models.UpdateLaborRateRequest true "Datos actualizados de la tarifa"
+// @Success 200 {object} models.LaborRate
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /labor-rates/{id} [put]
+func (lrc *LaborRateController) UpdateLaborRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateLaborRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var rate models.LaborRate
+	if err := config.DB.First(&rate, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tarifa no encontrada"})
+		return
+	}
+
+	// Actualizar campos
+	if req.Name != nil {
+		rate.Name = *req.Name
+	}
+	if req.Unit != nil {
+		rate.Unit = *req.Unit
+	}
+	if req.HourlyCost != nil {
+		rate.HourlyCost = *req.HourlyCost
+	}
+	if req.IsActive != nil {
+		rate.IsActive = *req.IsActive
+	}
+	if req.Notes != nil {
+		rate.Notes = *req.Notes
+	}
+
+	if err := config.DB.Save(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar tarifa"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tarifa actualizada exitosamente",
+		"rate":    rate,
+	})
+}
+
+// @Summary Eliminar tarifa de mano de obra
+// @Description Eliminar una tarifa de mano de obra del catálogo
+// @Tags labor-rates
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la tarifa"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /labor-rates/{id} [delete]
+func (lrc *LaborRateController) DeleteLaborRate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var rate models.LaborRate
+	if err := config.DB.First(&rate, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tarifa no encontrada"})
+		return
+	}
+
+	if err := config.DB.Delete(&rate).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar tarifa"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tarifa eliminada exitosamente"})
+}
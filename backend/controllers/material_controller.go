@@ -1,18 +1,25 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"raborimet-crm/backend/config"
 	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
 )
 
-type MaterialController struct{}
+type MaterialController struct {
+	stockService *services.StockService
+}
 
 func NewMaterialController() *MaterialController {
-	return &MaterialController{}
+	return &MaterialController{
+		stockService: services.NewStockService(),
+	}
 }
 
 // @Summary Obtener todos los materiales
@@ -115,14 +122,26 @@ func (mc *MaterialController) CreateMaterial(c *gin.Context) {
 		Category:    req.Category,
 		Unit:        req.Unit,
 		UnitPrice:   req.UnitPrice,
-		Stock:       req.Stock,
 		MinStock:    req.MinStock,
 		Supplier:    req.Supplier,
 		SKU:         req.SKU,
 		IsActive:    true,
 	}
 
-	if err := config.DB.Create(&material).Error; err != nil {
+	// El stock inicial entra como primer movimiento del material
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&material).Error; err != nil {
+			return err
+		}
+		created, err := mc.stockService.SetStock(tx, material, req.Stock, models.StockMovement{
+			Type:   models.StockMovementIn,
+			Reason: "Stock inicial",
+			UserID: currentUserID(c),
+		})
+		material = created
+		return err
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear material"})
 		return
 	}
@@ -188,9 +207,6 @@ func (mc *MaterialController) UpdateMaterial(c *gin.Context) {
 	if req.UnitPrice != nil {
 		material.UnitPrice = *req.UnitPrice
 	}
-	if req.Stock != nil {
-		material.Stock = *req.Stock
-	}
 	if req.MinStock != nil {
 		material.MinStock = *req.MinStock
 	}
@@ -204,8 +220,37 @@ func (mc *MaterialController) UpdateMaterial(c *gin.Context) {
 		material.IsActive = *req.IsActive
 	}
 
-	if err := config.DB.Save(&material).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar material"})
+	// El stock se fija con el material bloqueado para no pisar reservas concurrentes; la
+	// diferencia queda registrada como ajuste
+	status := http.StatusInternalServerError
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("stock", "reserved_stock").Save(&material).Error; err != nil {
+			return errors.New("Error al actualizar material")
+		}
+		locked, err := mc.stockService.Lock(tx, material.ID)
+		if err != nil {
+			return errors.New("Error al actualizar material")
+		}
+		stock := locked.Stock
+		if req.Stock != nil {
+			stock = *req.Stock
+		}
+		updated, err := mc.stockService.SetStock(tx, locked, stock, models.StockMovement{
+			Type:   models.StockMovementAdjust,
+			Reason: "Ajuste de stock al actualizar el material",
+			UserID: currentUserID(c),
+		})
+		if err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+		material.Stock = updated.Stock
+		material.ReservedStock = updated.ReservedStock
+		material.AvailableStock = updated.AvailableStock
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -255,13 +300,13 @@ func (mc *MaterialController) DeleteMaterial(c *gin.Context) {
 }
 
 // @Summary Actualizar stock de material
-// @Description Actualizar el stock de un material (entrada o salida)
+// @Description Registrar una entrada o salida de stock de un material. El movimiento queda en el historial con su motivo, costo unitario (por defecto el precio del material) y usuario. Una salida solo puede tomar el stock disponible (existencia menos lo reservado para proyectos)
 // @Tags materials
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID del material"
-// @Param stock body map[string]interface{} true "Datos de actualización de stock"
+// @Param stock body models.UpdateMaterialStockRequest true "Movimiento de stock"
 // @Success 200 {object} models.Material
 // @Failure 400 {object} map[string]string
 // @Router /materials/{id}/stock [patch]
@@ -272,18 +317,63 @@ func (mc *MaterialController) UpdateMaterialStock(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Quantity float64 `json:"quantity" binding:"required"`
-		Type     string  `json:"type" binding:"required"` // "in" o "out"
-		Reason   string  `json:"reason"`
-	}
+	var req models.UpdateMaterialStockRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if req.Type != "in" && req.Type != "out" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Tipo debe ser 'in' o 'out'"})
+	var material models.Material
+	if err := config.DB.First(&material, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Material no encontrado"})
+		return
+	}
+
+	// Actualizar stock; una salida no puede tomar stock reservado para proyectos
+	quantity := req.Quantity
+	if req.Type == models.StockMovementOut {
+		quantity = -quantity
+	}
+	movement := models.StockMovement{
+		Type:   req.Type,
+		Reason: req.Reason,
+		UserID: currentUserID(c),
+	}
+	if req.UnitCost != nil {
+		movement.UnitCost = *req.UnitCost
+	}
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		material, err = mc.stockService.Adjust(tx, material.ID, quantity, movement)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Stock actualizado exitosamente",
+		"material": material,
+	})
+}
+
+// @Summary Obtener movimientos de stock de un material
+// @Description Obtener el historial de entradas, salidas, ajustes y transferencias a proyectos de un material, del más reciente al más antiguo. Incluye el stock que resulta de sumar los movimientos para compararlo con el registrado
+// @Tags materials
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del material"
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(20)
+// @Param type query string false "Filtrar por tipo (in, out, adjust, transfer)"
+// @Param project_id query int false "Filtrar por proyecto"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /materials/{id}/movements [get]
+func (mc *MaterialController) GetMaterialMovements(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
 		return
 	}
 
@@ -293,30 +383,83 @@ func (mc *MaterialController) UpdateMaterialStock(c *gin.Context) {
 		return
 	}
 
-	// Actualizar stock
-	if req.Type == "in" {
-		material.Stock += req.Quantity
-	} else {
-		if material.Stock < req.Quantity {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Stock insuficiente"})
-			return
-		}
-		material.Stock -= req.Quantity
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.StockMovement{}).Where("material_id = ?", material.ID)
+	if movementType := c.Query("type"); movementType != "" {
+		query = query.Where("type = ?", movementType)
+	}
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	var movements []models.StockMovement
+	var total int64
+
+	query.Count(&total)
+	if err := query.Preload("Project").Preload("User").Offset(offset).Limit(limit).Order("created_at DESC, id DESC").Find(&movements).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener movimientos"})
+		return
 	}
 
-	if err := config.DB.Save(&material).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar stock"})
+	ledgerStock, err := mc.stockService.LedgerStock(config.DB, material.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener movimientos"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Stock actualizado exitosamente",
-		"material": material,
+		"movements":    movements,
+		"total":        total,
+		"page":         page,
+		"limit":        limit,
+		"stock":        material.Stock,
+		"ledger_stock": ledgerStock,
+	})
+}
+
+// @Summary Conciliar stock de un material
+// @Description Comparar el stock registrado con la suma de los movimientos y, si difieren, registrar un ajuste por la diferencia (por ejemplo, para el saldo inicial de materiales anteriores al historial)
+// @Tags materials
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del material"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /materials/{id}/movements/reconcile [post]
+func (mc *MaterialController) ReconcileMaterialStock(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var material models.Material
+	if err := config.DB.First(&material, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Material no encontrado"})
+		return
+	}
+
+	var adjusted float64
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		adjusted, err = mc.stockService.Reconcile(tx, material.ID, currentUserID(c))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al conciliar stock"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Stock conciliado exitosamente",
+		"adjusted": adjusted,
 	})
 }
 
 // @Summary Obtener materiales con stock bajo
-// @Description Obtener lista de materiales con stock por debajo del mínimo
+// @Description Obtener lista de materiales con stock disponible (existencia menos reservado) por debajo del mínimo
 // @Tags materials
 // @Produce json
 // @Security BearerAuth
@@ -324,7 +467,7 @@ func (mc *MaterialController) UpdateMaterialStock(c *gin.Context) {
 // @Router /materials/low-stock [get]
 func (mc *MaterialController) GetLowStockMaterials(c *gin.Context) {
 	var materials []models.Material
-	config.DB.Where("stock <= min_stock AND is_active = true").Find(&materials)
+	config.DB.Where("stock - reserved_stock <= min_stock AND is_active = true").Find(&materials)
 
 	c.JSON(http.StatusOK, gin.H{
 		"materials": materials,
@@ -361,7 +504,7 @@ func (mc *MaterialController) GetMaterialStats(c *gin.Context) {
 
 	config.DB.Model(&models.Material{}).Count(&totalMaterials)
 	config.DB.Model(&models.Material{}).Where("is_active = true").Count(&activeMaterials)
-	config.DB.Model(&models.Material{}).Where("stock <= min_stock AND is_active = true").Count(&lowStockMaterials)
+	config.DB.Model(&models.Material{}).Where("stock - reserved_stock <= min_stock AND is_active = true").Count(&lowStockMaterials)
 	config.DB.Model(&models.Material{}).Select("COALESCE(SUM(stock * unit_price), 0)").Scan(&totalValue)
 
 	// Materiales por categoría
@@ -0,0 +1,275 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+)
+
+type PaymentController struct{}
+
+func NewPaymentController() *PaymentController {
+	return &PaymentController{}
+}
+
+// @Summary Obtener pagos de una factura
+// @Description Obtener el historial de pagos de una factura, incluyendo los anulados
+// @Tags invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/payments [get]
+func (pc *PaymentController) GetInvoicePayments(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var invoice models.Invoice
+	if err := config.DB.First(&invoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura no encontrada"})
+		return
+	}
+
+	var payments []models.Payment
+	config.DB.Preload("RecordedBy").Preload("VoidedBy").Where("invoice_id = ?", invoice.ID).Order("payment_date ASC, id ASC").Find(&payments)
+
+	c.JSON(http.StatusOK, gin.H{
+		"payments":    payments,
+		"total":       invoice.Total,
+		"paid_amount": invoice.PaidAmount,
+		"balance":     invoice.Balance,
+	})
+}
+
+// @Summary Registrar pago
+// @Description Registrar un pago (total o parcial) sobre una factura y recalcular su saldo
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Param payment body models.CreatePaymentRequest true "Datos del pago"
+// @Success 201 {object} models.Payment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/payments [post]
+func (pc *PaymentController) CreatePayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req models.CreatePaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentDate := time.Now()
+	if req.PaymentDate != nil {
+		paymentDate = *req.PaymentDate
+	}
+
+	var invoice models.Invoice
+	var payment models.Payment
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		// Bloquear la factura para evitar pagos concurrentes sobre el mismo saldo
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Factura no encontrada")
+		}
+
+		switch invoice.Status {
+		case "draft":
+			status = http.StatusBadRequest
+			return errors.New("No se pueden registrar pagos en una factura en borrador")
+		case "cancelled":
+			status = http.StatusBadRequest
+			return errors.New("No se pueden registrar pagos en una factura cancelada")
+		case "paid":
+			status = http.StatusBadRequest
+			return errors.New("La factura ya está pagada")
+		}
+
+		if req.Amount > invoice.Balance {
+			status = http.StatusBadRequest
+			return errors.New("El monto excede el saldo pendiente de la factura")
+		}
+
+		payment = models.Payment{
+			InvoiceID:    invoice.ID,
+			Amount:       req.Amount,
+			PaymentDate:  paymentDate,
+			Method:       req.Method,
+			Reference:    req.Reference,
+			Notes:        req.Notes,
+			RecordedByID: userID.(uint),
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return errors.New("Error al registrar pago")
+		}
+
+		if err := recalculateInvoiceBalance(tx, &invoice); err != nil {
+			return errors.New("Error al actualizar saldo de la factura")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("RecordedBy").First(&payment, payment.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Pago registrado exitosamente",
+		"payment": payment,
+		"invoice": invoice,
+	})
+}
+
+// @Summary Anular pago
+// @Description Anular un pago registrado. El pago se conserva con el motivo y el usuario que lo anuló
+// @Tags invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura"
+// @Param paymentId path int true "ID del pago"
+// @Param void body models.VoidPaymentRequest true "Motivo de la anulación"
+// @Success 200 {object} models.Payment
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /invoices/{id}/payments/{paymentId}/void [post]
+func (pc *PaymentController) VoidPayment(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+	paymentID, err := strconv.ParseUint(c.Param("paymentId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de pago inválido"})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "No autorizado"})
+		return
+	}
+
+	var req models.VoidPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var invoice models.Invoice
+	var payment models.Payment
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&invoice, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Factura no encontrada")
+		}
+
+		if err := tx.Where("invoice_id = ?", invoice.ID).First(&payment, uint(paymentID)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Pago no encontrado")
+		}
+
+		if payment.IsVoided {
+			status = http.StatusBadRequest
+			return errors.New("El pago ya fue anulado")
+		}
+
+		now := time.Now()
+		voidedBy := userID.(uint)
+		payment.IsVoided = true
+		payment.VoidedAt = &now
+		payment.VoidedByID = &voidedBy
+		payment.VoidReason = req.Reason
+		if err := tx.Save(&payment).Error; err != nil {
+			return errors.New("Error al anular pago")
+		}
+
+		if err := recalculateInvoiceBalance(tx, &invoice); err != nil {
+			return errors.New("Error al actualizar saldo de la factura")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("RecordedBy").Preload("VoidedBy").First(&payment, payment.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Pago anulado exitosamente",
+		"payment": payment,
+		"invoice": invoice,
+	})
+}
+
+// recalculateInvoiceBalance recalcula el monto pagado, el monto acreditado, el saldo,
+// la fecha de pago y el estado de la factura a partir de los pagos vigentes y las
+// notas de crédito emitidas, y guarda los cambios.
+func recalculateInvoiceBalance(tx *gorm.DB, invoice *models.Invoice) error {
+	var paid models.Money
+	if err := tx.Model(&models.Payment{}).Where("invoice_id = ? AND is_voided = ?", invoice.ID, false).Select("COALESCE(SUM(amount), 0)").Scan(&paid).Error; err != nil {
+		return err
+	}
+
+	var credited models.Money
+	if err := tx.Model(&models.CreditNote{}).Where("invoice_id = ?", invoice.ID).Select("COALESCE(SUM(total), 0)").Scan(&credited).Error; err != nil {
+		return err
+	}
+
+	invoice.PaidAmount = paid
+	invoice.CreditedAmount = credited
+	invoice.Balance = invoice.Total - credited - paid
+
+	if invoice.Status != "draft" && invoice.Status != "cancelled" {
+		if paid > 0 && invoice.Balance <= 0 {
+			var lastPayment models.Payment
+			if err := tx.Where("invoice_id = ? AND is_voided = ?", invoice.ID, false).Order("payment_date DESC").First(&lastPayment).Error; err != nil {
+				return err
+			}
+			invoice.Status = "paid"
+			invoice.PaidDate = &lastPayment.PaymentDate
+		} else {
+			invoice.PaidDate = nil
+			if invoice.Status == "paid" {
+				invoice.Status = "sent"
+				if invoice.DueDate.Before(time.Now()) {
+					invoice.Status = "overdue"
+				}
+			}
+		}
+	}
+
+	return tx.Model(invoice).Select("paid_amount", "credited_amount", "balance", "paid_date", "status").Updates(invoice).Error
+}
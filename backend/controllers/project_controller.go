@@ -1,19 +1,29 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"raborimet-crm/backend/config"
 	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
 )
 
-type ProjectController struct{}
+type ProjectController struct {
+	numberingService       *services.NumberingService
+	projectMaterialService *services.ProjectMaterialService
+	projectCostService     *services.ProjectCostService
+}
 
 func NewProjectController() *ProjectController {
-	return &ProjectController{}
+	return &ProjectController{
+		numberingService:       services.NewNumberingService(),
+		projectMaterialService: services.NewProjectMaterialService(),
+		projectCostService:     services.NewProjectCostService(),
+	}
 }
 
 // @Summary Obtener todos los proyectos
@@ -130,9 +140,6 @@ func (pc *ProjectController) CreateProject(c *gin.Context) {
 		return
 	}
 
-	// Generar código único del proyecto
-	projectCode := pc.generateProjectCode()
-
 	project := models.Project{
 		Name:        req.Name,
 		Description: req.Description,
@@ -150,10 +157,18 @@ func (pc *ProjectController) CreateProject(c *gin.Context) {
 		EstimatedCost: req.EstimatedCost,
 		Progress:    0,
 		Notes:       req.Notes,
-		Code:        projectCode,
 	}
 
-	if err := config.DB.Create(&project).Error; err != nil {
+	// El código se reserva en la misma transacción que crea el proyecto
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		code, err := pc.numberingService.Next(tx, services.SeriesProject)
+		if err != nil {
+			return err
+		}
+		project.Code = code
+		return tx.Create(&project).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear proyecto"})
 		return
 	}
@@ -167,8 +182,108 @@ func (pc *ProjectController) CreateProject(c *gin.Context) {
 	})
 }
 
+// @Summary Duplicar proyecto
+// @Description Crear una copia de un proyecto en planificación con sus materiales (salvo los cancelados) como planificados y un código nuevo. No copia fechas, avance, costos reales, bitácoras, cotizaciones ni facturas. Opcionalmente se asigna a otro cliente
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param project body models.DuplicateProjectRequest false "Cliente y nombre de la copia"
+// @Success 201 {object} models.Project
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/duplicate [post]
+func (pc *ProjectController) DuplicateProject(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.DuplicateProjectRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var original models.Project
+	if err := config.DB.Preload("ProjectMaterials").First(&original, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proyecto no encontrado"})
+		return
+	}
+
+	clientID, _, err := resolveDocumentTarget(config.DB, original.ClientID, nil, req.ClientID, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name := req.Name
+	if name == "" {
+		name = original.Name + " (copia)"
+	}
+
+	materials := make([]models.ProjectMaterial, 0, len(original.ProjectMaterials))
+	for _, material := range original.ProjectMaterials {
+		if material.Status == "cancelled" {
+			continue
+		}
+		copied := models.ProjectMaterial{
+			MaterialID:      material.MaterialID,
+			QuantityPlanned: material.QuantityPlanned,
+			UnitPrice:       material.UnitPrice,
+			Status:          "planned",
+			Notes:           material.Notes,
+		}
+		pc.projectMaterialService.PriceProjectMaterial(&copied)
+		materials = append(materials, copied)
+	}
+
+	project := models.Project{
+		Name:             name,
+		Description:      original.Description,
+		ClientID:         clientID,
+		Status:           "planning",
+		Priority:         original.Priority,
+		Type:             original.Type,
+		ProjectType:      original.ProjectType,
+		Address:          original.Address,
+		City:             original.City,
+		State:            original.State,
+		ZipCode:          original.ZipCode,
+		Budget:           original.Budget,
+		EstimatedCost:    original.EstimatedCost,
+		Progress:         0,
+		Notes:            original.Notes,
+		ProjectMaterials: materials,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		code, err := pc.numberingService.Next(tx, services.SeriesProject)
+		if err != nil {
+			return err
+		}
+		project.Code = code
+		return tx.Create(&project).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al duplicar proyecto"})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("ProjectMaterials.Material").First(&project, project.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Proyecto duplicado exitosamente",
+		"project": project,
+	})
+}
+
 // @Summary Actualizar proyecto
-// @Description Actualizar información de un proyecto
+// @Description Actualizar información de un proyecto. El costo real no se edita: se calcula de materiales usados, horas registradas y otros gastos. Al cancelarlo se cancelan sus materiales pendientes y se libera el stock reservado
 // @Tags projects
 // @Accept json
 // @Produce json
@@ -197,6 +312,8 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 		return
 	}
 
+	cancelling := req.Status != nil && *req.Status == "cancelled" && project.Status != "cancelled"
+
 	// Actualizar campos
 	if req.Name != nil {
 		project.Name = *req.Name
@@ -237,9 +354,6 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 	if req.EstimatedCost != nil {
 		project.EstimatedCost = *req.EstimatedCost
 	}
-	if req.ActualCost != nil {
-		project.ActualCost = *req.ActualCost
-	}
 	if req.Progress != nil {
 		project.Progress = *req.Progress
 	}
@@ -247,8 +361,20 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 		project.Notes = *req.Notes
 	}
 
-	if err := config.DB.Save(&project).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar proyecto"})
+	// Al cancelar el proyecto se cancelan sus materiales pendientes y se libera el stock reservado
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("actual_cost").Save(&project).Error; err != nil {
+			return errors.New("Error al actualizar proyecto")
+		}
+		if cancelling {
+			if err := pc.projectMaterialService.CancelProjectMaterials(tx, project.ID); err != nil {
+				return errors.New("Error al liberar el stock reservado del proyecto")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -262,7 +388,7 @@ func (pc *ProjectController) UpdateProject(c *gin.Context) {
 }
 
 // @Summary Eliminar proyecto
-// @Description Eliminar un proyecto (soft delete)
+// @Description Eliminar un proyecto (soft delete). Sus materiales pendientes se cancelan y se libera el stock reservado
 // @Tags projects
 // @Produce json
 // @Security BearerAuth
@@ -283,8 +409,18 @@ func (pc *ProjectController) DeleteProject(c *gin.Context) {
 		return
 	}
 
-	if err := config.DB.Delete(&project).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar proyecto"})
+	// Los materiales pendientes se cancelan para liberar su stock reservado
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := pc.projectMaterialService.CancelProjectMaterials(tx, project.ID); err != nil {
+			return errors.New("Error al liberar el stock reservado del proyecto")
+		}
+		if err := tx.Delete(&project).Error; err != nil {
+			return errors.New("Error al eliminar proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -321,7 +457,7 @@ func (pc *ProjectController) GetProjectStats(c *gin.Context) {
 	config.DB.Model(&models.Project{}).Select("type, COUNT(*) as count").Group("type").Scan(&typeStats)
 
 	// Calcular presupuesto total y costo real
-	var budgetSum, actualCostSum float64
+	var budgetSum, actualCostSum models.Money
 	config.DB.Model(&models.Project{}).Select("COALESCE(SUM(budget), 0)").Scan(&budgetSum)
 	config.DB.Model(&models.Project{}).Select("COALESCE(SUM(actual_cost), 0)").Scan(&actualCostSum)
 
@@ -340,6 +476,37 @@ func (pc *ProjectController) GetProjectStats(c *gin.Context) {
 	})
 }
 
+// @Summary Obtener costos del proyecto
+// @Description Obtener el desglose del costo real del proyecto (materiales usados, mano de obra registrada y otros gastos) con la variación frente al presupuesto y el porcentaje consumido
+// @Tags projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Success 200 {object} services.ProjectCosts
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/costs [get]
+func (pc *ProjectController) GetProjectCosts(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var project models.Project
+	if err := config.DB.First(&project, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proyecto no encontrado"})
+		return
+	}
+
+	costs, err := pc.projectCostService.Calculate(config.DB, project)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al calcular costos del proyecto"})
+		return
+	}
+
+	c.JSON(http.StatusOK, costs)
+}
+
 // @Summary Obtener materiales del proyecto
 // @Description Obtener lista de materiales asociados a un proyecto
 // @Tags projects
@@ -363,9 +530,3 @@ func (pc *ProjectController) GetProjectMaterials(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"materials": projectMaterials})
 }
-
-// Helper function para generar código único del proyecto
-func (pc *ProjectController) generateProjectCode() string {
-	now := time.Now()
-	return "PRJ-" + now.Format("20060102") + "-" + strconv.FormatInt(now.Unix()%10000, 10)
-}
\ No newline at end of file
@@ -0,0 +1,246 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type ProjectExpenseController struct {
+	projectCostService *services.ProjectCostService
+}
+
+func NewProjectExpenseController() *ProjectExpenseController {
+	return &ProjectExpenseController{
+		projectCostService: services.NewProjectCostService(),
+	}
+}
+
+// @Summary Obtener gastos del proyecto
+// @Description Obtener los otros gastos registrados en un proyecto (subcontratos, equipos, fletes, permisos, etc.)
+// @Tags projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Success 200 {object} map[string]interface{}
+// @Router /projects/{id}/expenses [get]
+func (pec *ProjectExpenseController) GetProjectExpenses(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var expenses []models.ProjectExpense
+	if err := config.DB.Preload("RecordedBy").Where("project_id = ?", uint(id)).Order("date DESC, id DESC").Find(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener gastos del proyecto"})
+		return
+	}
+
+	var total models.Money
+	for _, expense := range expenses {
+		total += expense.Amount
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"expenses": expenses,
+		"total":    total,
+	})
+}
+
+// @Summary Registrar gasto del proyecto
+// @Description Registrar un gasto del proyecto que no es material ni mano de obra. El costo real del proyecto se actualiza
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param expense body models.CreateProjectExpenseRequest true "Datos del gasto"
+// @Success 201 {object} models.ProjectExpense
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/expenses [post]
+func (pec *ProjectExpenseController) CreateProjectExpense(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.CreateProjectExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := config.DB.First(&project, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proyecto no encontrado"})
+		return
+	}
+
+	expense := models.ProjectExpense{
+		ProjectID:    project.ID,
+		Date:         time.Now(),
+		Category:     req.Category,
+		Description:  req.Description,
+		Amount:       req.Amount,
+		Supplier:     req.Supplier,
+		Reference:    req.Reference,
+		RecordedByID: currentUserID(c),
+		Notes:        req.Notes,
+	}
+	if req.Date != nil {
+		expense.Date = *req.Date
+	}
+	if expense.Category == "" {
+		expense.Category = "other"
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&expense).Error; err != nil {
+			return errors.New("Error al registrar gasto del proyecto")
+		}
+		if err := pec.projectCostService.Recalculate(tx, project.ID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Gasto registrado exitosamente",
+		"expense": expense,
+	})
+}
+
+// @Summary Actualizar gasto del proyecto
+// @Description Actualizar un gasto del proyecto. El costo real del proyecto se actualiza
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param expenseId path int true "ID del gasto"
+// @Param expense body models.UpdateProjectExpenseRequest true "Datos actualizados"
+// @Success 200 {object} models.ProjectExpense
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/expenses/{expenseId} [put]
+func (pec *ProjectExpenseController) UpdateProjectExpense(c *gin.Context) {
+	var req models.UpdateProjectExpenseRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var expense models.ProjectExpense
+	if status, err := findProjectExpense(c, &expense); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Actualizar campos
+	if req.Date != nil {
+		expense.Date = *req.Date
+	}
+	if req.Category != nil {
+		expense.Category = *req.Category
+	}
+	if req.Description != nil {
+		expense.Description = *req.Description
+	}
+	if req.Amount != nil {
+		expense.Amount = *req.Amount
+	}
+	if req.Supplier != nil {
+		expense.Supplier = *req.Supplier
+	}
+	if req.Reference != nil {
+		expense.Reference = *req.Reference
+	}
+	if req.Notes != nil {
+		expense.Notes = *req.Notes
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("RecordedBy").Save(&expense).Error; err != nil {
+			return errors.New("Error al actualizar gasto del proyecto")
+		}
+		if err := pec.projectCostService.Recalculate(tx, expense.ProjectID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Gasto actualizado exitosamente",
+		"expense": expense,
+	})
+}
+
+// @Summary Eliminar gasto del proyecto
+// @Description Eliminar un gasto del proyecto (soft delete). El costo real del proyecto se actualiza
+// @Tags projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param expenseId path int true "ID del gasto"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/expenses/{expenseId} [delete]
+func (pec *ProjectExpenseController) DeleteProjectExpense(c *gin.Context) {
+	var expense models.ProjectExpense
+	if status, err := findProjectExpense(c, &expense); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&expense).Error; err != nil {
+			return errors.New("Error al eliminar gasto del proyecto")
+		}
+		if err := pec.projectCostService.Recalculate(tx, expense.ProjectID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Gasto eliminado exitosamente"})
+}
+
+// findProjectExpense carga el gasto del proyecto indicado en la ruta. Devuelve el código
+// HTTP a usar en caso de error
+func findProjectExpense(c *gin.Context, expense *models.ProjectExpense) (int, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("ID inválido")
+	}
+	expenseID, err := strconv.ParseUint(c.Param("expenseId"), 10, 32)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("ID de gasto inválido")
+	}
+
+	if err := config.DB.Where("id = ? AND project_id = ?", uint(expenseID), uint(id)).First(expense).Error; err != nil {
+		return http.StatusNotFound, errors.New("Gasto del proyecto no encontrado")
+	}
+	return http.StatusOK, nil
+}
@@ -0,0 +1,255 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type ProjectMaterialController struct {
+	projectMaterialService *services.ProjectMaterialService
+	projectCostService     *services.ProjectCostService
+}
+
+func NewProjectMaterialController() *ProjectMaterialController {
+	return &ProjectMaterialController{
+		projectMaterialService: services.NewProjectMaterialService(),
+		projectCostService:     services.NewProjectCostService(),
+	}
+}
+
+// @Summary Agregar material al proyecto
+// @Description Planificar un material del catálogo para un proyecto. El precio unitario por defecto es el del catálogo y el costo total se calcula con la cantidad planificada. El material queda en estado planned
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param material body models.CreateProjectMaterialRequest true "Material y cantidad planificada"
+// @Success 201 {object} models.ProjectMaterial
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/materials [post]
+func (pmc *ProjectMaterialController) AddProjectMaterial(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.CreateProjectMaterialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var project models.Project
+	if err := config.DB.First(&project, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Proyecto no encontrado"})
+		return
+	}
+
+	var material models.Material
+	if err := config.DB.First(&material, req.MaterialID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Material no encontrado"})
+		return
+	}
+	if !material.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "El material " + material.Name + " no está activo"})
+		return
+	}
+
+	projectMaterial := models.ProjectMaterial{
+		ProjectID:       project.ID,
+		MaterialID:      material.ID,
+		QuantityPlanned: req.QuantityPlanned,
+		UnitPrice:       material.UnitPrice,
+		Status:          "planned",
+		DeliveryDate:    req.DeliveryDate,
+		Notes:           req.Notes,
+	}
+	if req.UnitPrice != nil {
+		projectMaterial.UnitPrice = *req.UnitPrice
+	}
+	pmc.projectMaterialService.PriceProjectMaterial(&projectMaterial)
+
+	if err := config.DB.Create(&projectMaterial).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al agregar material al proyecto"})
+		return
+	}
+
+	config.DB.Preload("Material").First(&projectMaterial, projectMaterial.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Material agregado al proyecto exitosamente",
+		"material": projectMaterial,
+	})
+}
+
+// @Summary Actualizar material del proyecto
+// @Description Actualizar cantidades, precio, fecha de entrega o notas de un material del proyecto. La cantidad usada solo se registra en materiales entregados o usados; en los usados la diferencia se descuenta o se devuelve al stock. Si hay stock reservado, cambiar la cantidad planificada ajusta la reserva. El costo total y el costo real del proyecto se recalculan
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param materialId path int true "ID del material del proyecto"
+// @Param material body models.UpdateProjectMaterialRequest true "Datos actualizados"
+// @Success 200 {object} models.ProjectMaterial
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/materials/{materialId} [put]
+func (pmc *ProjectMaterialController) UpdateProjectMaterial(c *gin.Context) {
+	var req models.UpdateProjectMaterialRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var projectMaterial models.ProjectMaterial
+	status := http.StatusInternalServerError
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockProjectMaterial(tx, c, &projectMaterial); err != nil {
+			status = code
+			return err
+		}
+		if err := pmc.projectMaterialService.ApplyUpdate(tx, &projectMaterial, req, currentUserID(c)); err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+		if err := tx.Omit("Project", "Material").Save(&projectMaterial).Error; err != nil {
+			return errors.New("Error al actualizar material del proyecto")
+		}
+		if err := pmc.projectCostService.Recalculate(tx, projectMaterial.ProjectID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Material").First(&projectMaterial, projectMaterial.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Material del proyecto actualizado exitosamente",
+		"material": projectMaterial,
+	})
+}
+
+// @Summary Cambiar estado de material del proyecto
+// @Description Avanzar el estado de un material del proyecto: planned → ordered → delivered → used (se pueden saltar pasos, no retroceder), o cancelarlo mientras no se haya usado. Al pedirlo (o entregarlo sin pedido) se reserva la cantidad planificada del stock disponible; al usarlo se descuenta del stock la cantidad usada (la planificada por defecto) y el costo total pasa a calcularse con esa cantidad; al cancelarlo se libera la reserva. Al entregarlo se registra la fecha de entrega (hoy por defecto). El costo real del proyecto se recalcula
+// @Tags projects
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param materialId path int true "ID del material del proyecto"
+// @Param status body models.ChangeProjectMaterialStatusRequest true "Nuevo estado"
+// @Success 200 {object} models.ProjectMaterial
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/materials/{materialId}/status [patch]
+func (pmc *ProjectMaterialController) ChangeProjectMaterialStatus(c *gin.Context) {
+	var req models.ChangeProjectMaterialStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var projectMaterial models.ProjectMaterial
+	status := http.StatusInternalServerError
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockProjectMaterial(tx, c, &projectMaterial); err != nil {
+			status = code
+			return err
+		}
+		if err := pmc.projectMaterialService.ChangeStatus(tx, &projectMaterial, req, currentUserID(c)); err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+		if err := tx.Model(&projectMaterial).Select("status", "delivery_date", "quantity_used", "quantity_reserved", "total_cost").Updates(&projectMaterial).Error; err != nil {
+			return errors.New("Error al cambiar estado del material")
+		}
+		if err := pmc.projectCostService.Recalculate(tx, projectMaterial.ProjectID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Material").First(&projectMaterial, projectMaterial.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Estado del material actualizado exitosamente",
+		"material": projectMaterial,
+	})
+}
+
+// @Summary Eliminar material del proyecto
+// @Description Quitar un material del proyecto y liberar su stock reservado. Solo se pueden quitar materiales planificados, pedidos o cancelados
+// @Tags projects
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del proyecto"
+// @Param materialId path int true "ID del material del proyecto"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /projects/{id}/materials/{materialId} [delete]
+func (pmc *ProjectMaterialController) DeleteProjectMaterial(c *gin.Context) {
+	var projectMaterial models.ProjectMaterial
+	status := http.StatusInternalServerError
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockProjectMaterial(tx, c, &projectMaterial); err != nil {
+			status = code
+			return err
+		}
+		if projectMaterial.Status != "planned" && projectMaterial.Status != "ordered" && projectMaterial.Status != "cancelled" {
+			status = http.StatusBadRequest
+			return errors.New("Solo se pueden quitar materiales planificados, pedidos o cancelados")
+		}
+		if err := pmc.projectMaterialService.ReleaseReservation(tx, &projectMaterial); err != nil {
+			return errors.New("Error al liberar el stock reservado")
+		}
+		if err := tx.Delete(&projectMaterial).Error; err != nil {
+			return errors.New("Error al quitar material del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Material quitado del proyecto exitosamente"})
+}
+
+// lockProjectMaterial carga y bloquea el material del proyecto indicado en la ruta.
+// Devuelve el código HTTP a usar en caso de error
+func lockProjectMaterial(tx *gorm.DB, c *gin.Context, projectMaterial *models.ProjectMaterial) (int, error) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("ID inválido")
+	}
+	materialID, err := strconv.ParseUint(c.Param("materialId"), 10, 32)
+	if err != nil {
+		return http.StatusBadRequest, errors.New("ID de material inválido")
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND project_id = ?", uint(materialID), uint(id)).First(projectMaterial).Error; err != nil {
+		return http.StatusNotFound, errors.New("Material del proyecto no encontrado")
+	}
+	return http.StatusOK, nil
+}
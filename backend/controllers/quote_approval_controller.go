@@ -0,0 +1,348 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type QuoteApprovalController struct {
+	linkService  *services.QuoteLinkService
+	quoteService *services.QuoteService
+	pdfService   *services.PDFService
+}
+
+func NewQuoteApprovalController() *QuoteApprovalController {
+	return &QuoteApprovalController{
+		linkService:  services.NewQuoteLinkService(),
+		quoteService: services.NewQuoteService(),
+		pdfService:   services.NewPDFService(),
+	}
+}
+
+// @Summary Generar enlace de aprobación
+// @Description Generar un enlace público, firmado y con vencimiento, para que el cliente vea la cotización y la acepte o rechace. Si la cotización está en borrador se marca como enviada
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param link body models.CreateQuoteApprovalLinkRequest false "Vigencia del enlace en días"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 503 {object} map[string]string
+// @Router /quotes/{id}/approval-link [post]
+func (qac *QuoteApprovalController) CreateApprovalLink(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.CreateQuoteApprovalLinkRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	if err := qac.linkService.CheckConfigured(); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+		return
+	}
+
+	ttl := qac.linkService.DefaultTTL()
+	if req.ExpiresInDays != nil {
+		ttl = time.Duration(*req.ExpiresInDays) * 24 * time.Hour
+	}
+
+	var quote models.Quote
+	var token string
+	var expiresAt time.Time
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Cotización no encontrada")
+		}
+
+		if quote.Status == "draft" {
+			if err := qac.quoteService.ChangeStatus(tx, &quote, "sent", ""); err != nil {
+				status = http.StatusBadRequest
+				return err
+			}
+		}
+		if quote.Status != "sent" {
+			status = http.StatusBadRequest
+			return fmt.Errorf("No se puede enviar a aprobación una cotización en estado %s", quote.Status)
+		}
+
+		var err error
+		if token, expiresAt, err = qac.linkService.GenerateApprovalToken(&quote, ttl); err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Enlace de aprobación generado exitosamente",
+		"token":      token,
+		"url":        qac.linkService.ApprovalURL(token),
+		"expires_at": expiresAt,
+		"revision":   quote.Revision,
+	})
+}
+
+// @Summary Obtener aprobaciones de una cotización
+// @Description Obtener las decisiones registradas por el cliente desde el enlace público
+// @Tags quotes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Success 200 {array} models.QuoteApproval
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/approvals [get]
+func (qac *QuoteApprovalController) GetQuoteApprovals(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.First(&quote, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	}
+
+	var approvals []models.QuoteApproval
+	config.DB.Where("quote_id = ?", quote.ID).Order("signed_at DESC").Find(&approvals)
+
+	c.JSON(http.StatusOK, approvals)
+}
+
+// @Summary Ver cotización desde el enlace público
+// @Description Obtener la cotización asociada a un enlace de aprobación. No requiere autenticación
+// @Tags public
+// @Produce json
+// @Param token path string true "Token del enlace"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Router /public/quotes/{token} [get]
+func (qac *QuoteApprovalController) GetPublicQuote(c *gin.Context) {
+	quote, status, err := qac.loadLinkedQuote(c.Param("token"))
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, publicQuoteView(quote))
+}
+
+// @Summary Descargar PDF desde el enlace público
+// @Description Descargar el PDF de la cotización asociada a un enlace de aprobación. No requiere autenticación
+// @Tags public
+// @Produce application/pdf
+// @Param token path string true "Token del enlace"
+// @Success 200 {file} file
+// @Failure 401 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Router /public/quotes/{token}/pdf [get]
+func (qac *QuoteApprovalController) GetPublicQuotePDF(c *gin.Context) {
+	quote, status, err := qac.loadLinkedQuote(c.Param("token"))
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, err := qac.pdfService.RenderQuote(quote)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al generar PDF de la cotización"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.pdf\"", quote.QuoteNumber))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// @Summary Aceptar cotización desde el enlace público
+// @Description Aceptar la cotización registrando el nombre del firmante, su IP y la fecha. No requiere autenticación
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "Token del enlace"
+// @Param approval body models.AcceptQuoteRequest true "Datos del firmante"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Router /public/quotes/{token}/accept [post]
+func (qac *QuoteApprovalController) AcceptPublicQuote(c *gin.Context) {
+	var req models.AcceptQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	qac.decide(c, models.QuoteApproval{
+		Decision:    "accepted",
+		SignerName:  req.SignerName,
+		SignerEmail: req.SignerEmail,
+	}, "Cotización aceptada exitosamente")
+}
+
+// @Summary Rechazar cotización desde el enlace público
+// @Description Rechazar la cotización indicando el motivo; se registra el nombre del firmante, su IP y la fecha. No requiere autenticación
+// @Tags public
+// @Accept json
+// @Produce json
+// @Param token path string true "Token del enlace"
+// @Param rejection body models.RejectQuoteRequest true "Datos del firmante y motivo"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Failure 401 {object} map[string]string
+// @Failure 410 {object} map[string]string
+// @Router /public/quotes/{token}/reject [post]
+func (qac *QuoteApprovalController) RejectPublicQuote(c *gin.Context) {
+	var req models.RejectQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	qac.decide(c, models.QuoteApproval{
+		Decision:    "rejected",
+		SignerName:  req.SignerName,
+		SignerEmail: req.SignerEmail,
+		Reason:      req.Reason,
+	}, "Cotización rechazada")
+}
+
+// decide aplica la decisión del cliente con la misma validación de estados que
+// ChangeQuoteStatus y la deja registrada junto con los datos del firmante
+func (qac *QuoteApprovalController) decide(c *gin.Context, approval models.QuoteApproval, message string) {
+	claims, err := qac.linkService.ParseApprovalToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	var quote models.Quote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, claims.QuoteID).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Cotización no encontrada")
+		}
+		if quote.Revision != claims.Revision {
+			status = http.StatusGone
+			return errors.New("La cotización fue modificada después de enviarse; solicite un nuevo enlace")
+		}
+
+		if err := qac.quoteService.ChangeStatus(tx, &quote, approval.Decision, approval.Reason); err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+
+		approval.QuoteID = quote.ID
+		approval.Revision = quote.Revision
+		approval.IPAddress = c.ClientIP()
+		approval.UserAgent = c.Request.UserAgent()
+		approval.SignedAt = time.Now()
+		if err := tx.Create(&approval).Error; err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al registrar la decisión")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   message,
+		"status":    quote.Status,
+		"signed_at": approval.SignedAt,
+	})
+}
+
+// loadLinkedQuote valida el enlace y carga la cotización con su cliente e items
+func (qac *QuoteApprovalController) loadLinkedQuote(token string) (*models.Quote, int, error) {
+	claims, err := qac.linkService.ParseApprovalToken(token)
+	if err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+
+	var quote models.Quote
+	if err := config.DB.Preload("Client").Preload("Items", orderedQuoteItems).First(&quote, claims.QuoteID).Error; err != nil {
+		return nil, http.StatusNotFound, errors.New("Cotización no encontrada")
+	}
+	if quote.Revision != claims.Revision {
+		return nil, http.StatusGone, errors.New("La cotización fue modificada después de enviarse; solicite un nuevo enlace")
+	}
+	return &quote, http.StatusOK, nil
+}
+
+// publicQuoteView expone solo los datos de la cotización que el cliente debe ver
+func publicQuoteView(quote *models.Quote) gin.H {
+	items := make([]gin.H, 0, len(quote.Items))
+	for _, item := range quote.Items {
+		items = append(items, gin.H{
+			"description":      item.Description,
+			"quantity":         item.Quantity,
+			"unit":             item.Unit,
+			"unit_price":       item.UnitPrice,
+			"discount_type":    item.DiscountType,
+			"discount_percent": item.DiscountPercent,
+			"discount":         item.Discount,
+			"total":            item.Total,
+			"notes":            item.Notes,
+		})
+	}
+
+	return gin.H{
+		"quote_number":       quote.QuoteNumber,
+		"revision":           quote.Revision,
+		"client_name":        quote.Client.Name,
+		"title":              quote.Title,
+		"description":        quote.Description,
+		"status":             quote.Status,
+		"valid_until":        quote.ValidUntil,
+		"currency":           quote.Currency,
+		"subtotal":           quote.Subtotal,
+		"discount_type":      quote.DiscountType,
+		"discount_percent":   quote.DiscountPercent,
+		"discount":           quote.Discount,
+		"tax_rate":           quote.TaxRate,
+		"tax_amount":         quote.TaxAmount,
+		"withholding_amount": quote.WithholdingAmount,
+		"tax_breakdown":      quote.TaxBreakdown,
+		"total":              quote.Total,
+		"notes":              quote.Notes,
+		"terms":              quote.Terms,
+		"items":              items,
+		"sent_at":            quote.SentAt,
+		"accepted_at":        quote.AcceptedAt,
+		"rejected_at":        quote.RejectedAt,
+	}
+}
@@ -1,20 +1,42 @@
 package controllers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"raborimet-crm/backend/config"
 	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
 )
 
-type QuoteController struct{}
+type QuoteController struct {
+	numberingService *services.NumberingService
+	pdfService       *services.PDFService
+	versionService   *services.QuoteVersionService
+	quoteService     *services.QuoteService
+	costingService   *services.QuoteCostingService
+	templateService  *services.QuoteTemplateService
+	taxService       *services.TaxService
+	pricingService   *services.PricingService
+}
 
 func NewQuoteController() *QuoteController {
-	return &QuoteController{}
+	return &QuoteController{
+		numberingService: services.NewNumberingService(),
+		pdfService:       services.NewPDFService(),
+		versionService:   services.NewQuoteVersionService(),
+		quoteService:     services.NewQuoteService(),
+		costingService:   services.NewQuoteCostingService(),
+		templateService:  services.NewQuoteTemplateService(),
+		taxService:       services.NewTaxService(),
+		pricingService:   services.NewPricingService(),
+	}
 }
 
 // @Summary Obtener todas las cotizaciones
@@ -36,7 +58,7 @@ func (qc *QuoteController) GetQuotes(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
-	query := config.DB.Model(&models.Quote{}).Preload("Client").Preload("Project").Preload("QuoteItems")
+	query := config.DB.Model(&models.Quote{}).Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems)
 
 	if status != "" {
 		query = query.Where("status = ?", status)
@@ -77,7 +99,7 @@ func (qc *QuoteController) GetQuote(c *gin.Context) {
 	}
 
 	var quote models.Quote
-	if err := config.DB.Preload("Client").Preload("Project").Preload("QuoteItems").First(&quote, uint(id)).Error; err != nil {
+	if err := config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, uint(id)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
 		return
 	}
@@ -85,8 +107,40 @@ func (qc *QuoteController) GetQuote(c *gin.Context) {
 	c.JSON(http.StatusOK, quote)
 }
 
+// @Summary Descargar cotización en PDF
+// @Description Generar el PDF de una cotización con los datos de la empresa, el cliente, los conceptos y los totales
+// @Tags quotes
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/pdf [get]
+func (qc *QuoteController) GetQuotePDF(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.Preload("Client").Preload("Items", orderedQuoteItems).First(&quote, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	}
+
+	content, err := qc.pdfService.RenderQuote(&quote)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al generar PDF de la cotización"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=\"%s.pdf\"", quote.QuoteNumber))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
 // @Summary Crear nueva cotización
-// @Description Crear una nueva cotización
+// @Description Crear una nueva cotización. Los items pueden referir a materiales (material_id) o a tarifas de mano de obra (work_type) del catálogo. La cotización y cada item admiten un descuento fijo (discount) o porcentual (discount_type percent y discount_percent), que se resta antes de impuestos
 // @Tags quotes
 // @Accept json
 // @Produce json
@@ -102,63 +156,237 @@ func (qc *QuoteController) CreateQuote(c *gin.Context) {
 		return
 	}
 
-	// Generar número de cotización
-	quoteNumber := qc.generateQuoteNumber()
+	quote := models.Quote{
+		ClientID:        req.ClientID,
+		ProjectID:       req.ProjectID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Status:          "draft",
+		ValidUntil:      req.ValidUntil,
+		Currency:        req.Currency,
+		TaxRate:         req.TaxRate,
+		TaxCode:         req.TaxCode,
+		DiscountType:    req.DiscountType,
+		DiscountPercent: req.DiscountPercent,
+		DiscountFixed:   req.Discount,
+		MarkupPercent:   req.MarkupPercent,
+		Notes:           req.Notes,
+		Terms:           req.Terms,
+	}
+
+	status := http.StatusInternalServerError
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		status, err = qc.createQuote(tx, &quote, req.Items, currentUserID(c), "Creación")
+		return err
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Cargar la cotización completa
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
 
-	// Calcular totales
-	subtotal := 0.0
-	for _, item := range req.Items {
-		subtotal += item.Quantity * item.UnitPrice
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Cotización creada exitosamente",
+		"quote":   quote,
+	})
+}
+
+// @Summary Crear cotización desde plantilla
+// @Description Crear una cotización en borrador a partir de una plantilla, calculando las cantidades de los items con los parámetros indicados (por ejemplo, el área en m2). Los parámetros omitidos toman su valor por defecto
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la plantilla"
+// @Param quote body models.CreateQuoteFromTemplateRequest true "Cliente, proyecto y parámetros"
+// @Success 201 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/from-template/{id} [post]
+func (qc *QuoteController) CreateQuoteFromTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
 	}
 
-	taxAmount := subtotal * (req.TaxRate / 100)
-	total := subtotal + taxAmount - req.Discount
+	var req models.CreateQuoteFromTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var template models.QuoteTemplate
+	if status, err := loadQuoteTemplate(config.DB, uint(id), &template); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	parameters, err := qc.templateService.ResolveParameters(&template, req.Parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	items, err := qc.templateService.BuildItemRequests(&template, parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	validUntil := req.ValidUntil
+	if validUntil == nil && template.ValidDays > 0 {
+		date := time.Now().AddDate(0, 0, template.ValidDays)
+		validUntil = &date
+	}
 
 	quote := models.Quote{
-		QuoteNumber: quoteNumber,
-		ClientID:    req.ClientID,
-		ProjectID:   req.ProjectID,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      "draft",
-		ValidUntil:  req.ValidUntil,
-		Subtotal:    subtotal,
-		TaxRate:     req.TaxRate,
-		TaxAmount:   taxAmount,
-		Discount:    req.Discount,
-		Total:       total,
-		Notes:       req.Notes,
-		Terms:       req.Terms,
-	}
-
-	if err := config.DB.Create(&quote).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear cotización"})
+		ClientID:      req.ClientID,
+		ProjectID:     req.ProjectID,
+		Title:         template.Title,
+		Description:   template.Description,
+		Status:        "draft",
+		ValidUntil:    validUntil,
+		TaxRate:       template.TaxRate,
+		TaxCode:       template.TaxCode,
+		MarkupPercent: template.MarkupPercent,
+		Notes:         template.Notes,
+		Terms:         template.Terms,
+	}
+	if req.Title != "" {
+		quote.Title = req.Title
+	}
+
+	status := http.StatusInternalServerError
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		status, err = qc.createQuote(tx, &quote, items, currentUserID(c), fmt.Sprintf("Creación desde la plantilla %s", template.Name))
+		return err
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Crear items de la cotización
-	for _, itemReq := range req.Items {
-		item := models.QuoteItem{
-			QuoteID:     quote.ID,
-			Description: itemReq.Description,
-			Quantity:    itemReq.Quantity,
-			UnitPrice:   itemReq.UnitPrice,
-			Total:       itemReq.Quantity * itemReq.UnitPrice,
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Cotización creada exitosamente",
+		"quote":      quote,
+		"parameters": parameters,
+	})
+}
+
+// @Summary Duplicar cotización
+// @Description Crear una copia en borrador de una cotización con sus items y un número nuevo. Opcionalmente se asigna a otro cliente y/o proyecto; si cambia el cliente y no se indica proyecto, la copia queda sin proyecto
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param quote body models.DuplicateQuoteRequest false "Cliente y proyecto de la copia"
+// @Success 201 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/duplicate [post]
+func (qc *QuoteController) DuplicateQuote(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.DuplicateQuoteRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
 		}
-		config.DB.Create(&item)
 	}
 
-	// Cargar la cotización completa
-	config.DB.Preload("Client").Preload("Project").Preload("QuoteItems").First(&quote, quote.ID)
+	var original models.Quote
+	if err := config.DB.Preload("Items", orderedQuoteItems).First(&original, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	}
+
+	clientID, projectID, err := resolveDocumentTarget(config.DB, original.ClientID, original.ProjectID, req.ClientID, req.ProjectID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// La copia conserva el plazo de validez de la original, contado desde hoy
+	var validUntil *time.Time
+	if original.ValidUntil != nil {
+		if validity := original.ValidUntil.Sub(original.CreatedAt); validity > 0 {
+			date := time.Now().Add(validity)
+			validUntil = &date
+		}
+	}
+
+	quote := models.Quote{
+		ClientID:        clientID,
+		ProjectID:       projectID,
+		Title:           original.Title,
+		Description:     original.Description,
+		Status:          "draft",
+		ValidUntil:      validUntil,
+		Currency:        original.Currency,
+		TaxRate:         original.TaxRate,
+		TaxCode:         original.TaxCode,
+		DiscountType:    original.DiscountType,
+		DiscountPercent: original.DiscountPercent,
+		DiscountFixed:   original.DiscountFixed,
+		MarkupPercent:   original.MarkupPercent,
+		Notes:           original.Notes,
+		Terms:           original.Terms,
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		number, err := qc.numberingService.Next(tx, services.SeriesQuote)
+		if err != nil {
+			return err
+		}
+		quote.QuoteNumber = number
+
+		if err := tx.Create(&quote).Error; err != nil {
+			return err
+		}
+
+		for _, item := range original.Items {
+			item.ID = 0
+			item.QuoteID = quote.ID
+			item.Material = nil
+			if err := tx.Create(&item).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := recalculateQuoteTotals(tx, qc.pricingService, &quote); err != nil {
+			return err
+		}
+
+		_, err = qc.versionService.Record(tx, quote.ID, currentUserID(c), fmt.Sprintf("Duplicada de %s", original.QuoteNumber))
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al duplicar cotización"})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Cotización creada exitosamente",
+		"message": "Cotización duplicada exitosamente",
 		"quote":   quote,
 	})
 }
 
 // @Summary Actualizar cotización
-// @Description Actualizar información de una cotización
+// @Description Actualizar información de una cotización en borrador. Al cambiar la tasa, el código de impuesto o el descuento se recalculan los totales. El estado se cambia con PATCH /quotes/{id}/status
 // @Tags quotes
 // @Accept json
 // @Produce json
@@ -181,30 +409,89 @@ func (qc *QuoteController) UpdateQuote(c *gin.Context) {
 		return
 	}
 
+	// Solo las cotizaciones en borrador se pueden editar: una enviada ya la vio el cliente y
+	// una aceptada ya trasladó sus importes al presupuesto del proyecto
 	var quote models.Quote
-	if err := config.DB.First(&quote, uint(id)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+	userID := currentUserID(c)
+	status := http.StatusInternalServerError
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockEditableQuote(tx, uint(id), &quote); err != nil {
+			status = code
+			if code == http.StatusBadRequest {
+				return errors.New("Solo se pueden editar cotizaciones en borrador")
+			}
+			return err
+		}
+		if err := qc.applyQuoteUpdate(tx, &quote, req); err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+
+		// Cada cambio de contenido queda registrado como una nueva revisión
+		if err := qc.versionService.EnsureBaseline(tx, quote.ID, userID); err != nil {
+			return errors.New("Error al actualizar cotización")
+		}
+		if err := tx.Omit("revision", "status", "sent_at", "accepted_at", "rejected_at", "rejection_reason", "expired_at").Save(&quote).Error; err != nil {
+			return errors.New("Error al actualizar cotización")
+		}
+		// Recalcular totales si es necesario
+		if req.TaxRate != nil || req.TaxCode != nil || req.DiscountType != nil || req.DiscountPercent != nil || req.Discount != nil {
+			if err := recalculateQuoteTotals(tx, qc.pricingService, &quote); err != nil {
+				return errors.New("Error al recalcular totales de la cotización")
+			}
+		}
+		if _, err := qc.versionService.Record(tx, quote.ID, userID, "Actualización de la cotización"); err != nil {
+			return errors.New("Error al actualizar cotización")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Actualizar campos
+	config.DB.First(&quote, quote.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Cotización actualizada exitosamente",
+		"quote":   quote,
+	})
+}
+
+// applyQuoteUpdate aplica a la cotización los campos indicados. No guarda los cambios
+func (qc *QuoteController) applyQuoteUpdate(tx *gorm.DB, quote *models.Quote, req models.UpdateQuoteRequest) error {
 	if req.Title != nil {
 		quote.Title = *req.Title
 	}
 	if req.Description != nil {
 		quote.Description = *req.Description
 	}
-	if req.Status != nil {
-		quote.Status = *req.Status
-	}
 	if req.ValidUntil != nil {
 		quote.ValidUntil = req.ValidUntil
 	}
+	if req.Currency != nil {
+		quote.Currency = services.NormalizeCurrency(*req.Currency)
+	}
 	if req.TaxRate != nil {
 		quote.TaxRate = *req.TaxRate
 	}
+	if req.TaxCode != nil {
+		if err := qc.taxService.ValidateCode(tx, *req.TaxCode); err != nil {
+			return err
+		}
+		quote.TaxCode = *req.TaxCode
+	}
+	if req.DiscountType != nil {
+		quote.DiscountType = *req.DiscountType
+	}
+	if req.DiscountPercent != nil {
+		quote.DiscountPercent = *req.DiscountPercent
+	}
 	if req.Discount != nil {
-		quote.Discount = *req.Discount
+		quote.DiscountFixed = *req.Discount
+	}
+	if req.MarkupPercent != nil {
+		quote.MarkupPercent = *req.MarkupPercent
 	}
 	if req.Notes != nil {
 		quote.Notes = *req.Notes
@@ -212,24 +499,7 @@ func (qc *QuoteController) UpdateQuote(c *gin.Context) {
 	if req.Terms != nil {
 		quote.Terms = *req.Terms
 	}
-
-	// Recalcular totales si es necesario
-	if req.TaxRate != nil || req.Discount != nil {
-		taxAmount := quote.Subtotal * (quote.TaxRate / 100)
-		total := quote.Subtotal + taxAmount - quote.Discount
-		quote.TaxAmount = taxAmount
-		quote.Total = total
-	}
-
-	if err := config.DB.Save(&quote).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar cotización"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"message": "Cotización actualizada exitosamente",
-		"quote":   quote,
-	})
+	return nil
 }
 
 // @Summary Eliminar cotización
@@ -288,12 +558,12 @@ func (qc *QuoteController) GetQuoteStats(c *gin.Context) {
 	config.DB.Model(&models.Quote{}).Where("status = ?", "rejected").Count(&stats.RejectedQuotes)
 
 	// Valor total de cotizaciones
-	var totalValue float64
+	var totalValue models.Money
 	config.DB.Model(&models.Quote{}).Select("COALESCE(SUM(total), 0)").Scan(&totalValue)
 	stats.TotalValue = totalValue
 
 	// Valor de cotizaciones aceptadas
-	var acceptedValue float64
+	var acceptedValue models.Money
 	config.DB.Model(&models.Quote{}).Where("status = ?", "accepted").Select("COALESCE(SUM(total), 0)").Scan(&acceptedValue)
 	stats.AcceptedValue = acceptedValue
 
@@ -305,27 +575,14 @@ func (qc *QuoteController) GetQuoteStats(c *gin.Context) {
 	c.JSON(http.StatusOK, stats)
 }
 
-// Función auxiliar para generar número de cotización
-func (qc *QuoteController) generateQuoteNumber() string {
-	now := time.Now()
-	year := now.Year()
-	month := int(now.Month())
-
-	// Contar cotizaciones del mes actual
-	var count int64
-	config.DB.Model(&models.Quote{}).Where("created_at >= DATE_TRUNC('month', CURRENT_DATE)").Count(&count)
-
-	return fmt.Sprintf("COT-%d%02d-%04d", year, month, count+1)
-}
-
 // @Summary Cambiar estado de cotización
-// @Description Cambiar el estado de una cotización (draft, sent, accepted, rejected)
+// @Description Cambiar el estado de una cotización según el flujo permitido: draft → sent → accepted/rejected/expired; sent, rejected y expired pueden volver a draft. Una cotización aceptada no cambia de estado. Al aceptarla se crea o actualiza el proyecto según la configuración (QUOTE_ACCEPT_*)
 // @Tags quotes
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path int true "ID de la cotización"
-// @Param status body map[string]string true "Nuevo estado"
+// @Param status body models.ChangeQuoteStatusRequest true "Nuevo estado (y motivo si se rechaza)"
 // @Success 200 {object} models.Quote
 // @Failure 400 {object} map[string]string
 // @Router /quotes/{id}/status [patch]
@@ -336,38 +593,29 @@ func (qc *QuoteController) ChangeQuoteStatus(c *gin.Context) {
 		return
 	}
 
-	var req struct {
-		Status string `json:"status" binding:"required"`
-	}
+	var req models.ChangeQuoteStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Validar estado
-	validStatuses := []string{"draft", "sent", "accepted", "rejected"}
-	valid := false
-	for _, status := range validStatuses {
-		if req.Status == status {
-			valid = true
-			break
-		}
-	}
-	if !valid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Estado inválido"})
-		return
-	}
-
 	var quote models.Quote
-	if err := config.DB.First(&quote, uint(id)).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
-		return
-	}
+	status := http.StatusInternalServerError
 
-	quote.Status = req.Status
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Cotización no encontrada")
+		}
 
-	if err := config.DB.Save(&quote).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar estado"})
+		if err := qc.quoteService.ChangeStatus(tx, &quote, req.Status, req.Reason); err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -375,4 +623,83 @@ func (qc *QuoteController) ChangeQuoteStatus(c *gin.Context) {
 		"message": "Estado actualizado exitosamente",
 		"quote":   quote,
 	})
-}
\ No newline at end of file
+}
+
+// createQuote reserva el número, crea la cotización con sus items (con los datos del
+// catálogo si corresponde), calcula los totales y registra la revisión 1, dentro de tx.
+// Devuelve el código HTTP a usar si falla
+func (qc *QuoteController) createQuote(tx *gorm.DB, quote *models.Quote, items []models.CreateQuoteItemRequest, userID *uint, note string) (int, error) {
+	number, err := qc.numberingService.Next(tx, services.SeriesQuote)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Error al crear cotización")
+	}
+	quote.QuoteNumber = number
+	quote.Currency = services.NormalizeCurrency(quote.Currency)
+
+	if err := qc.taxService.ValidateCode(tx, quote.TaxCode); err != nil {
+		return http.StatusBadRequest, err
+	}
+	if err := tx.Create(quote).Error; err != nil {
+		return http.StatusInternalServerError, errors.New("Error al crear cotización")
+	}
+
+	for i, itemReq := range items {
+		item, err := qc.costingService.BuildItem(tx, quote, itemReq, i+1)
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("Item %d: %s", i+1, err.Error())
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			return http.StatusInternalServerError, errors.New("Error al crear cotización")
+		}
+	}
+
+	if err := recalculateQuoteTotals(tx, qc.pricingService, quote); err != nil {
+		return http.StatusInternalServerError, errors.New("Error al crear cotización")
+	}
+
+	if _, err := qc.versionService.Record(tx, quote.ID, userID, note); err != nil {
+		return http.StatusInternalServerError, errors.New("Error al crear cotización")
+	}
+	return http.StatusCreated, nil
+}
+
+// validateClientProject verifica que el proyecto indicado, si lo hay, exista y pertenezca al cliente
+func validateClientProject(db *gorm.DB, clientID uint, projectID *uint) error {
+	if projectID == nil {
+		return nil
+	}
+	var project models.Project
+	if err := db.First(&project, *projectID).Error; err != nil {
+		return errors.New("Proyecto no encontrado")
+	}
+	if project.ClientID != clientID {
+		return errors.New("El proyecto no pertenece al cliente")
+	}
+	return nil
+}
+
+// resolveDocumentTarget valida el cliente y el proyecto de un documento duplicado. Si
+// cambia el cliente y no se indica proyecto, el documento queda sin proyecto
+func resolveDocumentTarget(db *gorm.DB, clientID uint, projectID *uint, newClientID, newProjectID *uint) (uint, *uint, error) {
+	if newClientID != nil && *newClientID != clientID {
+		var client models.Client
+		if err := db.First(&client, *newClientID).Error; err != nil {
+			return 0, nil, errors.New("Cliente no encontrado")
+		}
+		clientID = client.ID
+		projectID = nil
+	}
+
+	if newProjectID != nil {
+		var project models.Project
+		if err := db.First(&project, *newProjectID).Error; err != nil {
+			return 0, nil, errors.New("Proyecto no encontrado")
+		}
+		if project.ClientID != clientID {
+			return 0, nil, errors.New("El proyecto no pertenece al cliente")
+		}
+		projectID = &project.ID
+	}
+
+	return clientID, projectID, nil
+}
@@ -0,0 +1,400 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type QuoteItemController struct {
+	versionService *services.QuoteVersionService
+	costingService *services.QuoteCostingService
+	taxService     *services.TaxService
+	pricingService *services.PricingService
+}
+
+func NewQuoteItemController() *QuoteItemController {
+	return &QuoteItemController{
+		versionService: services.NewQuoteVersionService(),
+		costingService: services.NewQuoteCostingService(),
+		taxService:     services.NewTaxService(),
+		pricingService: services.NewPricingService(),
+	}
+}
+
+// @Summary Agregar item a cotización
+// @Description Agregar un item al final de una cotización en borrador. Con material_id o work_type la descripción, unidad y costo se toman del catálogo y el precio se calcula con el margen. Los totales se recalculan
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param item body models.CreateQuoteItemRequest true "Datos del item"
+// @Success 201 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/items [post]
+func (qic *QuoteItemController) AddQuoteItem(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.CreateQuoteItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := currentUserID(c)
+
+	var quote models.Quote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockEditableQuote(tx, uint(id), &quote); err != nil {
+			status = code
+			return err
+		}
+		if err := qic.versionService.EnsureBaseline(tx, quote.ID, userID); err != nil {
+			return errors.New("Error al registrar revisión de la cotización")
+		}
+
+		var lastPosition int
+		tx.Model(&models.QuoteItem{}).Where("quote_id = ?", quote.ID).Select("COALESCE(MAX(position), 0)").Scan(&lastPosition)
+
+		item, err := qic.costingService.BuildItem(tx, &quote, req, lastPosition+1)
+		if err != nil {
+			status = http.StatusBadRequest
+			return err
+		}
+		if err := tx.Create(&item).Error; err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al agregar item")
+		}
+
+		if err := recalculateQuoteTotals(tx, qic.pricingService, &quote); err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al recalcular totales de la cotización")
+		}
+		return recordQuoteRevision(tx, qic.versionService, quote.ID, userID, "Item agregado")
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Item agregado exitosamente",
+		"quote":   quote,
+	})
+}
+
+// @Summary Actualizar item de cotización
+// @Description Actualizar un item de una cotización en borrador. Un nuevo margen recalcula el precio; un nuevo precio recalcula el margen. Los totales se recalculan
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param itemId path int true "ID del item"
+// @Param item body models.UpdateQuoteItemRequest true "Datos actualizados del item"
+// @Success 200 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/items/{itemId} [put]
+func (qic *QuoteItemController) UpdateQuoteItem(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de item inválido"})
+		return
+	}
+
+	var req models.UpdateQuoteItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := currentUserID(c)
+
+	var quote models.Quote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockEditableQuote(tx, uint(id), &quote); err != nil {
+			status = code
+			return err
+		}
+		if err := qic.versionService.EnsureBaseline(tx, quote.ID, userID); err != nil {
+			return errors.New("Error al registrar revisión de la cotización")
+		}
+
+		var item models.QuoteItem
+		if err := tx.Where("id = ? AND quote_id = ?", uint(itemID), quote.ID).First(&item).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Item no encontrado")
+		}
+		if req.TaxCode != nil {
+			if err := qic.taxService.ValidateCode(tx, *req.TaxCode); err != nil {
+				status = http.StatusBadRequest
+				return err
+			}
+		}
+
+		// Actualizar campos
+		if req.Description != nil {
+			item.Description = *req.Description
+		}
+		if req.Quantity != nil {
+			item.Quantity = *req.Quantity
+		}
+		if req.Unit != nil {
+			item.Unit = *req.Unit
+		}
+		if req.Notes != nil {
+			item.Notes = *req.Notes
+		}
+		qic.costingService.ApplyItemUpdate(&item, req)
+
+		if err := tx.Save(&item).Error; err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al actualizar item")
+		}
+
+		if err := recalculateQuoteTotals(tx, qic.pricingService, &quote); err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al recalcular totales de la cotización")
+		}
+		return recordQuoteRevision(tx, qic.versionService, quote.ID, userID, "Item actualizado")
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item actualizado exitosamente",
+		"quote":   quote,
+	})
+}
+
+// @Summary Eliminar item de cotización
+// @Description Eliminar un item de una cotización en borrador. Debe quedar al menos un item
+// @Tags quotes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param itemId path int true "ID del item"
+// @Success 200 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/items/{itemId} [delete]
+func (qic *QuoteItemController) DeleteQuoteItem(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("itemId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID de item inválido"})
+		return
+	}
+
+	userID := currentUserID(c)
+
+	var quote models.Quote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockEditableQuote(tx, uint(id), &quote); err != nil {
+			status = code
+			return err
+		}
+		if err := qic.versionService.EnsureBaseline(tx, quote.ID, userID); err != nil {
+			return errors.New("Error al registrar revisión de la cotización")
+		}
+
+		var item models.QuoteItem
+		if err := tx.Where("id = ? AND quote_id = ?", uint(itemID), quote.ID).First(&item).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Item no encontrado")
+		}
+
+		var itemCount int64
+		tx.Model(&models.QuoteItem{}).Where("quote_id = ?", quote.ID).Count(&itemCount)
+		if itemCount <= 1 {
+			status = http.StatusBadRequest
+			return errors.New("La cotización debe tener al menos un item")
+		}
+
+		if err := tx.Delete(&item).Error; err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al eliminar item")
+		}
+
+		if err := recalculateQuoteTotals(tx, qic.pricingService, &quote); err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al recalcular totales de la cotización")
+		}
+		return recordQuoteRevision(tx, qic.versionService, quote.ID, userID, "Item eliminado")
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Item eliminado exitosamente",
+		"quote":   quote,
+	})
+}
+
+// @Summary Reordenar items de cotización
+// @Description Definir el orden de los items de una cotización en borrador. Deben enviarse todos los IDs de items
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param order body models.ReorderQuoteItemsRequest true "IDs de los items en el orden deseado"
+// @Success 200 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/items/reorder [post]
+func (qic *QuoteItemController) ReorderQuoteItems(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.ReorderQuoteItemsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := currentUserID(c)
+
+	var quote models.Quote
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if code, err := lockEditableQuote(tx, uint(id), &quote); err != nil {
+			status = code
+			return err
+		}
+		if err := qic.versionService.EnsureBaseline(tx, quote.ID, userID); err != nil {
+			return errors.New("Error al registrar revisión de la cotización")
+		}
+
+		var itemIDs []uint
+		tx.Model(&models.QuoteItem{}).Where("quote_id = ?", quote.ID).Pluck("id", &itemIDs)
+
+		// El nuevo orden debe incluir cada item de la cotización exactamente una vez
+		pending := make(map[uint]bool, len(itemIDs))
+		for _, itemID := range itemIDs {
+			pending[itemID] = true
+		}
+		for _, itemID := range req.ItemIDs {
+			if !pending[itemID] {
+				status = http.StatusBadRequest
+				return errors.New("La lista de items no coincide con los items de la cotización")
+			}
+			delete(pending, itemID)
+		}
+		if len(pending) > 0 {
+			status = http.StatusBadRequest
+			return errors.New("La lista de items no coincide con los items de la cotización")
+		}
+
+		for i, itemID := range req.ItemIDs {
+			if err := tx.Model(&models.QuoteItem{}).Where("id = ?", itemID).Update("position", i+1).Error; err != nil {
+				status = http.StatusInternalServerError
+				return errors.New("Error al reordenar items")
+			}
+		}
+		return recordQuoteRevision(tx, qic.versionService, quote.ID, userID, "Items reordenados")
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Items reordenados exitosamente",
+		"quote":   quote,
+	})
+}
+
+// lockEditableQuote carga y bloquea la cotización verificando que sus items aún puedan
+// editarse (solo en borrador). Devuelve el código HTTP a usar en caso de error
+func lockEditableQuote(tx *gorm.DB, id uint, quote *models.Quote) (int, error) {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(quote, id).Error; err != nil {
+		return http.StatusNotFound, errors.New("Cotización no encontrada")
+	}
+	if quote.Status != "draft" {
+		return http.StatusBadRequest, errors.New("Solo se pueden editar los items de cotizaciones en borrador")
+	}
+	return http.StatusOK, nil
+}
+
+// recalculateQuoteTotals recalcula subtotal, descuento, impuestos, retenciones, total, costo
+// y margen de la cotización a partir de sus items y guarda los cambios
+func recalculateQuoteTotals(tx *gorm.DB, pricingService *services.PricingService, quote *models.Quote) error {
+	var items []models.QuoteItem
+	if err := tx.Where("quote_id = ?", quote.ID).Select("total", "cost_total", "tax_code").Find(&items).Error; err != nil {
+		return err
+	}
+
+	quote.CostTotal = 0
+	for _, item := range items {
+		quote.CostTotal += item.CostTotal
+	}
+	if err := pricingService.PriceQuote(tx, quote, items); err != nil {
+		return err
+	}
+	quote.CalculateMargin()
+
+	return tx.Model(quote).
+		Select("subtotal", "discount_type", "discount", "tax_amount", "withholding_amount", "tax_breakdown", "total", "cost_total", "gross_margin", "margin_percent").
+		Updates(quote).Error
+}
+
+// recordQuoteRevision guarda la revisión resultante de un cambio en la cotización
+func recordQuoteRevision(tx *gorm.DB, versionService *services.QuoteVersionService, quoteID uint, userID *uint, note string) error {
+	if _, err := versionService.Record(tx, quoteID, userID, note); err != nil {
+		return errors.New("Error al registrar revisión de la cotización")
+	}
+	return nil
+}
+
+// orderedQuoteItems ordena los items de la cotización según su posición
+func orderedQuoteItems(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
@@ -0,0 +1,336 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type QuoteTemplateController struct {
+	templateService *services.QuoteTemplateService
+	taxService      *services.TaxService
+}
+
+func NewQuoteTemplateController() *QuoteTemplateController {
+	return &QuoteTemplateController{
+		templateService: services.NewQuoteTemplateService(),
+		taxService:      services.NewTaxService(),
+	}
+}
+
+// @Summary Obtener plantillas de cotización
+// @Description Obtener la lista de plantillas de cotización
+// @Tags quote-templates
+// @Produce json
+// @Security BearerAuth
+// @Param active query bool false "Solo plantillas activas"
+// @Success 200 {array} models.QuoteTemplate
+// @Router /quote-templates [get]
+func (qtc *QuoteTemplateController) GetQuoteTemplates(c *gin.Context) {
+	query := config.DB.Model(&models.QuoteTemplate{})
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var templates []models.QuoteTemplate
+	if err := query.Order("name ASC").Find(&templates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener plantillas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, templates)
+}
+
+// @Summary Obtener plantilla de cotización
+// @Description Obtener una plantilla de cotización con sus parámetros e items
+// @Tags quote-templates
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la plantilla"
+// @Success 200 {object} models.QuoteTemplate
+// @Failure 404 {object} map[string]string
+// @Router /quote-templates/{id} [get]
+func (qtc *QuoteTemplateController) GetQuoteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var template models.QuoteTemplate
+	if err := config.DB.Preload("Items", orderedTemplateItems).Preload("Items.Material").First(&template, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+// @Summary Crear plantilla de cotización
+// @Description Crear una plantilla de cotización. Las cantidades de los items son fórmulas de los parámetros, por ejemplo "ceil(area * 1.1 / 25)"
+// @Tags quote-templates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param template body models.CreateQuoteTemplateRequest true "Datos de la plantilla"
+// @Success 201 {object} models.QuoteTemplate
+// @Failure 400 {object} map[string]string
+// @Router /quote-templates [post]
+func (qtc *QuoteTemplateController) CreateQuoteTemplate(c *gin.Context) {
+	var req models.CreateQuoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := qtc.templateService.Validate(req.Parameters, req.Items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := qtc.validateTaxCodes(req.TaxCode, req.Items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.QuoteTemplate
+	if err := config.DB.Where("name = ?", req.Name).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe una plantilla con este nombre"})
+		return
+	}
+
+	template := models.QuoteTemplate{
+		Name:          req.Name,
+		Description:   req.Description,
+		Title:         req.Title,
+		TaxRate:       req.TaxRate,
+		TaxCode:       req.TaxCode,
+		MarkupPercent: req.MarkupPercent,
+		ValidDays:     req.ValidDays,
+		Notes:         req.Notes,
+		Terms:         req.Terms,
+		Parameters:    req.Parameters,
+		IsActive:      true,
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&template).Error; err != nil {
+			return err
+		}
+		items := qtc.templateService.BuildItems(template.ID, req.Items)
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear plantilla"})
+		return
+	}
+
+	config.DB.Preload("Items", orderedTemplateItems).First(&template, template.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Plantilla creada exitosamente",
+		"template": template,
+	})
+}
+
+// @Summary Actualizar plantilla de cotización
+// @Description Actualizar una plantilla de cotización. Si se envían items reemplazan a los actuales. Las cotizaciones ya generadas no cambian
+// @Tags quote-templates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la plantilla"
+// @Param template body models.UpdateQuoteTemplateRequest true "Datos actualizados de la plantilla"
+// @Success 200 {object} models.QuoteTemplate
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quote-templates/{id} [put]
+func (qtc *QuoteTemplateController) UpdateQuoteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateQuoteTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var template models.QuoteTemplate
+	if err := config.DB.Preload("Items", orderedTemplateItems).First(&template, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada"})
+		return
+	}
+
+	if req.Name != nil && *req.Name != template.Name {
+		var existing models.QuoteTemplate
+		if err := config.DB.Where("name = ? AND id != ?", *req.Name, template.ID).First(&existing).Error; err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe otra plantilla con este nombre"})
+			return
+		}
+	}
+
+	// Los parámetros y las fórmulas se validan juntos con los valores resultantes
+	parameters := []models.QuoteTemplateParameter(template.Parameters)
+	if req.Parameters != nil {
+		parameters = *req.Parameters
+	}
+	items := templateItemRequests(template.Items)
+	if req.Items != nil {
+		items = *req.Items
+	}
+	if err := qtc.templateService.Validate(parameters, items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	taxCode := template.TaxCode
+	if req.TaxCode != nil {
+		taxCode = *req.TaxCode
+	}
+	if err := qtc.validateTaxCodes(taxCode, items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Actualizar campos
+	if req.Name != nil {
+		template.Name = *req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Title != nil {
+		template.Title = *req.Title
+	}
+	if req.TaxRate != nil {
+		template.TaxRate = *req.TaxRate
+	}
+	template.TaxCode = taxCode
+	if req.MarkupPercent != nil {
+		template.MarkupPercent = *req.MarkupPercent
+	}
+	if req.ValidDays != nil {
+		template.ValidDays = *req.ValidDays
+	}
+	if req.Notes != nil {
+		template.Notes = *req.Notes
+	}
+	if req.Terms != nil {
+		template.Terms = *req.Terms
+	}
+	if req.IsActive != nil {
+		template.IsActive = *req.IsActive
+	}
+	template.Parameters = parameters
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Save(&template).Error; err != nil {
+			return err
+		}
+		if req.Items == nil {
+			return nil
+		}
+		if err := tx.Where("template_id = ?", template.ID).Delete(&models.QuoteTemplateItem{}).Error; err != nil {
+			return err
+		}
+		newItems := qtc.templateService.BuildItems(template.ID, *req.Items)
+		return tx.Create(&newItems).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar plantilla"})
+		return
+	}
+
+	config.DB.Preload("Items", orderedTemplateItems).First(&template, template.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Plantilla actualizada exitosamente",
+		"template": template,
+	})
+}
+
+// @Summary Eliminar plantilla de cotización
+// @Description Eliminar una plantilla de cotización. Las cotizaciones ya generadas no se modifican
+// @Tags quote-templates
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la plantilla"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quote-templates/{id} [delete]
+func (qtc *QuoteTemplateController) DeleteQuoteTemplate(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var template models.QuoteTemplate
+	if err := config.DB.First(&template, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Plantilla no encontrada"})
+		return
+	}
+
+	if err := config.DB.Delete(&template).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar plantilla"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Plantilla eliminada exitosamente"})
+}
+
+// validateTaxCodes verifica los códigos de impuesto de la plantilla y de sus items
+func (qtc *QuoteTemplateController) validateTaxCodes(taxCode string, items []models.QuoteTemplateItemRequest) error {
+	if err := qtc.taxService.ValidateCode(config.DB, taxCode); err != nil {
+		return err
+	}
+	for i, item := range items {
+		if err := qtc.taxService.ValidateCode(config.DB, item.TaxCode); err != nil {
+			return fmt.Errorf("Item %d: %s", i+1, err.Error())
+		}
+	}
+	return nil
+}
+
+// loadQuoteTemplate carga una plantilla activa con sus items ordenados
+func loadQuoteTemplate(db *gorm.DB, id uint, template *models.QuoteTemplate) (int, error) {
+	if err := db.Preload("Items", orderedTemplateItems).First(template, id).Error; err != nil {
+		return http.StatusNotFound, errors.New("Plantilla no encontrada")
+	}
+	if !template.IsActive {
+		return http.StatusBadRequest, errors.New("La plantilla no está activa")
+	}
+	return http.StatusOK, nil
+}
+
+// orderedTemplateItems ordena los items de la plantilla según su posición
+func orderedTemplateItems(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, id ASC")
+}
+
+func templateItemRequests(items []models.QuoteTemplateItem) []models.QuoteTemplateItemRequest {
+	requests := make([]models.QuoteTemplateItemRequest, 0, len(items))
+	for _, item := range items {
+		requests = append(requests, models.QuoteTemplateItemRequest{
+			MaterialID:      item.MaterialID,
+			WorkType:        item.WorkType,
+			Description:     item.Description,
+			Unit:            item.Unit,
+			QuantityFormula: item.QuantityFormula,
+			UnitCost:        item.UnitCost,
+			MarkupPercent:   item.MarkupPercent,
+			UnitPrice:       item.UnitPrice,
+			TaxCode:         item.TaxCode,
+			Notes:           item.Notes,
+		})
+	}
+	return requests
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type QuoteVersionController struct {
+	versionService *services.QuoteVersionService
+	quoteService   *services.QuoteService
+}
+
+func NewQuoteVersionController() *QuoteVersionController {
+	return &QuoteVersionController{
+		versionService: services.NewQuoteVersionService(),
+		quoteService:   services.NewQuoteService(),
+	}
+}
+
+// @Summary Obtener revisiones de una cotización
+// @Description Obtener el historial de revisiones de una cotización, de la más reciente a la más antigua
+// @Tags quotes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/versions [get]
+func (qvc *QuoteVersionController) GetQuoteVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.First(&quote, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	}
+
+	var versions []models.QuoteVersion
+	config.DB.Preload("CreatedBy").Where("quote_id = ?", quote.ID).Order("revision DESC").Find(&versions)
+
+	c.JSON(http.StatusOK, gin.H{
+		"quote_number":     quote.QuoteNumber,
+		"current_revision": quote.Revision,
+		"versions":         versions,
+	})
+}
+
+// @Summary Obtener una revisión de una cotización
+// @Description Obtener el contenido de una revisión específica de una cotización
+// @Tags quotes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param revision path int true "Número de revisión"
+// @Success 200 {object} models.QuoteVersion
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/versions/{revision} [get]
+func (qvc *QuoteVersionController) GetQuoteVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revisión inválida"})
+		return
+	}
+
+	var version models.QuoteVersion
+	if err := config.DB.Preload("CreatedBy").Where("quote_id = ? AND revision = ?", uint(id), revision).First(&version).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revisión no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+// @Summary Comparar revisiones de una cotización
+// @Description Obtener las diferencias de encabezado e items entre dos revisiones de una cotización
+// @Tags quotes
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param from query int true "Revisión de origen"
+// @Param to query int false "Revisión de destino (por defecto la vigente)"
+// @Success 200 {object} models.QuoteVersionDiff
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/versions/diff [get]
+func (qvc *QuoteVersionController) DiffQuoteVersions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var quote models.Quote
+	if err := config.DB.First(&quote, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cotización no encontrada"})
+		return
+	}
+
+	fromRevision, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revisión de origen inválida"})
+		return
+	}
+
+	toRevision := quote.Revision
+	if toStr := c.Query("to"); toStr != "" {
+		if toRevision, err = strconv.Atoi(toStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Revisión de destino inválida"})
+			return
+		}
+	}
+
+	var from, to models.QuoteVersion
+	if err := config.DB.Where("quote_id = ? AND revision = ?", quote.ID, fromRevision).First(&from).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revisión de origen no encontrada"})
+		return
+	}
+	if err := config.DB.Where("quote_id = ? AND revision = ?", quote.ID, toRevision).First(&to).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revisión de destino no encontrada"})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.DiffQuoteSnapshots(from.Revision, from.Snapshot, to.Revision, to.Snapshot))
+}
+
+// @Summary Restaurar revisión de una cotización
+// @Description Restaurar el contenido de una revisión anterior como borrador vigente. Se registra como una nueva revisión
+// @Tags quotes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la cotización"
+// @Param revision path int true "Número de revisión a restaurar"
+// @Param restore body models.RestoreQuoteVersionRequest false "Nota de la restauración"
+// @Success 200 {object} models.Quote
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /quotes/{id}/versions/{revision}/restore [post]
+func (qvc *QuoteVersionController) RestoreQuoteVersion(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revisión inválida"})
+		return
+	}
+
+	var req models.RestoreQuoteVersionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	userID := currentUserID(c)
+
+	var quote models.Quote
+	var restored *models.QuoteVersion
+	status := http.StatusInternalServerError
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&quote, uint(id)).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Cotización no encontrada")
+		}
+
+		if quote.Status != "draft" && !services.CanTransitionQuote(quote.Status, "draft") {
+			status = http.StatusBadRequest
+			return fmt.Errorf("No se puede restaurar una revisión de una cotización en estado %s", quote.Status)
+		}
+
+		var version models.QuoteVersion
+		if err := tx.Where("quote_id = ? AND revision = ?", quote.ID, revision).First(&version).Error; err != nil {
+			status = http.StatusNotFound
+			return errors.New("Revisión no encontrada")
+		}
+
+		if quote.Status != "draft" {
+			if err := qvc.quoteService.ChangeStatus(tx, &quote, "draft", ""); err != nil {
+				status = http.StatusBadRequest
+				return err
+			}
+		}
+
+		var err error
+		if restored, err = qvc.versionService.Restore(tx, &quote, &version, userID, req.Note); err != nil {
+			status = http.StatusInternalServerError
+			return errors.New("Error al restaurar revisión")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items", orderedQuoteItems).First(&quote, quote.ID)
+
+	response := gin.H{
+		"message": "Revisión restaurada exitosamente",
+		"quote":   quote,
+	}
+	if restored != nil {
+		response["version"] = restored
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// currentUserID devuelve el ID del usuario autenticado, si lo hay
+func currentUserID(c *gin.Context) *uint {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return nil
+	}
+	id, ok := userID.(uint)
+	if !ok {
+		return nil
+	}
+	return &id
+}
@@ -0,0 +1,359 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type RecurringInvoiceController struct {
+	taxService *services.TaxService
+}
+
+func NewRecurringInvoiceController() *RecurringInvoiceController {
+	return &RecurringInvoiceController{
+		taxService: services.NewTaxService(),
+	}
+}
+
+// @Summary Obtener facturas recurrentes
+// @Description Obtener lista de plantillas de facturación recurrente
+// @Tags recurring-invoices
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Param client_id query int false "Filtrar por cliente"
+// @Param project_id query int false "Filtrar por proyecto"
+// @Param active query bool false "Filtrar por estado activo"
+// @Success 200 {object} map[string]interface{}
+// @Router /recurring-invoices [get]
+func (rc *RecurringInvoiceController) GetRecurringInvoices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	clientID := c.Query("client_id")
+	projectID := c.Query("project_id")
+	activeStr := c.Query("active")
+
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.RecurringInvoice{}).Preload("Client").Preload("Project").Preload("Items")
+
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	if projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+
+	if activeStr != "" {
+		active, _ := strconv.ParseBool(activeStr)
+		query = query.Where("is_active = ?", active)
+	}
+
+	var recurringInvoices []models.RecurringInvoice
+	var total int64
+
+	query.Count(&total)
+	query.Offset(offset).Limit(limit).Order("next_run_date ASC").Find(&recurringInvoices)
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring_invoices": recurringInvoices,
+		"total":              total,
+		"page":               page,
+		"limit":              limit,
+	})
+}
+
+// @Summary Obtener factura recurrente por ID
+// @Description Obtener una plantilla recurrente junto con las facturas generadas
+// @Tags recurring-invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura recurrente"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]string
+// @Router /recurring-invoices/{id} [get]
+func (rc *RecurringInvoiceController) GetRecurringInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var recurringInvoice models.RecurringInvoice
+	if err := config.DB.Preload("Client").Preload("Project").Preload("Items").First(&recurringInvoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura recurrente no encontrada"})
+		return
+	}
+
+	var invoices []models.Invoice
+	config.DB.Where("recurring_invoice_id = ?", recurringInvoice.ID).Order("recurrence_period DESC").Find(&invoices)
+
+	c.JSON(http.StatusOK, gin.H{
+		"recurring_invoice": recurringInvoice,
+		"invoices":          invoices,
+	})
+}
+
+// @Summary Crear factura recurrente
+// @Description Crear una plantilla de facturación recurrente
+// @Tags recurring-invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param recurring_invoice body models.CreateRecurringInvoiceRequest true "Datos de la factura recurrente"
+// @Success 201 {object} models.RecurringInvoice
+// @Failure 400 {object} map[string]string
+// @Router /recurring-invoices [post]
+func (rc *RecurringInvoiceController) CreateRecurringInvoice(c *gin.Context) {
+	var req models.CreateRecurringInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := services.ValidateRecurrence(req.Frequency, req.DayOfMonth, req.StartDate, req.EndDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Verificar que el cliente existe
+	var client models.Client
+	if err := config.DB.First(&client, req.ClientID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cliente no encontrado"})
+		return
+	}
+	if err := validateClientProject(config.DB, client.ID, req.ProjectID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := validateInvoiceTaxCodes(rc.taxService, req.TaxCode, req.Items); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paymentTermDays := 30
+	if req.PaymentTermDays != nil {
+		paymentTermDays = *req.PaymentTermDays
+	}
+
+	recurringInvoice := models.RecurringInvoice{
+		ClientID:        req.ClientID,
+		ProjectID:       req.ProjectID,
+		Title:           req.Title,
+		Description:     req.Description,
+		Frequency:       req.Frequency,
+		DayOfMonth:      req.DayOfMonth,
+		StartDate:       req.StartDate,
+		EndDate:         req.EndDate,
+		NextRunDate:     services.FirstRecurrenceDate(req.StartDate, req.DayOfMonth),
+		PaymentTermDays: paymentTermDays,
+		Currency:        services.NormalizeCurrency(req.Currency),
+		TaxRate:         req.TaxRate,
+		TaxCode:         req.TaxCode,
+		DiscountType:    services.NormalizeDiscountType(req.DiscountType),
+		DiscountPercent: req.DiscountPercent,
+		Discount:        req.Discount,
+		Notes:           req.Notes,
+		Terms:           req.Terms,
+		IsActive:        true,
+		Items:           buildRecurringInvoiceItems(req.Items),
+	}
+
+	if err := config.DB.Create(&recurringInvoice).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear factura recurrente"})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items").First(&recurringInvoice, recurringInvoice.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":           "Factura recurrente creada exitosamente",
+		"recurring_invoice": recurringInvoice,
+	})
+}
+
+// @Summary Actualizar factura recurrente
+// @Description Actualizar una plantilla recurrente. Los cambios aplican a los próximos periodos
+// @Tags recurring-invoices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura recurrente"
+// @Param recurring_invoice body models.UpdateRecurringInvoiceRequest true "Datos actualizados"
+// @Success 200 {object} models.RecurringInvoice
+// @Failure 400 {object} map[string]string
+// @Router /recurring-invoices/{id} [put]
+func (rc *RecurringInvoiceController) UpdateRecurringInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateRecurringInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var recurringInvoice models.RecurringInvoice
+	if err := config.DB.First(&recurringInvoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura recurrente no encontrada"})
+		return
+	}
+
+	// Actualizar campos
+	if req.Title != nil {
+		recurringInvoice.Title = *req.Title
+	}
+	if req.Description != nil {
+		recurringInvoice.Description = *req.Description
+	}
+	if req.Frequency != nil {
+		recurringInvoice.Frequency = *req.Frequency
+	}
+	if req.DayOfMonth != nil {
+		recurringInvoice.DayOfMonth = *req.DayOfMonth
+	}
+	if req.EndDate != nil {
+		recurringInvoice.EndDate = req.EndDate
+	}
+	if req.PaymentTermDays != nil {
+		recurringInvoice.PaymentTermDays = *req.PaymentTermDays
+	}
+	if req.Currency != nil {
+		recurringInvoice.Currency = services.NormalizeCurrency(*req.Currency)
+	}
+	if req.TaxRate != nil {
+		recurringInvoice.TaxRate = *req.TaxRate
+	}
+	if req.TaxCode != nil {
+		recurringInvoice.TaxCode = *req.TaxCode
+	}
+	if req.DiscountType != nil {
+		recurringInvoice.DiscountType = services.NormalizeDiscountType(*req.DiscountType)
+	}
+	if req.DiscountPercent != nil {
+		recurringInvoice.DiscountPercent = *req.DiscountPercent
+	}
+	if req.Discount != nil {
+		recurringInvoice.Discount = *req.Discount
+	}
+	if req.Notes != nil {
+		recurringInvoice.Notes = *req.Notes
+	}
+	if req.Terms != nil {
+		recurringInvoice.Terms = *req.Terms
+	}
+	if req.IsActive != nil {
+		recurringInvoice.IsActive = *req.IsActive
+	}
+
+	if err := services.ValidateRecurrence(recurringInvoice.Frequency, recurringInvoice.DayOfMonth, recurringInvoice.StartDate, recurringInvoice.EndDate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var itemRequests []models.CreateInvoiceItemRequest
+	if req.Items != nil {
+		itemRequests = *req.Items
+	}
+	if err := validateInvoiceTaxCodes(rc.taxService, recurringInvoice.TaxCode, itemRequests); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Si cambia la frecuencia o el día de emisión, reprogramar el próximo periodo pendiente
+	if req.Frequency != nil || req.DayOfMonth != nil {
+		recurringInvoice.NextRunDate = services.RescheduleRecurrence(&recurringInvoice)
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Save(&recurringInvoice).Error; err != nil {
+			return err
+		}
+		if req.Items != nil {
+			if err := tx.Where("recurring_invoice_id = ?", recurringInvoice.ID).Delete(&models.RecurringInvoiceItem{}).Error; err != nil {
+				return err
+			}
+			items := buildRecurringInvoiceItems(*req.Items)
+			for i := range items {
+				items[i].RecurringInvoiceID = recurringInvoice.ID
+			}
+			return tx.Create(&items).Error
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar factura recurrente"})
+		return
+	}
+
+	config.DB.Preload("Client").Preload("Project").Preload("Items").First(&recurringInvoice, recurringInvoice.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":           "Factura recurrente actualizada exitosamente",
+		"recurring_invoice": recurringInvoice,
+	})
+}
+
+// @Summary Eliminar factura recurrente
+// @Description Eliminar una plantilla recurrente (soft delete). Las facturas ya generadas se conservan
+// @Tags recurring-invoices
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID de la factura recurrente"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /recurring-invoices/{id} [delete]
+func (rc *RecurringInvoiceController) DeleteRecurringInvoice(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var recurringInvoice models.RecurringInvoice
+	if err := config.DB.First(&recurringInvoice, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Factura recurrente no encontrada"})
+		return
+	}
+
+	if err := config.DB.Delete(&recurringInvoice).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al eliminar factura recurrente"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Factura recurrente eliminada exitosamente"})
+}
+
+// Función auxiliar para armar los items de una factura recurrente
+func buildRecurringInvoiceItems(reqItems []models.CreateInvoiceItemRequest) []models.RecurringInvoiceItem {
+	items := make([]models.RecurringInvoiceItem, 0, len(reqItems))
+	for _, itemReq := range reqItems {
+		unit := itemReq.Unit
+		if unit == "" {
+			unit = "pcs"
+		}
+		items = append(items, models.RecurringInvoiceItem{
+			Description:     itemReq.Description,
+			Quantity:        itemReq.Quantity,
+			Unit:            unit,
+			UnitPrice:       itemReq.UnitPrice,
+			DiscountType:    services.NormalizeDiscountType(itemReq.DiscountType),
+			DiscountPercent: itemReq.DiscountPercent,
+			Discount:        itemReq.Discount,
+			TaxCode:         itemReq.TaxCode,
+			Notes:           itemReq.Notes,
+		})
+	}
+	return items
+}
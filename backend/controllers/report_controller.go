@@ -1,18 +1,26 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 	"raborimet-crm/backend/config"
 	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
 )
 
-type ReportController struct{}
+type ReportController struct {
+	exchangeRateService *services.ExchangeRateService
+}
 
 func NewReportController() *ReportController {
-	return &ReportController{}
+	return &ReportController{
+		exchangeRateService: services.NewExchangeRateService(),
+	}
 }
 
 // @Summary Reporte de clientes
@@ -59,7 +67,7 @@ func (rc *ReportController) GetClientsReport(c *gin.Context) {
 	activeClients := 0
 	totalProjects := 0
 	totalQuotes := 0
-	var totalQuoteValue float64
+	var totalQuoteValue models.Money
 
 	clientData := []map[string]interface{}{}
 	for _, client := range clients {
@@ -69,7 +77,7 @@ func (rc *ReportController) GetClientsReport(c *gin.Context) {
 		totalProjects += len(client.Projects)
 		totalQuotes += len(client.Quotes)
 
-		var clientQuoteValue float64
+		var clientQuoteValue models.Money
 		for _, quote := range client.Quotes {
 			clientQuoteValue += quote.Total
 			totalQuoteValue += quote.Total
@@ -144,7 +152,7 @@ func (rc *ReportController) GetProjectsReport(c *gin.Context) {
 
 	// Calcular estadísticas
 	statusCount := make(map[string]int)
-	var totalBudget, totalCost float64
+	var totalBudget, totalCost models.Money
 	totalProjects := len(projects)
 
 	projectData := []map[string]interface{}{}
@@ -159,9 +167,9 @@ func (rc *ReportController) GetProjectsReport(c *gin.Context) {
 		}
 
 		// Calcular costo de materiales
-		var materialsCost float64
+		var materialsCost models.Money
 		for _, pm := range project.ProjectMaterials {
-			materialsCost += pm.QuantityPlanned * pm.UnitPrice
+			materialsCost += models.LineTotal(pm.QuantityPlanned, pm.UnitPrice)
 		}
 
 		projectData = append(projectData, map[string]interface{}{
@@ -236,8 +244,8 @@ func (rc *ReportController) GetQuotesReport(c *gin.Context) {
 
 	// Calcular estadísticas
 	statusCount := make(map[string]int)
-	statusValue := make(map[string]float64)
-	var totalValue float64
+	statusValue := make(map[string]models.Money)
+	var totalValue models.Money
 	totalQuotes := len(quotes)
 
 	quoteData := []map[string]interface{}{}
@@ -257,20 +265,24 @@ func (rc *ReportController) GetQuotesReport(c *gin.Context) {
 		}
 
 		quoteData = append(quoteData, map[string]interface{}{
-			"id":           quote.ID,
-			"quote_number": quote.QuoteNumber,
-			"title":        quote.Title,
-			"client":       clientName,
-			"project":      projectName,
-			"status":       quote.Status,
-			"subtotal":     quote.Subtotal,
-			"tax_rate":     quote.TaxRate,
-			"tax_amount":   quote.TaxAmount,
-			"discount":     quote.Discount,
-			"total":        quote.Total,
-			"valid_until":  quote.ValidUntil,
-			"created_at":   quote.CreatedAt,
-			"items_count":  len(quote.Items),
+			"id":            quote.ID,
+			"quote_number":  quote.QuoteNumber,
+			"title":         quote.Title,
+			"client":        clientName,
+			"project":       projectName,
+			"status":        quote.Status,
+			"currency":      quote.Currency,
+			"subtotal":      quote.Subtotal,
+			"tax_rate":      quote.TaxRate,
+			"tax_code":      quote.TaxCode,
+			"tax_amount":    quote.TaxAmount,
+			"withholding":   quote.WithholdingAmount,
+			"tax_breakdown": quote.TaxBreakdown,
+			"discount":      quote.Discount,
+			"total":         quote.Total,
+			"valid_until":   quote.ValidUntil,
+			"created_at":    quote.CreatedAt,
+			"items_count":   len(quote.Items),
 		})
 	}
 
@@ -312,7 +324,7 @@ func (rc *ReportController) GetMaterialsReport(c *gin.Context) {
 		query = query.Where("category = ?", category)
 	}
 	if lowStock {
-		query = query.Where("stock <= min_stock")
+		query = query.Where("stock - reserved_stock <= min_stock")
 	}
 
 	var materials []models.Material
@@ -320,12 +332,13 @@ func (rc *ReportController) GetMaterialsReport(c *gin.Context) {
 
 	// Calcular estadísticas
 	categoryStats := make(map[string]map[string]interface{})
-	var totalValue, totalStock float64
+	var totalValue models.Money
+	var totalStock float64
 	lowStockCount := 0
 
 	materialData := []map[string]interface{}{}
 	for _, material := range materials {
-		value := material.Stock * material.UnitPrice
+		value := models.LineTotal(material.Stock, material.UnitPrice)
 		totalValue += value
 		totalStock += material.Stock
 
@@ -337,12 +350,12 @@ func (rc *ReportController) GetMaterialsReport(c *gin.Context) {
 		if _, exists := categoryStats[material.Category]; !exists {
 			categoryStats[material.Category] = map[string]interface{}{
 				"count": 0,
-				"value": 0.0,
+				"value": models.Money(0),
 				"stock": 0.0,
 			}
 		}
 		categoryStats[material.Category]["count"] = categoryStats[material.Category]["count"].(int) + 1
-		categoryStats[material.Category]["value"] = categoryStats[material.Category]["value"].(float64) + value
+		categoryStats[material.Category]["value"] = categoryStats[material.Category]["value"].(models.Money) + value
 		categoryStats[material.Category]["stock"] = categoryStats[material.Category]["stock"].(float64) + material.Stock
 
 		materialData = append(materialData, map[string]interface{}{
@@ -352,12 +365,14 @@ func (rc *ReportController) GetMaterialsReport(c *gin.Context) {
 			"unit":        material.Unit,
 			"price":       material.UnitPrice,
 			"stock":       material.Stock,
+			"reserved":    material.ReservedStock,
+			"available":   material.AvailableStock,
 			"min_stock":   material.MinStock,
 			"value":       value,
 			"supplier":    material.Supplier,
 			"sku":         material.SKU,
 			"is_active":   material.IsActive,
-			"low_stock":   material.Stock <= material.MinStock,
+			"low_stock":   material.AvailableStock <= material.MinStock,
 			"created_at":  material.CreatedAt,
 		})
 	}
@@ -375,7 +390,7 @@ func (rc *ReportController) GetMaterialsReport(c *gin.Context) {
 }
 
 // @Summary Reporte financiero
-// @Description Generar reporte financiero consolidado
+// @Description Generar reporte financiero consolidado en la moneda base. Las cotizaciones en otra moneda se convierten con el tipo de cambio vigente en su fecha; las que no tienen tipo de cambio se omiten y se informan en missing_rates
 // @Tags reports
 // @Produce json
 // @Security BearerAuth
@@ -413,9 +428,10 @@ func (rc *ReportController) GetFinancialReport(c *gin.Context) {
 		end = defaultEnd
 	}
 
+	converter := rc.exchangeRateService.NewConverter(config.DB)
+
 	// Ingresos (cotizaciones aceptadas)
-	var revenue float64
-	config.DB.Model(&models.Quote{}).Where("status = ? AND created_at BETWEEN ? AND ?", "accepted", start, end).Select("COALESCE(SUM(total), 0)").Scan(&revenue)
+	revenue := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status = ? AND created_at BETWEEN ? AND ?", "accepted", start, end)).Float64()
 
 	// Costos de proyectos
 	var projectCosts float64
@@ -426,8 +442,7 @@ func (rc *ReportController) GetFinancialReport(c *gin.Context) {
 	config.DB.Model(&models.Material{}).Select("COALESCE(SUM(stock * price), 0)").Scan(&inventoryValue)
 
 	// Cotizaciones pendientes
-	var pendingQuotes float64
-	config.DB.Model(&models.Quote{}).Where("status IN ? AND created_at BETWEEN ? AND ?", []string{"draft", "sent"}, start, end).Select("COALESCE(SUM(total), 0)").Scan(&pendingQuotes)
+	pendingQuotes := quoteTotalsInBase(converter, config.DB.Model(&models.Quote{}).Where("status IN ? AND created_at BETWEEN ? AND ?", []string{"draft", "sent"}, start, end)).Float64()
 
 	// Proyectos activos
 	var activeProjectsValue float64
@@ -445,6 +460,8 @@ func (rc *ReportController) GetFinancialReport(c *gin.Context) {
 			"start_date": start.Format("2006-01-02"),
 			"end_date":   end.Format("2006-01-02"),
 		},
+		"currency":      services.BaseCurrency(),
+		"missing_rates": converter.Missing(),
 		"revenue": map[string]interface{}{
 			"total":        revenue,
 			"pending":      pendingQuotes,
@@ -461,4 +478,247 @@ func (rc *ReportController) GetFinancialReport(c *gin.Context) {
 			"inventory_value": inventoryValue,
 		},
 	})
-}
\ No newline at end of file
+}
+
+// @Summary Antigüedad de saldos por cobrar
+// @Description Generar reporte de cuentas por cobrar agrupado por cliente y antigüedad (al corriente, 1-30, 31-60, 61-90, 90+ días). Los saldos se convierten a la moneda base con el tipo de cambio de la fecha de emisión
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param as_of query string false "Fecha de corte (YYYY-MM-DD)"
+// @Param client_id query int false "Filtrar por cliente"
+// @Success 200 {object} map[string]interface{}
+// @Router /reports/receivables-aging [get]
+func (rc *ReportController) GetReceivablesAging(c *gin.Context) {
+	asOf := time.Now()
+	if asOfStr := c.Query("as_of"); asOfStr != "" {
+		if parsedDate, err := time.Parse("2006-01-02", asOfStr); err == nil {
+			asOf = parsedDate.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+	clientID := c.Query("client_id")
+
+	query := config.DB.Model(&models.Invoice{}).Where("status IN ? AND balance > 0 AND issue_date <= ?", []string{"sent", "overdue"}, asOf)
+	if clientID != "" {
+		query = query.Where("client_id = ?", clientID)
+	}
+
+	var invoices []models.Invoice
+	query.Preload("Client").Order("due_date ASC").Find(&invoices)
+
+	type agingRow struct {
+		ClientID   uint         `json:"client_id"`
+		ClientName string       `json:"client_name"`
+		Current    models.Money `json:"current"`
+		Days1To30  models.Money `json:"days_1_30"`
+		Days31To60 models.Money `json:"days_31_60"`
+		Days61To90 models.Money `json:"days_61_90"`
+		Over90     models.Money `json:"days_over_90"`
+		Total      models.Money `json:"total"`
+		Invoices   int          `json:"invoices"`
+	}
+
+	rows := map[uint]*agingRow{}
+	totals := &agingRow{ClientName: "Total"}
+	converter := rc.exchangeRateService.NewConverter(config.DB)
+
+	for _, invoice := range invoices {
+		// Las facturas sin tipo de cambio quedan fuera y se informan en missing_rates
+		balance, ok := converter.ToBase(invoice.Balance, invoice.Currency, invoice.IssueDate)
+		if !ok {
+			continue
+		}
+
+		row, exists := rows[invoice.ClientID]
+		if !exists {
+			clientName := "Cliente desconocido"
+			if invoice.Client.ID != 0 {
+				clientName = invoice.Client.Name
+			}
+			row = &agingRow{ClientID: invoice.ClientID, ClientName: clientName}
+			rows[invoice.ClientID] = row
+		}
+
+		daysPastDue := int(asOf.Sub(invoice.DueDate).Hours() / 24)
+		for _, r := range []*agingRow{row, totals} {
+			switch {
+			case daysPastDue <= 0:
+				r.Current += balance
+			case daysPastDue <= 30:
+				r.Days1To30 += balance
+			case daysPastDue <= 60:
+				r.Days31To60 += balance
+			case daysPastDue <= 90:
+				r.Days61To90 += balance
+			default:
+				r.Over90 += balance
+			}
+			r.Total += balance
+			r.Invoices++
+		}
+	}
+
+	clientData := make([]*agingRow, 0, len(rows))
+	for _, row := range rows {
+		clientData = append(clientData, row)
+	}
+	sort.Slice(clientData, func(i, j int) bool {
+		return clientData[i].Total > clientData[j].Total
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"as_of":         asOf.Format("2006-01-02"),
+		"currency":      services.BaseCurrency(),
+		"missing_rates": converter.Missing(),
+		"summary":       totals,
+		"clients":       clientData,
+	})
+}
+
+// @Summary Reporte de impuestos
+// @Description Generar el desglose de impuestos trasladados, retenciones y bases exentas de las facturas emitidas en el periodo, descontando las notas de crédito del mismo periodo. Los importes se convierten a la moneda base con el tipo de cambio de la fecha de cada documento
+// @Tags reports
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string false "Fecha de inicio (YYYY-MM-DD)"
+// @Param end_date query string false "Fecha de fin (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Router /reports/taxes [get]
+func (rc *ReportController) GetTaxReport(c *gin.Context) {
+	// Por defecto el mes en curso
+	now := time.Now()
+	start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	end := now
+	if startDate := c.Query("start_date"); startDate != "" {
+		if parsedDate, err := time.Parse("2006-01-02", startDate); err == nil {
+			start = parsedDate
+		}
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		if parsedDate, err := time.Parse("2006-01-02", endDate); err == nil {
+			end = parsedDate.AddDate(0, 0, 1).Add(-time.Second)
+		}
+	}
+
+	var invoices []models.Invoice
+	config.DB.Where("status NOT IN ? AND issue_date BETWEEN ? AND ?", []string{"draft", "cancelled"}, start, end).Find(&invoices)
+
+	var creditNotes []models.CreditNote
+	config.DB.Preload("Invoice").Where("issue_date BETWEEN ? AND ?", start, end).Find(&creditNotes)
+
+	type taxRow struct {
+		Name     string       `json:"name"`
+		Type     string       `json:"type"`
+		Rate     float64      `json:"rate"`
+		Base     models.Money `json:"base"`
+		Amount   models.Money `json:"amount"`
+		Credited models.Money `json:"credited"` // Importe revertido por notas de crédito
+		Net      models.Money `json:"net"`
+	}
+
+	rows := map[string]*taxRow{}
+	order := []string{}
+	rowFor := func(line models.TaxLine) *taxRow {
+		key := fmt.Sprintf("%s|%s|%g", line.Type, line.Name, line.Rate)
+		row, exists := rows[key]
+		if !exists {
+			row = &taxRow{Name: line.Name, Type: line.Type, Rate: line.Rate}
+			rows[key] = row
+			order = append(order, key)
+		}
+		return row
+	}
+
+	// Los documentos sin tipo de cambio quedan fuera y se informan en missing_rates
+	converter := rc.exchangeRateService.NewConverter(config.DB)
+	toBase := func(amount models.Money, currency string, date time.Time) models.Money {
+		converted, _ := converter.ToBase(amount, currency, date)
+		return converted
+	}
+
+	var subtotal, transferred, withheld, credited models.Money
+	for _, invoice := range invoices {
+		if _, ok := converter.ToBase(0, invoice.Currency, invoice.IssueDate); !ok {
+			continue
+		}
+		subtotal += toBase(invoice.Subtotal, invoice.Currency, invoice.IssueDate)
+		transferred += toBase(invoice.TaxAmount, invoice.Currency, invoice.IssueDate)
+		withheld += toBase(invoice.WithholdingAmount, invoice.Currency, invoice.IssueDate)
+		for _, line := range documentTaxLines(invoice.TaxBreakdown, invoice.TaxRate, invoice.Subtotal, invoice.TaxAmount) {
+			row := rowFor(line)
+			row.Base += toBase(line.Base, invoice.Currency, invoice.IssueDate)
+			row.Amount += toBase(line.Amount, invoice.Currency, invoice.IssueDate)
+		}
+	}
+	for _, creditNote := range creditNotes {
+		if creditNote.Invoice == nil || creditNote.Invoice.Status == "draft" {
+			continue
+		}
+		currency := creditNote.Invoice.Currency
+		if _, ok := converter.ToBase(0, currency, creditNote.IssueDate); !ok {
+			continue
+		}
+		credited += toBase(creditNote.Total, currency, creditNote.IssueDate)
+		for _, line := range documentTaxLines(creditNote.TaxBreakdown, creditNote.Invoice.TaxRate, creditNote.Subtotal, creditNote.TaxAmount) {
+			rowFor(line).Credited += toBase(line.Amount, currency, creditNote.IssueDate)
+		}
+	}
+
+	taxes := make([]*taxRow, 0, len(order))
+	for _, taxType := range []string{models.TaxTransferred, models.TaxWithheld, models.TaxExempt} {
+		for _, key := range order {
+			if row := rows[key]; row.Type == taxType {
+				row.Net = row.Amount - row.Credited
+				taxes = append(taxes, row)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"period": map[string]interface{}{
+			"start_date": start.Format("2006-01-02"),
+			"end_date":   end.Format("2006-01-02"),
+		},
+		"currency":      services.BaseCurrency(),
+		"missing_rates": converter.Missing(),
+		"summary": map[string]interface{}{
+			"invoices":     len(invoices),
+			"credit_notes": len(creditNotes),
+			"subtotal":     subtotal,
+			"transferred":  transferred,
+			"withheld":     withheld,
+			"credited":     credited,
+		},
+		"taxes": taxes,
+	})
+}
+
+// documentTaxLines devuelve el desglose de impuestos de un documento. Los documentos
+// anteriores a los códigos de impuesto no tienen desglose y se informan como IVA a su
+// tasa única
+func documentTaxLines(breakdown models.TaxBreakdown, taxRate float64, subtotal, taxAmount models.Money) models.TaxBreakdown {
+	if len(breakdown) > 0 || taxAmount == 0 {
+		return breakdown
+	}
+	return models.TaxBreakdown{{Name: "IVA", Type: models.TaxTransferred, Rate: taxRate, Base: subtotal, Amount: taxAmount}}
+}
+
+// quoteTotalsInBase suma los totales de las cotizaciones de la consulta convertidos a la
+// moneda base con el tipo de cambio de la fecha de cada cotización. Las que no tienen
+// tipo de cambio quedan fuera de la suma y se informan en converter.Missing()
+func quoteTotalsInBase(converter *services.CurrencyConverter, query *gorm.DB) models.Money {
+	var rows []struct {
+		Total     models.Money
+		Currency  string
+		CreatedAt time.Time
+	}
+	query.Select("total, currency, created_at").Scan(&rows)
+
+	var sum models.Money
+	for _, row := range rows {
+		if amount, ok := converter.ToBase(row.Total, row.Currency, row.CreatedAt); ok {
+			sum += amount
+		}
+	}
+	return sum
+}
@@ -0,0 +1,152 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type TaxCodeController struct {
+	taxService *services.TaxService
+}
+
+func NewTaxCodeController() *TaxCodeController {
+	return &TaxCodeController{
+		taxService: services.NewTaxService(),
+	}
+}
+
+// @Summary Obtener códigos de impuesto
+// @Description Obtener los códigos de impuesto que se asignan a cotizaciones, facturas y sus items
+// @Tags tax-codes
+// @Produce json
+// @Security BearerAuth
+// @Param active query bool false "Solo códigos activos"
+// @Success 200 {array} models.TaxCode
+// @Router /tax-codes [get]
+func (tcc *TaxCodeController) GetTaxCodes(c *gin.Context) {
+	query := config.DB.Model(&models.TaxCode{})
+	if c.Query("active") == "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	var codes []models.TaxCode
+	if err := query.Order("code ASC").Find(&codes).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener códigos de impuesto"})
+		return
+	}
+
+	c.JSON(http.StatusOK, codes)
+}
+
+// @Summary Crear código de impuesto
+// @Description Crear un código de impuesto con sus componentes: trasladados (IVA), retenciones (ISR, IVA retenido) o exentos
+// @Tags tax-codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param tax_code body models.CreateTaxCodeRequest true "Datos del código de impuesto"
+// @Success 201 {object} models.TaxCode
+// @Failure 400 {object} map[string]string
+// @Router /admin/tax-codes [post]
+func (tcc *TaxCodeController) CreateTaxCode(c *gin.Context) {
+	var req models.CreateTaxCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	code := strings.ToUpper(strings.TrimSpace(req.Code))
+	var existing models.TaxCode
+	if err := config.DB.Where("code = ?", code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Ya existe un código de impuesto con este código"})
+		return
+	}
+
+	taxCode := models.TaxCode{
+		Code:       code,
+		Name:       req.Name,
+		Components: req.Components,
+		IsActive:   true,
+	}
+
+	if err := config.DB.Create(&taxCode).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al crear código de impuesto"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Código de impuesto creado exitosamente",
+		"tax_code": taxCode,
+	})
+}
+
+// @Summary Actualizar código de impuesto
+// @Description Actualizar el nombre, los componentes o el estado de un código de impuesto. Los componentes de un código ya usado en documentos, plantillas o items no pueden cambiarse: cree un código nuevo y desactive este. Un código inactivo no puede asignarse a documentos nuevos
+// @Tags tax-codes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del código de impuesto"
+// @Param tax_code body models.UpdateTaxCodeRequest true "Datos actualizados del código de impuesto"
+// @Success 200 {object} models.TaxCode
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /admin/tax-codes/{id} [put]
+func (tcc *TaxCodeController) UpdateTaxCode(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateTaxCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var taxCode models.TaxCode
+	if err := config.DB.First(&taxCode, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Código de impuesto no encontrado"})
+		return
+	}
+
+	// Actualizar campos
+	if req.Name != nil {
+		taxCode.Name = *req.Name
+	}
+	if req.Components != nil && !reflect.DeepEqual(models.TaxComponents(*req.Components), taxCode.Components) {
+		referenced, err := tcc.taxService.IsReferenced(config.DB, taxCode.Code)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al verificar el uso del código de impuesto"})
+			return
+		}
+		if referenced {
+			c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("El código %s ya se usa en documentos; cree un código nuevo con los componentes actualizados y desactive este", taxCode.Code)})
+			return
+		}
+		taxCode.Components = *req.Components
+	}
+	if req.IsActive != nil {
+		taxCode.IsActive = *req.IsActive
+	}
+
+	if err := config.DB.Save(&taxCode).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al actualizar código de impuesto"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Código de impuesto actualizado exitosamente",
+		"tax_code": taxCode,
+	})
+}
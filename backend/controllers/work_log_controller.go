@@ -0,0 +1,380 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+	"raborimet-crm/backend/services"
+)
+
+type WorkLogController struct {
+	workLogService     *services.WorkLogService
+	projectCostService *services.ProjectCostService
+}
+
+func NewWorkLogController() *WorkLogController {
+	return &WorkLogController{
+		workLogService:     services.NewWorkLogService(),
+		projectCostService: services.NewProjectCostService(),
+	}
+}
+
+// @Summary Obtener registros de horas
+// @Description Obtener registros de horas con paginación, filtrados por proyecto, empleado y rango de fechas
+// @Tags worklogs
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "Número de página" default(1)
+// @Param limit query int false "Elementos por página" default(10)
+// @Param project_id query int false "Filtrar por proyecto"
+// @Param user_id query int false "Filtrar por empleado"
+// @Param start_date query string false "Desde (YYYY-MM-DD)"
+// @Param end_date query string false "Hasta, inclusive (YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]string
+// @Router /worklogs [get]
+func (wlc *WorkLogController) GetWorkLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	offset := (page - 1) * limit
+
+	query := config.DB.Model(&models.WorkLog{})
+
+	if projectID := c.Query("project_id"); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+	if startDate := c.Query("start_date"); startDate != "" {
+		start, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha de inicio inválida"})
+			return
+		}
+		query = query.Where("date >= ?", start)
+	}
+	if endDate := c.Query("end_date"); endDate != "" {
+		end, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Fecha de fin inválida"})
+			return
+		}
+		query = query.Where("date < ?", end.AddDate(0, 0, 1))
+	}
+
+	var workLogs []models.WorkLog
+	var total int64
+	var totals struct {
+		Hours     float64
+		TotalCost models.Money
+	}
+
+	query.Count(&total)
+	query.Session(&gorm.Session{}).Select("COALESCE(SUM(hours), 0) AS hours, COALESCE(SUM(total_cost), 0) AS total_cost").Scan(&totals)
+	if err := query.Preload("Project").Preload("User").Offset(offset).Limit(limit).Order("start_time DESC").Find(&workLogs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener registros de horas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"work_logs":   workLogs,
+		"total":       total,
+		"page":        page,
+		"limit":       limit,
+		"total_hours": totals.Hours,
+		"total_cost":  totals.TotalCost,
+	})
+}
+
+// @Summary Obtener registro de horas por ID
+// @Description Obtener los detalles de un registro de horas
+// @Tags worklogs
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del registro"
+// @Success 200 {object} models.WorkLog
+// @Failure 404 {object} map[string]string
+// @Router /worklogs/{id} [get]
+func (wlc *WorkLogController) GetWorkLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var workLog models.WorkLog
+	if err := config.DB.Preload("Project").Preload("User").First(&workLog, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registro de horas no encontrado"})
+		return
+	}
+
+	c.JSON(http.StatusOK, workLog)
+}
+
+// @Summary Crear registro de horas
+// @Description Registrar horas trabajadas en un proyecto. Las horas se calculan de la hora de inicio a la de fin y el costo total con la tarifa por hora (por defecto la del tipo de trabajo en el catálogo de tarifas). El empleado no puede tener otro registro en el mismo horario. El costo real del proyecto se actualiza
+// @Tags worklogs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param work_log body models.CreateWorkLogRequest true "Datos del registro"
+// @Success 201 {object} models.WorkLog
+// @Failure 400 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /worklogs [post]
+func (wlc *WorkLogController) CreateWorkLog(c *gin.Context) {
+	var req models.CreateWorkLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := req.UserID
+	if userID == nil {
+		userID = currentUserID(c)
+	}
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+		return
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, *userID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Usuario no encontrado"})
+		return
+	}
+
+	var project models.Project
+	if err := config.DB.First(&project, req.ProjectID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Proyecto no encontrado"})
+		return
+	}
+
+	workLog := models.WorkLog{
+		ProjectID:   project.ID,
+		UserID:      *userID,
+		StartTime:   req.StartTime,
+		EndTime:     req.EndTime,
+		Description: req.Description,
+		WorkType:    req.WorkType,
+		Notes:       req.Notes,
+	}
+	if req.HourlyRate != nil {
+		workLog.HourlyRate = *req.HourlyRate
+	} else {
+		workLog.HourlyRate = wlc.workLogService.DefaultHourlyRate(config.DB, req.WorkType)
+	}
+
+	if status, err := wlc.saveWorkLog(&workLog, workLog.ProjectID); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Project").Preload("User").First(&workLog, workLog.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Registro de horas creado exitosamente",
+		"work_log": workLog,
+	})
+}
+
+// @Summary Actualizar registro de horas
+// @Description Actualizar un registro de horas. Las horas y el costo total se recalculan; si cambia el tipo de trabajo sin indicar tarifa, se toma la del catálogo. El costo real del proyecto se actualiza
+// @Tags worklogs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del registro"
+// @Param work_log body models.UpdateWorkLogRequest true "Datos actualizados"
+// @Success 200 {object} models.WorkLog
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /worklogs/{id} [put]
+func (wlc *WorkLogController) UpdateWorkLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var req models.UpdateWorkLogRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var workLog models.WorkLog
+	if err := config.DB.First(&workLog, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registro de horas no encontrado"})
+		return
+	}
+
+	previousProjectID := workLog.ProjectID
+
+	// Actualizar campos
+	if req.ProjectID != nil {
+		var project models.Project
+		if err := config.DB.First(&project, *req.ProjectID).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Proyecto no encontrado"})
+			return
+		}
+		workLog.ProjectID = project.ID
+	}
+	if req.StartTime != nil {
+		workLog.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		workLog.EndTime = *req.EndTime
+	}
+	if req.Description != nil {
+		workLog.Description = *req.Description
+	}
+	if req.WorkType != nil && *req.WorkType != workLog.WorkType {
+		workLog.WorkType = *req.WorkType
+		if req.HourlyRate == nil {
+			workLog.HourlyRate = wlc.workLogService.DefaultHourlyRate(config.DB, workLog.WorkType)
+		}
+	}
+	if req.HourlyRate != nil {
+		workLog.HourlyRate = *req.HourlyRate
+	}
+	if req.Notes != nil {
+		workLog.Notes = *req.Notes
+	}
+
+	if status, err := wlc.saveWorkLog(&workLog, previousProjectID); err != nil {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	config.DB.Preload("Project").Preload("User").First(&workLog, workLog.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Registro de horas actualizado exitosamente",
+		"work_log": workLog,
+	})
+}
+
+// @Summary Eliminar registro de horas
+// @Description Eliminar un registro de horas (soft delete). El costo real del proyecto se actualiza
+// @Tags worklogs
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ID del registro"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /worklogs/{id} [delete]
+func (wlc *WorkLogController) DeleteWorkLog(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ID inválido"})
+		return
+	}
+
+	var workLog models.WorkLog
+	if err := config.DB.First(&workLog, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Registro de horas no encontrado"})
+		return
+	}
+
+	err = config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&workLog).Error; err != nil {
+			return errors.New("Error al eliminar registro de horas")
+		}
+		if err := wlc.projectCostService.Recalculate(tx, workLog.ProjectID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Registro de horas eliminado exitosamente"})
+}
+
+// @Summary Obtener hoja de horas semanal
+// @Description Obtener las horas y el costo de mano de obra de un empleado en una semana (de lunes a domingo), por día y por proyecto
+// @Tags worklogs
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "ID del empleado (por defecto el usuario autenticado)"
+// @Param week query string false "Cualquier día de la semana (YYYY-MM-DD, por defecto hoy)"
+// @Success 200 {object} services.WeeklyTimesheet
+// @Failure 400 {object} map[string]string
+// @Router /worklogs/timesheet [get]
+func (wlc *WorkLogController) GetWeeklyTimesheet(c *gin.Context) {
+	userID := currentUserID(c)
+	if param := c.Query("user_id"); param != "" {
+		id, err := strconv.ParseUint(param, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "ID de empleado inválido"})
+			return
+		}
+		requested := uint(id)
+		userID = &requested
+	}
+	if userID == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Usuario no autenticado"})
+		return
+	}
+
+	now := time.Now()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if week := c.Query("week"); week != "" {
+		parsed, err := time.Parse("2006-01-02", week)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Semana inválida"})
+			return
+		}
+		day = parsed
+	}
+
+	timesheet, err := wlc.workLogService.Timesheet(config.DB, *userID, day)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error al obtener hoja de horas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, timesheet)
+}
+
+// saveWorkLog calcula horas y costo del registro y lo guarda, verificando dentro de la
+// misma transacción que no se cruce con otro registro del empleado, y actualiza el costo
+// real del proyecto (y del proyecto anterior si el registro cambió de proyecto). Devuelve
+// el código HTTP a usar en caso de error
+func (wlc *WorkLogController) saveWorkLog(workLog *models.WorkLog, previousProjectID uint) (int, error) {
+	if err := wlc.workLogService.Price(workLog); err != nil {
+		return http.StatusBadRequest, err
+	}
+
+	status := http.StatusInternalServerError
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := wlc.workLogService.CheckOverlap(tx, workLog); err != nil {
+			status = http.StatusConflict
+			return err
+		}
+		if err := tx.Omit("Project", "User").Save(workLog).Error; err != nil {
+			return errors.New("Error al guardar registro de horas")
+		}
+		if err := wlc.projectCostService.Recalculate(tx, workLog.ProjectID); err != nil {
+			return errors.New("Error al actualizar el costo real del proyecto")
+		}
+		if previousProjectID != workLog.ProjectID {
+			if err := wlc.projectCostService.Recalculate(tx, previousProjectID); err != nil {
+				return errors.New("Error al actualizar el costo real del proyecto")
+			}
+		}
+		return nil
+	})
+	return status, err
+}
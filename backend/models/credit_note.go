@@ -0,0 +1,56 @@
+package models
+
+import (
+	"time"
+)
+
+type CreditNote struct {
+	ID                uint         `json:"id" gorm:"primaryKey"`
+	CreditNoteNumber  string       `json:"credit_note_number" gorm:"uniqueIndex;not null"`
+	InvoiceID         uint         `json:"invoice_id" gorm:"not null;index"`
+	Invoice           *Invoice     `json:"invoice,omitempty" gorm:"foreignKey:InvoiceID"`
+	ClientID          uint         `json:"client_id" gorm:"not null"`
+	Client            Client       `json:"client" gorm:"foreignKey:ClientID"`
+	Reason            string       `json:"reason" gorm:"type:text;not null"`
+	IssueDate         time.Time    `json:"issue_date" gorm:"not null"`
+	Subtotal          Money        `json:"subtotal" gorm:"type:decimal(15,2);default:0"`
+	TaxAmount         Money        `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
+	WithholdingAmount Money        `json:"withholding_amount" gorm:"type:decimal(15,2);default:0"`
+	TaxBreakdown      TaxBreakdown `json:"tax_breakdown" gorm:"type:jsonb"`
+	Discount          Money        `json:"discount" gorm:"type:decimal(15,2);default:0"`
+	Total             Money        `json:"total" gorm:"type:decimal(15,2);default:0"`
+	IsCancellation    bool         `json:"is_cancellation" gorm:"default:false"` // Emitida al cancelar la factura
+	CreatedByID       uint         `json:"created_by_id" gorm:"not null"`
+	CreatedBy         User         `json:"created_by" gorm:"foreignKey:CreatedByID"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+
+	// Relaciones
+	Items []CreditNoteItem `json:"items,omitempty" gorm:"foreignKey:CreditNoteID;constraint:OnDelete:CASCADE"`
+}
+
+type CreditNoteItem struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	CreditNoteID  uint      `json:"credit_note_id" gorm:"not null;index"`
+	InvoiceItemID uint      `json:"invoice_item_id" gorm:"not null;index"`
+	Description   string    `json:"description" gorm:"not null"`
+	Quantity      float64   `json:"quantity" gorm:"type:decimal(10,2);not null"`
+	Unit          string    `json:"unit"`
+	UnitPrice     Money     `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	Total         Money     `json:"total" gorm:"type:decimal(15,2);not null"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type CreateCreditNoteRequest struct {
+	Reason string                        `json:"reason" binding:"required"`
+	Items  []CreateCreditNoteItemRequest `json:"items"` // Vacío para revertir todo el saldo acreditable
+}
+
+type CreateCreditNoteItemRequest struct {
+	InvoiceItemID uint    `json:"invoice_item_id" binding:"required"`
+	Quantity      float64 `json:"quantity" binding:"required,gt=0"`
+}
+
+type CancelInvoiceRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
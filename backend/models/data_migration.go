@@ -0,0 +1,13 @@
+package models
+
+import (
+	"time"
+)
+
+// DataMigration registra una migración de datos que ya se aplicó, para que no vuelva
+// a ejecutarse en cada inicio
+type DataMigration struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"uniqueIndex;not null"`
+	AppliedAt time.Time `json:"applied_at"`
+}
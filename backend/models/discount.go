@@ -0,0 +1,8 @@
+package models
+
+// Modos de descuento de documentos y líneas. Los descuentos se restan antes de calcular
+// los impuestos (ver services.PricingService)
+const (
+	DiscountTypeFixed   = "fixed"   // Importe fijo en discount
+	DiscountTypePercent = "percent" // Porcentaje en discount_percent sobre el importe
+)
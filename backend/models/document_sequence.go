@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+// DocumentSequence guarda la configuración y el contador de una serie de numeración
+// de documentos (cotizaciones, proyectos, facturas, notas de crédito, etc.)
+type DocumentSequence struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	Code          string    `json:"code" gorm:"uniqueIndex;not null"` // quote, project, invoice, credit_note
+	Name          string    `json:"name"`
+	Prefix        string    `json:"prefix"`
+	Format        string    `json:"format" gorm:"not null"`              // Tokens: {PREFIX}, {YYYY}, {YY}, {MM}, {SEQ}
+	Padding       int       `json:"padding" gorm:"default:4"`            // Dígitos mínimos del correlativo
+	ResetPeriod   string    `json:"reset_period" gorm:"default:'never'"` // never, yearly, monthly
+	CurrentPeriod string    `json:"current_period"`                      // Periodo al que corresponde LastNumber
+	LastNumber    int64     `json:"last_number" gorm:"not null;default:0"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type UpdateDocumentSequenceRequest struct {
+	Name        *string `json:"name"`
+	Prefix      *string `json:"prefix"`
+	Format      *string `json:"format"`
+	Padding     *int    `json:"padding" binding:"omitempty,min=1,max=12"`
+	ResetPeriod *string `json:"reset_period" binding:"omitempty,oneof=never yearly monthly"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// ExchangeRate es el tipo de cambio de una moneda a la moneda base (BASE_CURRENCY) vigente
+// desde una fecha: 1 USD = Rate MXN. Rige hasta la siguiente fecha registrada
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Currency  string    `json:"currency" gorm:"size:3;not null;uniqueIndex:idx_exchange_rate_date"` // Código ISO 4217: USD, EUR
+	Date      time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_exchange_rate_date"`
+	Rate      float64   `json:"rate" gorm:"type:decimal(18,6);not null"`
+	Source    string    `json:"source" gorm:"not null;default:'manual'"` // manual, csv
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateExchangeRateRequest struct {
+	Currency string    `json:"currency" binding:"required,len=3,alpha"`
+	Date     time.Time `json:"date" binding:"required"`
+	Rate     float64   `json:"rate" binding:"required,gt=0"`
+}
+
+type UpdateExchangeRateRequest struct {
+	Rate float64 `json:"rate" binding:"required,gt=0"`
+}
+
+// ExchangeRateImportResult resume la importación de un archivo CSV de tipos de cambio
+type ExchangeRateImportResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Errors  []string `json:"errors"`
+}
@@ -7,92 +7,133 @@ import (
 )
 
 type Invoice struct {
-	ID            uint           `json:"id" gorm:"primaryKey"`
-	InvoiceNumber string         `json:"invoice_number" gorm:"uniqueIndex;not null"`
-	ClientID      uint           `json:"client_id" gorm:"not null"`
-	Client        Client         `json:"client" gorm:"foreignKey:ClientID"`
-	ProjectID     *uint          `json:"project_id"`
-	Project       *Project       `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
-	QuoteID       *uint          `json:"quote_id"`
-	Quote         *Quote         `json:"quote,omitempty" gorm:"foreignKey:QuoteID"`
-	Title         string         `json:"title" gorm:"not null"`
-	Description   string         `json:"description" gorm:"type:text"`
-	Status        string         `json:"status" gorm:"default:'draft'"` // draft, sent, paid, overdue, cancelled
-	IssueDate     time.Time      `json:"issue_date" gorm:"not null"`
-	DueDate       time.Time      `json:"due_date" gorm:"not null"`
-	PaidDate      *time.Time     `json:"paid_date"`
-	Subtotal      float64        `json:"subtotal" gorm:"type:decimal(15,2);default:0"`
-	TaxRate       float64        `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
-	TaxAmount     float64        `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	Discount      float64        `json:"discount" gorm:"type:decimal(15,2);default:0"`
-	Total         float64        `json:"total" gorm:"type:decimal(15,2);default:0"`
-	PaidAmount    float64        `json:"paid_amount" gorm:"type:decimal(15,2);default:0"`
-	Balance       float64        `json:"balance" gorm:"type:decimal(15,2);default:0"`
-	Notes         string         `json:"notes" gorm:"type:text"`
-	Terms         string         `json:"terms" gorm:"type:text"`
-	CreatedAt     time.Time      `json:"created_at"`
-	UpdatedAt     time.Time      `json:"updated_at"`
-	DeletedAt     gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	InvoiceNumber      string         `json:"invoice_number" gorm:"uniqueIndex;not null"`
+	ClientID           uint           `json:"client_id" gorm:"not null"`
+	Client             Client         `json:"client" gorm:"foreignKey:ClientID"`
+	ProjectID          *uint          `json:"project_id"`
+	Project            *Project       `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
+	QuoteID            *uint          `json:"quote_id"`
+	Quote              *Quote         `json:"quote,omitempty" gorm:"foreignKey:QuoteID"`
+	RecurringInvoiceID *uint          `json:"recurring_invoice_id" gorm:"uniqueIndex:idx_invoice_recurrence"`        // Factura recurrente de origen
+	RecurrencePeriod   string         `json:"recurrence_period,omitempty" gorm:"uniqueIndex:idx_invoice_recurrence"` // Periodo facturado (YYYY-MM), único por factura recurrente
+	Title              string         `json:"title" gorm:"not null"`
+	Description        string         `json:"description" gorm:"type:text"`
+	Status             string         `json:"status" gorm:"default:'draft'"` // draft, sent, paid, overdue, cancelled
+	IssueDate          time.Time      `json:"issue_date" gorm:"not null"`
+	DueDate            time.Time      `json:"due_date" gorm:"not null"`
+	PaidDate           *time.Time     `json:"paid_date"`
+	Currency           string         `json:"currency" gorm:"size:3;not null;default:'MXN'"` // Código ISO 4217 de los importes
+	Subtotal           Money          `json:"subtotal" gorm:"type:decimal(15,2);default:0"`
+	TaxRate            float64        `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	TaxCode            string         `json:"tax_code"`                                               // Código de impuesto por defecto de los items (ver TaxCode)
+	TaxAmount          Money          `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`         // Impuestos trasladados
+	WithholdingAmount  Money          `json:"withholding_amount" gorm:"type:decimal(15,2);default:0"` // Retenciones
+	TaxBreakdown       TaxBreakdown   `json:"tax_breakdown" gorm:"type:jsonb"`
+	DiscountType       string         `json:"discount_type" gorm:"not null;default:'fixed'"`       // fixed, percent
+	DiscountPercent    float64        `json:"discount_percent" gorm:"type:decimal(5,2);default:0"` // Con discount_type percent
+	Discount           Money          `json:"discount" gorm:"type:decimal(15,2);default:0"`        // Descuento aplicado sobre el subtotal, antes de impuestos
+	DiscountFixed      Money          `json:"discount_fixed" gorm:"type:decimal(15,2);default:0"`  // Importe pedido con discount_type fixed; discount puede ser menor si supera el subtotal
+	Total              Money          `json:"total" gorm:"type:decimal(15,2);default:0"`
+	PaidAmount         Money          `json:"paid_amount" gorm:"type:decimal(15,2);default:0"`
+	CreditedAmount     Money          `json:"credited_amount" gorm:"type:decimal(15,2);default:0"` // Suma de notas de crédito emitidas
+	Balance            Money          `json:"balance" gorm:"type:decimal(15,2);default:0"`
+	Notes              string         `json:"notes" gorm:"type:text"`
+	Terms              string         `json:"terms" gorm:"type:text"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relaciones
-	Items []InvoiceItem `json:"items,omitempty" gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE"`
+	Items       []InvoiceItem `json:"items,omitempty" gorm:"foreignKey:InvoiceID;constraint:OnDelete:CASCADE"`
+	Payments    []Payment     `json:"payments,omitempty" gorm:"foreignKey:InvoiceID"`
+	CreditNotes []CreditNote  `json:"credit_notes,omitempty" gorm:"foreignKey:InvoiceID"`
 }
 
 type InvoiceItem struct {
-	ID          uint    `json:"id" gorm:"primaryKey"`
-	InvoiceID   uint    `json:"invoice_id" gorm:"not null"`
-	Description string  `json:"description" gorm:"not null"`
-	Quantity    float64 `json:"quantity" gorm:"type:decimal(10,2);not null"`
-	Unit        string  `json:"unit" gorm:"default:'pcs'"` // pcs, m2, m3, kg, etc.
-	UnitPrice   float64 `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	Total       float64 `json:"total" gorm:"type:decimal(15,2);not null"`
-	Notes       string  `json:"notes"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	InvoiceID       uint      `json:"invoice_id" gorm:"not null"`
+	Description     string    `json:"description" gorm:"not null"`
+	Quantity        float64   `json:"quantity" gorm:"type:decimal(10,2);not null"`
+	Unit            string    `json:"unit" gorm:"default:'pcs'"` // pcs, m2, m3, kg, etc.
+	UnitPrice       Money     `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	DiscountType    string    `json:"discount_type" gorm:"not null;default:'fixed'"` // fixed, percent
+	DiscountPercent float64   `json:"discount_percent" gorm:"type:decimal(5,2);default:0"`
+	Discount        Money     `json:"discount" gorm:"type:decimal(15,2);default:0"`       // Aplicado, sin superar el importe de la línea
+	DiscountFixed   Money     `json:"discount_fixed" gorm:"type:decimal(15,2);default:0"` // Importe pedido con discount_type fixed
+	Total           Money     `json:"total" gorm:"type:decimal(15,2);not null"`           // Cantidad por precio menos el descuento de la línea
+	TaxCode         string    `json:"tax_code"`                                           // Vacío usa el de la factura
+	Notes           string    `json:"notes"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 type CreateInvoiceRequest struct {
-	ClientID    uint                     `json:"client_id" binding:"required"`
-	ProjectID   *uint                    `json:"project_id"`
-	QuoteID     *uint                    `json:"quote_id"`
-	Title       string                   `json:"title" binding:"required"`
-	Description string                   `json:"description"`
-	DueDate     time.Time                `json:"due_date" binding:"required"`
-	TaxRate     float64                  `json:"tax_rate"`
-	Discount    float64                  `json:"discount"`
-	Notes       string                   `json:"notes"`
-	Terms       string                   `json:"terms"`
-	Items       []CreateInvoiceItemRequest `json:"items" binding:"required,min=1"`
+	ClientID        uint                       `json:"client_id" binding:"required"`
+	ProjectID       *uint                      `json:"project_id"` // Debe pertenecer al cliente; las cotizaciones se facturan con POST /quotes/{id}/invoice
+	Title           string                     `json:"title" binding:"required"`
+	Description     string                     `json:"description"`
+	IssueDate       *time.Time                 `json:"issue_date"`
+	DueDate         time.Time                  `json:"due_date" binding:"required"`
+	Currency        string                     `json:"currency" binding:"omitempty,len=3,alpha"` // Por defecto la moneda base
+	TaxRate         float64                    `json:"tax_rate"`
+	TaxCode         string                     `json:"tax_code"`
+	DiscountType    string                     `json:"discount_type" binding:"omitempty,oneof=fixed percent"` // Por defecto fixed
+	DiscountPercent float64                    `json:"discount_percent" binding:"gte=0,lte=100"`
+	Discount        Money                      `json:"discount" binding:"gte=0"`
+	Notes           string                     `json:"notes"`
+	Terms           string                     `json:"terms"`
+	Items           []CreateInvoiceItemRequest `json:"items" binding:"required,min=1"`
 }
 
 type CreateInvoiceItemRequest struct {
-	Description string  `json:"description" binding:"required"`
-	Quantity    float64 `json:"quantity" binding:"required,gt=0"`
-	Unit        string  `json:"unit"`
-	UnitPrice   float64 `json:"unit_price" binding:"required,gte=0"`
-	Notes       string  `json:"notes"`
+	Description     string  `json:"description" binding:"required"`
+	Quantity        float64 `json:"quantity" binding:"required,gt=0"`
+	Unit            string  `json:"unit"`
+	UnitPrice       Money   `json:"unit_price" binding:"required,gte=0"`
+	DiscountType    string  `json:"discount_type" binding:"omitempty,oneof=fixed percent"` // Por defecto fixed
+	DiscountPercent float64 `json:"discount_percent" binding:"gte=0,lte=100"`
+	Discount        Money   `json:"discount" binding:"gte=0"`
+	TaxCode         string  `json:"tax_code"`
+	Notes           string  `json:"notes"`
 }
 
 type UpdateInvoiceRequest struct {
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	DueDate     *time.Time `json:"due_date"`
-	PaidDate    *time.Time `json:"paid_date"`
-	TaxRate     float64    `json:"tax_rate"`
-	Discount    float64    `json:"discount"`
-	PaidAmount  float64    `json:"paid_amount"`
-	Notes       string     `json:"notes"`
-	Terms       string     `json:"terms"`
+	Title           *string    `json:"title"`
+	Description     *string    `json:"description"`
+	Status          *string    `json:"status"`
+	DueDate         *time.Time `json:"due_date"`
+	Currency        *string    `json:"currency" binding:"omitempty,len=3,alpha"` // Solo en borrador
+	TaxRate         *float64   `json:"tax_rate"`
+	TaxCode         *string    `json:"tax_code"`
+	DiscountType    *string    `json:"discount_type" binding:"omitempty,oneof=fixed percent"`
+	DiscountPercent *float64   `json:"discount_percent" binding:"omitempty,gte=0,lte=100"`
+	Discount        *Money     `json:"discount" binding:"omitempty,gte=0"`
+	Notes           *string    `json:"notes"`
+	Terms           *string    `json:"terms"`
 }
 
 type InvoiceStats struct {
-	TotalInvoices   int64   `json:"total_invoices"`
-	DraftInvoices   int64   `json:"draft_invoices"`
-	SentInvoices    int64   `json:"sent_invoices"`
-	PaidInvoices    int64   `json:"paid_invoices"`
-	OverdueInvoices int64   `json:"overdue_invoices"`
-	TotalValue      float64 `json:"total_value"`
-	PaidValue       float64 `json:"paid_value"`
-	OutstandingValue float64 `json:"outstanding_value"`
-}
\ No newline at end of file
+	TotalInvoices    int64    `json:"total_invoices"`
+	DraftInvoices    int64    `json:"draft_invoices"`
+	SentInvoices     int64    `json:"sent_invoices"`
+	PaidInvoices     int64    `json:"paid_invoices"`
+	OverdueInvoices  int64    `json:"overdue_invoices"`
+	TotalValue       Money    `json:"total_value"`
+	PaidValue        Money    `json:"paid_value"`
+	OutstandingValue Money    `json:"outstanding_value"`
+	Currency         string   `json:"currency"`      // Moneda base de los importes
+	MissingRates     []string `json:"missing_rates"` // Monedas y fechas sin tipo de cambio, fuera de los importes
+}
+
+type CreateInvoiceFromQuoteRequest struct {
+	Title     *string    `json:"title"`
+	IssueDate *time.Time `json:"issue_date"`
+	DueDate   *time.Time `json:"due_date"`
+}
+
+// DuplicateInvoiceRequest permite asignar la copia a otro cliente y/o proyecto
+type DuplicateInvoiceRequest struct {
+	ClientID  *uint `json:"client_id"`
+	ProjectID *uint `json:"project_id"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LaborRate es la tarifa de costo de mano de obra por tipo de trabajo. Se usa para
+// cotizar mano de obra desde el catálogo
+type LaborRate struct {
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	WorkType   string         `json:"work_type" gorm:"uniqueIndex;not null"` // construction, planning, supervision, etc.
+	Name       string         `json:"name" gorm:"not null"`
+	Unit       string         `json:"unit" gorm:"not null;default:'h'"`
+	HourlyCost Money          `json:"hourly_cost" gorm:"type:decimal(15,2);not null"`
+	IsActive   bool           `json:"is_active" gorm:"default:true"`
+	Notes      string         `json:"notes" gorm:"type:text"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type CreateLaborRateRequest struct {
+	WorkType   string `json:"work_type" binding:"required"`
+	Name       string `json:"name" binding:"required"`
+	Unit       string `json:"unit"`
+	HourlyCost Money  `json:"hourly_cost" binding:"gte=0"`
+	Notes      string `json:"notes"`
+}
+
+type UpdateLaborRateRequest struct {
+	Name       *string `json:"name" binding:"omitempty,min=1"`
+	Unit       *string `json:"unit" binding:"omitempty,min=1"`
+	HourlyCost *Money  `json:"hourly_cost" binding:"omitempty,gte=0"`
+	IsActive   *bool   `json:"is_active"`
+	Notes      *string `json:"notes"`
+}
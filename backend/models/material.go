@@ -7,26 +7,34 @@ import (
 )
 
 type Material struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Category    string         `json:"category" gorm:"not null"` // cement, steel, wood, electrical, plumbing, etc.
-	Unit        string         `json:"unit" gorm:"not null"` // kg, m3, m2, pcs, etc.
-	UnitPrice   float64        `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	Supplier    string         `json:"supplier"`
-	SKU         string         `json:"sku" gorm:"uniqueIndex"`
-	Stock       float64        `json:"stock" gorm:"type:decimal(10,2);default:0"`
-	MinStock    float64        `json:"min_stock" gorm:"type:decimal(10,2);default:0"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	Notes       string         `json:"notes" gorm:"type:text"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	Name           string         `json:"name" gorm:"not null"`
+	Description    string         `json:"description" gorm:"type:text"`
+	Category       string         `json:"category" gorm:"not null"` // cement, steel, wood, electrical, plumbing, etc.
+	Unit           string         `json:"unit" gorm:"not null"`     // kg, m3, m2, pcs, etc.
+	UnitPrice      Money          `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	Supplier       string         `json:"supplier"`
+	SKU            string         `json:"sku" gorm:"uniqueIndex"`
+	Stock          float64        `json:"stock" gorm:"type:decimal(10,2);default:0"`          // Existencia física
+	ReservedStock  float64        `json:"reserved_stock" gorm:"type:decimal(10,2);default:0"` // Apartado para proyectos (ver StockService)
+	AvailableStock float64        `json:"available_stock" gorm:"-"`                           // Stock - reservado
+	MinStock       float64        `json:"min_stock" gorm:"type:decimal(10,2);default:0"`
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	Notes          string         `json:"notes" gorm:"type:text"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relaciones
 	ProjectMaterials []ProjectMaterial `json:"project_materials,omitempty" gorm:"foreignKey:MaterialID"`
 }
 
+// AfterFind calcula el stock disponible al leer el material
+func (m *Material) AfterFind(tx *gorm.DB) error {
+	m.AvailableStock = m.Stock - m.ReservedStock
+	return nil
+}
+
 type ProjectMaterial struct {
 	ID               uint      `json:"id" gorm:"primaryKey"`
 	ProjectID        uint      `json:"project_id" gorm:"not null"`
@@ -35,9 +43,10 @@ type ProjectMaterial struct {
 	Material         Material  `json:"material" gorm:"foreignKey:MaterialID"`
 	QuantityPlanned  float64   `json:"quantity_planned" gorm:"type:decimal(10,2);not null"`
 	QuantityUsed     float64   `json:"quantity_used" gorm:"type:decimal(10,2);default:0"`
-	UnitPrice        float64   `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	TotalCost        float64   `json:"total_cost" gorm:"type:decimal(15,2);not null"`
-	Status           string    `json:"status" gorm:"default:'planned'"` // planned, ordered, delivered, used
+	QuantityReserved float64   `json:"quantity_reserved" gorm:"type:decimal(10,2);default:0"` // Stock apartado mientras está pedido o entregado
+	UnitPrice        Money     `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	TotalCost        Money     `json:"total_cost" gorm:"type:decimal(15,2);not null"` // Cantidad planificada (o usada, si ya se usó) por precio unitario
+	Status           string    `json:"status" gorm:"default:'planned'"` // planned, ordered, delivered, used, cancelled
 	DeliveryDate     *time.Time `json:"delivery_date"`
 	Notes            string    `json:"notes" gorm:"type:text"`
 	CreatedAt        time.Time `json:"created_at"`
@@ -50,14 +59,14 @@ type WorkLog struct {
 	Project     Project        `json:"project" gorm:"foreignKey:ProjectID"`
 	UserID      uint           `json:"user_id" gorm:"not null"`
 	User        User           `json:"user" gorm:"foreignKey:UserID"`
-	Date        time.Time      `json:"date" gorm:"not null"`
+	Date        time.Time      `json:"date" gorm:"not null;index"` // Día en que empieza el trabajo
 	StartTime   time.Time      `json:"start_time" gorm:"not null"`
 	EndTime     time.Time      `json:"end_time" gorm:"not null"`
-	Hours       float64        `json:"hours" gorm:"type:decimal(5,2);not null"`
+	Hours       float64        `json:"hours" gorm:"type:decimal(5,2);not null"` // Calculadas de StartTime a EndTime
 	Description string         `json:"description" gorm:"type:text;not null"`
 	WorkType    string         `json:"work_type" gorm:"not null"` // construction, planning, supervision, etc.
-	HourlyRate  float64        `json:"hourly_rate" gorm:"type:decimal(10,2)"`
-	TotalCost   float64        `json:"total_cost" gorm:"type:decimal(15,2)"`
+	HourlyRate  Money          `json:"hourly_rate" gorm:"type:decimal(10,2)"`
+	TotalCost   Money          `json:"total_cost" gorm:"type:decimal(15,2)"` // Hours × HourlyRate
 	Notes       string         `json:"notes" gorm:"type:text"`
 	CreatedAt   time.Time      `json:"created_at"`
 	UpdatedAt   time.Time      `json:"updated_at"`
@@ -69,7 +78,7 @@ type CreateMaterialRequest struct {
 	Description string  `json:"description"`
 	Category    string  `json:"category" binding:"required"`
 	Unit        string  `json:"unit" binding:"required"`
-	UnitPrice   float64 `json:"unit_price" binding:"required,gte=0"`
+	UnitPrice   Money   `json:"unit_price" binding:"required,gte=0"`
 	Supplier    string  `json:"supplier"`
 	SKU         string  `json:"sku"`
 	Stock       float64 `json:"stock"`
@@ -80,18 +89,31 @@ type CreateMaterialRequest struct {
 type CreateProjectMaterialRequest struct {
 	MaterialID      uint       `json:"material_id" binding:"required"`
 	QuantityPlanned float64    `json:"quantity_planned" binding:"required,gt=0"`
-	UnitPrice       float64    `json:"unit_price" binding:"required,gte=0"`
-	Status          string     `json:"status"`
+	UnitPrice       *Money     `json:"unit_price" binding:"omitempty,gte=0"` // Por defecto el precio del catálogo
 	DeliveryDate    *time.Time `json:"delivery_date"`
 	Notes           string     `json:"notes"`
 }
 
+type UpdateProjectMaterialRequest struct {
+	QuantityPlanned *float64   `json:"quantity_planned" binding:"omitempty,gt=0"`
+	QuantityUsed    *float64   `json:"quantity_used" binding:"omitempty,gte=0"` // Solo materiales entregados o usados
+	UnitPrice       *Money     `json:"unit_price" binding:"omitempty,gte=0"`
+	DeliveryDate    *time.Time `json:"delivery_date"`
+	Notes           *string    `json:"notes"`
+}
+
+type ChangeProjectMaterialStatusRequest struct {
+	Status       string     `json:"status" binding:"required,oneof=planned ordered delivered used cancelled"`
+	QuantityUsed *float64   `json:"quantity_used" binding:"omitempty,gte=0"` // Al marcar como usado; por defecto la cantidad planificada
+	DeliveryDate *time.Time `json:"delivery_date"`                          // Al marcar como entregado; por defecto hoy
+}
+
 type UpdateMaterialRequest struct {
 	Name        *string  `json:"name"`
 	Description *string  `json:"description"`
 	Category    *string  `json:"category"`
 	Unit        *string  `json:"unit"`
-	UnitPrice   *float64 `json:"unit_price"`
+	UnitPrice   *Money   `json:"unit_price"`
 	Supplier    *string  `json:"supplier"`
 	SKU         *string  `json:"sku"`
 	Stock       *float64 `json:"stock"`
@@ -102,11 +124,21 @@ type UpdateMaterialRequest struct {
 
 type CreateWorkLogRequest struct {
 	ProjectID   uint      `json:"project_id" binding:"required"`
-	Date        time.Time `json:"date" binding:"required"`
+	UserID      *uint     `json:"user_id"` // Por defecto el usuario autenticado
 	StartTime   time.Time `json:"start_time" binding:"required"`
 	EndTime     time.Time `json:"end_time" binding:"required"`
 	Description string    `json:"description" binding:"required"`
 	WorkType    string    `json:"work_type" binding:"required"`
-	HourlyRate  float64   `json:"hourly_rate"`
+	HourlyRate  *Money    `json:"hourly_rate" binding:"omitempty,gte=0"` // Por defecto la tarifa del tipo de trabajo
 	Notes       string    `json:"notes"`
+}
+
+type UpdateWorkLogRequest struct {
+	ProjectID   *uint      `json:"project_id"`
+	StartTime   *time.Time `json:"start_time"`
+	EndTime     *time.Time `json:"end_time"`
+	Description *string    `json:"description" binding:"omitempty,min=1"`
+	WorkType    *string    `json:"work_type" binding:"omitempty,min=1"`
+	HourlyRate  *Money     `json:"hourly_rate" binding:"omitempty,gte=0"`
+	Notes       *string    `json:"notes"`
 }
\ No newline at end of file
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money representa un importe monetario en centavos (punto fijo con dos decimales).
+// Se guarda en columnas decimal(15,2) y se serializa en JSON como número (123.45).
+//
+// Reglas de redondeo: todo resultado que no cae en un centavo exacto (importe de línea,
+// impuesto, prorrateos) se redondea al centavo más cercano, con los medios centavos
+// alejándose de cero. Las sumas y restas de Money son exactas.
+type Money int64
+
+// NewMoney convierte un número decimal a Money redondeando al centavo
+func NewMoney(value float64) Money {
+	return roundRat(new(big.Rat).Mul(exactRat(value), big.NewRat(100, 1)))
+}
+
+// ParseMoney interpreta un importe en texto decimal ("1234.5", "-0.125")
+func ParseMoney(value string) (Money, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	rat, ok := new(big.Rat).SetString(value)
+	if !ok {
+		return 0, fmt.Errorf("importe inválido: %q", value)
+	}
+	return roundRat(rat.Mul(rat, big.NewRat(100, 1))), nil
+}
+
+// Float64 devuelve el importe como número de punto flotante (solo para presentación)
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String devuelve el importe con dos decimales
+func (m Money) String() string {
+	sign := ""
+	cents := int64(m)
+	if cents < 0 {
+		sign = "-"
+		cents = -cents
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, cents/100, cents%100)
+}
+
+// MulFloat multiplica el importe por una cantidad (por ejemplo, unidades de una línea)
+// y redondea al centavo
+func (m Money) MulFloat(factor float64) Money {
+	return roundRat(new(big.Rat).Mul(exactRat(factor), new(big.Rat).SetInt64(int64(m))))
+}
+
+// Percent calcula el porcentaje indicado del importe (por ejemplo, la tasa de impuesto)
+// y redondea al centavo
+func (m Money) Percent(rate float64) Money {
+	rat := new(big.Rat).Mul(exactRat(rate), new(big.Rat).SetInt64(int64(m)))
+	return roundRat(rat.Quo(rat, big.NewRat(100, 1)))
+}
+
+// MulRatio calcula m * numerator / denominator redondeando al centavo. Se usa para
+// prorratear importes (por ejemplo, el impuesto de una devolución parcial)
+func (m Money) MulRatio(numerator, denominator Money) Money {
+	if denominator == 0 {
+		return 0
+	}
+	rat := new(big.Rat).SetFrac(
+		new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(numerator))),
+		big.NewInt(int64(denominator)),
+	)
+	return roundRat(rat)
+}
+
+// LineTotal calcula el importe de una línea: cantidad por precio unitario, redondeado al centavo
+func LineTotal(quantity float64, unitPrice Money) Money {
+	return unitPrice.MulFloat(quantity)
+}
+
+// MarshalJSON serializa el importe como número con dos decimales
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON acepta números o cadenas con el importe en decimal, sin pasar por float64
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if strings.HasPrefix(text, `"`) {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+	}
+	value, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = value
+	return nil
+}
+
+// Value guarda el importe como texto decimal para columnas numeric/decimal
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan lee el importe desde columnas numeric/decimal
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+		return nil
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case int64:
+		*m = Money(v * 100)
+		return nil
+	case float64:
+		*m = NewMoney(v)
+		return nil
+	}
+	return errors.New("tipo no soportado para Money")
+}
+
+// exactRat convierte un float64 al decimal más corto que lo representa (el valor que
+// escribió el usuario), evitando arrastrar el error binario de la representación
+func exactRat(value float64) *big.Rat {
+	rat, _ := new(big.Rat).SetString(strconv.FormatFloat(value, 'f', -1, 64))
+	return rat
+}
+
+// roundRat redondea un valor expresado en centavos al entero más cercano, con los medios
+// alejándose de cero
+func roundRat(rat *big.Rat) Money {
+	num := new(big.Int).Abs(rat.Num())
+	den := rat.Denom()
+	quotient, remainder := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(remainder, big.NewInt(2)).Cmp(den) >= 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	if rat.Sign() < 0 {
+		quotient.Neg(quotient)
+	}
+	return Money(quotient.Int64())
+}
@@ -0,0 +1,239 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestNewMoneyRoundsHalfAwayFromZero(t *testing.T) {
+	tests := []struct {
+		value float64
+		want  Money
+	}{
+		{0, 0},
+		{1.005, 101},
+		{1.004, 100},
+		{-1.005, -101},
+		{-1.004, -100},
+		{0.125, 13},
+		{-0.125, -13},
+		{2.675, 268},
+		{1234.5, 123450},
+	}
+	for _, tt := range tests {
+		if got := NewMoney(tt.value); got != tt.want {
+			t.Errorf("NewMoney(%v) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Money
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"  12.34 ", 1234, false},
+		{"1234.5", 123450, false},
+		{"-0.125", -13, false},
+		{"0.005", 1, false},
+		{"0.0049", 0, false},
+		{"9999999999999.99", 999999999999999, false},
+		{"abc", 0, true},
+		{"1,5", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseMoney(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, want %d", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		value Money
+		want  string
+	}{
+		{0, "0.00"},
+		{5, "0.05"},
+		{-5, "-0.05"},
+		{123450, "1234.50"},
+		{-100, "-1.00"},
+	}
+	for _, tt := range tests {
+		if got := tt.value.String(); got != tt.want {
+			t.Errorf("Money(%d).String() = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulFloat(t *testing.T) {
+	tests := []struct {
+		value  Money
+		factor float64
+		want   Money
+	}{
+		{1999, 3, 5997},
+		{1000, 0.333, 333},
+		{1000, 1.0005, 1001},   // 1000.5 centavos
+		{-1000, 1.0005, -1001}, // -1000.5 centavos
+		{33, 0.5, 17},          // 16.5 centavos
+		{10, 0.1, 1},           // Sin arrastrar el error binario de 0.1
+	}
+	for _, tt := range tests {
+		if got := tt.value.MulFloat(tt.factor); got != tt.want {
+			t.Errorf("Money(%d).MulFloat(%v) = %d, want %d", tt.value, tt.factor, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyPercent(t *testing.T) {
+	tests := []struct {
+		value Money
+		rate  float64
+		want  Money
+	}{
+		{10000, 16, 1600},
+		{1, 50, 1},   // 0.5 centavos
+		{-1, 50, -1}, // -0.5 centavos
+		{1, 49, 0},   // 0.49 centavos
+		{3125, 16, 500},
+		{12345, 10.6667, 1317}, // 1316.80 centavos
+		{10000, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := tt.value.Percent(tt.rate); got != tt.want {
+			t.Errorf("Money(%d).Percent(%v) = %d, want %d", tt.value, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyMulRatio(t *testing.T) {
+	tests := []struct {
+		value                  Money
+		numerator, denominator Money
+		want                   Money
+	}{
+		{1000, 1, 3, 333},
+		{1000, 2, 3, 667},
+		{-1000, 2, 3, -667},
+		{1, 1, 2, 1},   // 0.5 centavos
+		{-1, 1, 2, -1}, // -0.5 centavos
+		{1600, 5000, 10000, 800},
+		{1600, 0, 10000, 0},
+		{1600, 5000, 0, 0}, // Sin base no hay nada que prorratear
+	}
+	for _, tt := range tests {
+		if got := tt.value.MulRatio(tt.numerator, tt.denominator); got != tt.want {
+			t.Errorf("Money(%d).MulRatio(%d, %d) = %d, want %d", tt.value, tt.numerator, tt.denominator, got, tt.want)
+		}
+	}
+}
+
+func TestLineTotal(t *testing.T) {
+	tests := []struct {
+		quantity  float64
+		unitPrice Money
+		want      Money
+	}{
+		{2.5, 1999, 4998}, // 4997.5 centavos
+		{0.1, 3, 0},       // 0.3 centavos
+		{1.15, 1000, 1150},
+		{3, 0, 0},
+	}
+	for _, tt := range tests {
+		if got := LineTotal(tt.quantity, tt.unitPrice); got != tt.want {
+			t.Errorf("LineTotal(%v, %d) = %d, want %d", tt.quantity, tt.unitPrice, got, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	data, err := json.Marshal(struct {
+		Amount Money `json:"amount"`
+	}{Amount: -123405})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(data), `{"amount":-1234.05}`; got != want {
+		t.Errorf("json.Marshal = %s, want %s", got, want)
+	}
+
+	tests := []struct {
+		input   string
+		want    Money
+		wantErr bool
+	}{
+		{`12.34`, 1234, false},
+		{`"12.34"`, 1234, false},
+		{`0.125`, 13, false},
+		{`1e2`, 10000, false},
+		{`null`, 0, false},
+		{`"abc"`, 0, true},
+		{`true`, 0, true},
+	}
+	for _, tt := range tests {
+		var got Money
+		err := json.Unmarshal([]byte(tt.input), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("json.Unmarshal(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("json.Unmarshal(%s) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+// Los importes deben ir y volver de una columna decimal(15,2) sin cambiar
+func TestMoneyDecimalRoundTrip(t *testing.T) {
+	for _, value := range []Money{0, 1, -1, 99, 100, 123456, -123456, 999999999999999, -999999999999999} {
+		stored, err := value.Value()
+		if err != nil {
+			t.Fatalf("Money(%d).Value() error = %v", value, err)
+		}
+
+		var fromString, fromBytes Money
+		if err := fromString.Scan(stored); err != nil {
+			t.Fatalf("Scan(%v) error = %v", stored, err)
+		}
+		if err := fromBytes.Scan([]byte(stored.(string))); err != nil {
+			t.Fatalf("Scan([]byte %v) error = %v", stored, err)
+		}
+		if fromString != value || fromBytes != value {
+			t.Errorf("round trip of %d via %q = %d / %d", value, stored, fromString, fromBytes)
+		}
+	}
+}
+
+func TestMoneyScan(t *testing.T) {
+	tests := []struct {
+		input   interface{}
+		want    Money
+		wantErr bool
+	}{
+		{nil, 0, false},
+		{"1234.56", 123456, false},
+		{[]byte("-0.01"), -1, false},
+		{int64(15), 1500, false},
+		{float64(12.345), 1235, false},
+		{true, 0, true},
+	}
+	for _, tt := range tests {
+		got := Money(99)
+		err := got.Scan(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%#v) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Scan(%#v) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
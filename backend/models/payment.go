@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+)
+
+type Payment struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	InvoiceID    uint       `json:"invoice_id" gorm:"not null;index"`
+	Invoice      *Invoice   `json:"invoice,omitempty" gorm:"foreignKey:InvoiceID"`
+	Amount       Money      `json:"amount" gorm:"type:decimal(15,2);not null"`
+	PaymentDate  time.Time  `json:"payment_date" gorm:"not null"`
+	Method       string     `json:"method" gorm:"not null"` // cash, transfer, check, card, other
+	Reference    string     `json:"reference"`              // Número de transferencia, cheque, etc.
+	Notes        string     `json:"notes" gorm:"type:text"`
+	RecordedByID uint       `json:"recorded_by_id" gorm:"not null"`
+	RecordedBy   User       `json:"recorded_by" gorm:"foreignKey:RecordedByID"`
+	IsVoided     bool       `json:"is_voided" gorm:"default:false"`
+	VoidedAt     *time.Time `json:"voided_at"`
+	VoidedByID   *uint      `json:"voided_by_id"`
+	VoidedBy     *User      `json:"voided_by,omitempty" gorm:"foreignKey:VoidedByID"`
+	VoidReason   string     `json:"void_reason" gorm:"type:text"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+type CreatePaymentRequest struct {
+	Amount      Money      `json:"amount" binding:"required,gt=0"`
+	PaymentDate *time.Time `json:"payment_date"`
+	Method      string     `json:"method" binding:"required,oneof=cash transfer check card other"`
+	Reference   string     `json:"reference"`
+	Notes       string     `json:"notes"`
+}
+
+type VoidPaymentRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
@@ -23,9 +23,9 @@ type Project struct {
 	ZipCode      string         `json:"zip_code"`
 	StartDate    *time.Time     `json:"start_date"`
 	EndDate      *time.Time     `json:"end_date"`
-	Budget       float64        `json:"budget" gorm:"type:decimal(15,2)"`
-	EstimatedCost float64       `json:"estimated_cost" gorm:"type:decimal(15,2);default:0"`
-	ActualCost   float64        `json:"actual_cost" gorm:"type:decimal(15,2);default:0"`
+	Budget       Money          `json:"budget" gorm:"type:decimal(15,2)"`
+	EstimatedCost Money         `json:"estimated_cost" gorm:"type:decimal(15,2);default:0"`
+	ActualCost   Money          `json:"actual_cost" gorm:"type:decimal(15,2);default:0"` // Calculado por ProjectCostService
 	Progress     int            `json:"progress" gorm:"default:0"` // Porcentaje de 0-100
 	Notes        string         `json:"notes" gorm:"type:text"`
 	CreatedAt    time.Time      `json:"created_at"`
@@ -37,6 +37,7 @@ type Project struct {
 	Invoices        []Invoice         `json:"invoices,omitempty" gorm:"foreignKey:ProjectID"`
 	ProjectMaterials []ProjectMaterial `json:"project_materials,omitempty" gorm:"foreignKey:ProjectID"`
 	WorkLogs        []WorkLog         `json:"work_logs,omitempty" gorm:"foreignKey:ProjectID"`
+	Expenses        []ProjectExpense  `json:"expenses,omitempty" gorm:"foreignKey:ProjectID"`
 }
 
 type CreateProjectRequest struct {
@@ -53,8 +54,8 @@ type CreateProjectRequest struct {
 	ZipCode       string     `json:"zip_code"`
 	StartDate     *time.Time `json:"start_date"`
 	EndDate       *time.Time `json:"end_date"`
-	Budget        float64    `json:"budget"`
-	EstimatedCost float64    `json:"estimated_cost"`
+	Budget        Money      `json:"budget"`
+	EstimatedCost Money      `json:"estimated_cost"`
 	Notes         string     `json:"notes"`
 }
 
@@ -71,9 +72,8 @@ type UpdateProjectRequest struct {
 	ZipCode       *string    `json:"zip_code"`
 	StartDate     *time.Time `json:"start_date"`
 	EndDate       *time.Time `json:"end_date"`
-	Budget        *float64   `json:"budget"`
-	EstimatedCost *float64   `json:"estimated_cost"`
-	ActualCost    *float64   `json:"actual_cost"`
+	Budget        *Money     `json:"budget"`
+	EstimatedCost *Money     `json:"estimated_cost"`
 	Progress      *int       `json:"progress"`
 	Notes         *string    `json:"notes"`
 }
@@ -83,7 +83,13 @@ type ProjectStats struct {
 	ActiveProjects    int64   `json:"active_projects"`
 	CompletedProjects int64   `json:"completed_projects"`
 	PlanningProjects  int64   `json:"planning_projects"`
-	TotalBudget       float64 `json:"total_budget"`
-	TotalActualCost   float64 `json:"total_actual_cost"`
+	TotalBudget       Money   `json:"total_budget"`
+	TotalActualCost   Money   `json:"total_actual_cost"`
 	AverageProgress   float64 `json:"average_progress"`
-}
\ No newline at end of file
+}
+
+// DuplicateProjectRequest permite asignar la copia a otro cliente y cambiar su nombre
+type DuplicateProjectRequest struct {
+	ClientID *uint  `json:"client_id"`
+	Name     string `json:"name"` // Por defecto el nombre original con "(copia)"
+}
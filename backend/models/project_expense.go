@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ProjectExpense es un gasto del proyecto que no es material del inventario ni mano de obra
+// registrada (subcontratos, alquiler de equipos, fletes, permisos, etc.). Suma al costo
+// real del proyecto
+type ProjectExpense struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	ProjectID    uint           `json:"project_id" gorm:"not null;index"`
+	Date         time.Time      `json:"date" gorm:"not null"`
+	Category     string         `json:"category" gorm:"not null;default:'other'"` // subcontract, equipment, transport, permits, other
+	Description  string         `json:"description" gorm:"type:text;not null"`
+	Amount       Money          `json:"amount" gorm:"type:decimal(15,2);not null"`
+	Supplier     string         `json:"supplier"`
+	Reference    string         `json:"reference"` // Número de factura del proveedor, recibo, etc.
+	RecordedByID *uint          `json:"recorded_by_id"`
+	RecordedBy   *User          `json:"recorded_by,omitempty" gorm:"foreignKey:RecordedByID"`
+	Notes        string         `json:"notes" gorm:"type:text"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+type CreateProjectExpenseRequest struct {
+	Date        *time.Time `json:"date"` // Por defecto hoy
+	Category    string     `json:"category" binding:"omitempty,oneof=subcontract equipment transport permits other"`
+	Description string     `json:"description" binding:"required"`
+	Amount      Money      `json:"amount" binding:"gt=0"`
+	Supplier    string     `json:"supplier"`
+	Reference   string     `json:"reference"`
+	Notes       string     `json:"notes"`
+}
+
+type UpdateProjectExpenseRequest struct {
+	Date        *time.Time `json:"date"`
+	Category    *string    `json:"category" binding:"omitempty,oneof=subcontract equipment transport permits other"`
+	Description *string    `json:"description" binding:"omitempty,min=1"`
+	Amount      *Money     `json:"amount" binding:"omitempty,gt=0"`
+	Supplier    *string    `json:"supplier"`
+	Reference   *string    `json:"reference"`
+	Notes       *string    `json:"notes"`
+}
@@ -1,89 +1,180 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"gorm.io/gorm"
 )
 
 type Quote struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	QuoteNumber string         `json:"quote_number" gorm:"uniqueIndex;not null"`
-	ClientID    uint           `json:"client_id" gorm:"not null"`
-	Client      Client         `json:"client" gorm:"foreignKey:ClientID"`
-	ProjectID   *uint          `json:"project_id"`
-	Project     *Project       `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
-	Title       string         `json:"title" gorm:"not null"`
-	Description string         `json:"description" gorm:"type:text"`
-	Status      string         `json:"status" gorm:"default:'draft'"` // draft, sent, accepted, rejected, expired
-	ValidUntil  *time.Time     `json:"valid_until"`
-	Subtotal    float64        `json:"subtotal" gorm:"type:decimal(15,2);default:0"`
-	TaxRate     float64        `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
-	TaxAmount   float64        `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`
-	Discount    float64        `json:"discount" gorm:"type:decimal(15,2);default:0"`
-	Total       float64        `json:"total" gorm:"type:decimal(15,2);default:0"`
-	Notes       string         `json:"notes" gorm:"type:text"`
-	Terms       string         `json:"terms" gorm:"type:text"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                uint           `json:"id" gorm:"primaryKey"`
+	QuoteNumber       string         `json:"quote_number" gorm:"uniqueIndex;not null"`
+	ClientID          uint           `json:"client_id" gorm:"not null"`
+	Client            Client         `json:"client" gorm:"foreignKey:ClientID"`
+	ProjectID         *uint          `json:"project_id"`
+	Project           *Project       `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
+	Title             string         `json:"title" gorm:"not null"`
+	Description       string         `json:"description" gorm:"type:text"`
+	Status            string         `json:"status" gorm:"default:'draft'"` // draft, sent, accepted, rejected, expired
+	ValidUntil        *time.Time     `json:"valid_until"`
+	Currency          string         `json:"currency" gorm:"size:3;not null;default:'MXN'"` // Código ISO 4217 de los importes
+	Subtotal          Money          `json:"subtotal" gorm:"type:decimal(15,2);default:0"`
+	TaxRate           float64        `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	TaxCode           string         `json:"tax_code"`                                               // Código de impuesto por defecto de los items (ver TaxCode)
+	TaxAmount         Money          `json:"tax_amount" gorm:"type:decimal(15,2);default:0"`         // Impuestos trasladados
+	WithholdingAmount Money          `json:"withholding_amount" gorm:"type:decimal(15,2);default:0"` // Retenciones
+	TaxBreakdown      TaxBreakdown   `json:"tax_breakdown" gorm:"type:jsonb"`
+	DiscountType      string         `json:"discount_type" gorm:"not null;default:'fixed'"`       // fixed, percent
+	DiscountPercent   float64        `json:"discount_percent" gorm:"type:decimal(5,2);default:0"` // Con discount_type percent
+	Discount          Money          `json:"discount" gorm:"type:decimal(15,2);default:0"`        // Descuento aplicado sobre el subtotal, antes de impuestos
+	DiscountFixed     Money          `json:"discount_fixed" gorm:"type:decimal(15,2);default:0"`  // Importe pedido con discount_type fixed; discount puede ser menor si supera el subtotal
+	Total             Money          `json:"total" gorm:"type:decimal(15,2);default:0"`
+	MarkupPercent     float64        `json:"markup_percent" gorm:"type:decimal(7,2);default:0"` // Margen por defecto para los items del catálogo
+	CostTotal         Money          `json:"cost_total" gorm:"type:decimal(15,2);default:0"`
+	GrossMargin       Money          `json:"gross_margin" gorm:"type:decimal(15,2);default:0"`   // Subtotal - descuento - costo
+	MarginPercent     float64        `json:"margin_percent" gorm:"type:decimal(12,2);default:0"` // Margen bruto sobre la venta neta
+	Notes             string         `json:"notes" gorm:"type:text"`
+	Terms             string         `json:"terms" gorm:"type:text"`
+	Revision          int            `json:"revision" gorm:"not null;default:1"` // Revisión vigente (ver QuoteVersion)
+	SentAt            *time.Time     `json:"sent_at"`
+	AcceptedAt        *time.Time     `json:"accepted_at"`
+	RejectedAt        *time.Time     `json:"rejected_at"`
+	RejectionReason   string         `json:"rejection_reason" gorm:"type:text"`
+	ExpiredAt         *time.Time     `json:"expired_at"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relaciones
 	Items []QuoteItem `json:"items,omitempty" gorm:"foreignKey:QuoteID;constraint:OnDelete:CASCADE"`
 }
 
 type QuoteItem struct {
-	ID          uint    `json:"id" gorm:"primaryKey"`
-	QuoteID     uint    `json:"quote_id" gorm:"not null"`
-	Description string  `json:"description" gorm:"not null"`
-	Quantity    float64 `json:"quantity" gorm:"type:decimal(10,2);not null"`
-	Unit        string  `json:"unit" gorm:"default:'pcs'"` // pcs, m2, m3, kg, etc.
-	UnitPrice   float64 `json:"unit_price" gorm:"type:decimal(15,2);not null"`
-	Total       float64 `json:"total" gorm:"type:decimal(15,2);not null"`
-	Notes       string  `json:"notes"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	QuoteID         uint      `json:"quote_id" gorm:"not null"`
+	MaterialID      *uint     `json:"material_id"`
+	Material        *Material `json:"material,omitempty" gorm:"foreignKey:MaterialID"`
+	WorkType        string    `json:"work_type"` // Mano de obra del catálogo (LaborRate)
+	Description     string    `json:"description" gorm:"not null"`
+	Quantity        float64   `json:"quantity" gorm:"type:decimal(10,2);not null"`
+	Unit            string    `json:"unit" gorm:"default:'pcs'"` // pcs, m2, m3, kg, etc.
+	UnitCost        Money     `json:"unit_cost" gorm:"type:decimal(15,2);default:0"`
+	MarkupPercent   float64   `json:"markup_percent" gorm:"type:decimal(12,2);default:0"`
+	UnitPrice       Money     `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	CostTotal       Money     `json:"cost_total" gorm:"type:decimal(15,2);default:0"`
+	DiscountType    string    `json:"discount_type" gorm:"not null;default:'fixed'"` // fixed, percent
+	DiscountPercent float64   `json:"discount_percent" gorm:"type:decimal(5,2);default:0"`
+	Discount        Money     `json:"discount" gorm:"type:decimal(15,2);default:0"`       // Aplicado, sin superar el importe de la línea
+	DiscountFixed   Money     `json:"discount_fixed" gorm:"type:decimal(15,2);default:0"` // Importe pedido con discount_type fixed
+	Total           Money     `json:"total" gorm:"type:decimal(15,2);not null"`           // Cantidad por precio menos el descuento de la línea
+	TaxCode         string    `json:"tax_code"`                                           // Vacío usa el de la cotización
+	Notes           string    `json:"notes"`
+	Position        int       `json:"position" gorm:"default:0"` // Orden de la línea dentro de la cotización
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 type CreateQuoteRequest struct {
-	ClientID    uint                    `json:"client_id" binding:"required"`
-	ProjectID   *uint                   `json:"project_id"`
-	Title       string                  `json:"title" binding:"required"`
-	Description string                  `json:"description"`
-	ValidUntil  *time.Time              `json:"valid_until"`
-	TaxRate     float64                 `json:"tax_rate"`
-	Discount    float64                 `json:"discount"`
-	Notes       string                  `json:"notes"`
-	Terms       string                  `json:"terms"`
-	Items       []CreateQuoteItemRequest `json:"items" binding:"required,min=1"`
+	ClientID        uint                     `json:"client_id" binding:"required"`
+	ProjectID       *uint                    `json:"project_id"`
+	Title           string                   `json:"title" binding:"required"`
+	Description     string                   `json:"description"`
+	ValidUntil      *time.Time               `json:"valid_until"`
+	Currency        string                   `json:"currency" binding:"omitempty,len=3,alpha"` // Por defecto la moneda base
+	TaxRate         float64                  `json:"tax_rate"`
+	TaxCode         string                   `json:"tax_code"`
+	DiscountType    string                   `json:"discount_type" binding:"omitempty,oneof=fixed percent"` // Por defecto fixed
+	DiscountPercent float64                  `json:"discount_percent" binding:"gte=0,lte=100"`
+	Discount        Money                    `json:"discount" binding:"gte=0"`
+	MarkupPercent   float64                  `json:"markup_percent" binding:"gte=0"`
+	Notes           string                   `json:"notes"`
+	Terms           string                   `json:"terms"`
+	Items           []CreateQuoteItemRequest `json:"items" binding:"required,min=1,dive"`
 }
 
+// CreateQuoteItemRequest admite items libres o del catálogo. Con material_id o work_type la
+// descripción, la unidad y el costo se toman del catálogo, y el precio es el costo más el
+// margen (el del item o, si no se indica, el de la cotización). Un unit_price explícito
+// tiene prioridad sobre el margen
 type CreateQuoteItemRequest struct {
-	Description string  `json:"description" binding:"required"`
-	Quantity    float64 `json:"quantity" binding:"required,gt=0"`
-	Unit        string  `json:"unit"`
-	UnitPrice   float64 `json:"unit_price" binding:"required,gte=0"`
-	Notes       string  `json:"notes"`
+	MaterialID      *uint    `json:"material_id"`
+	WorkType        string   `json:"work_type"`
+	Description     string   `json:"description"`
+	Quantity        float64  `json:"quantity" binding:"required,gt=0"`
+	Unit            string   `json:"unit"`
+	UnitCost        *Money   `json:"unit_cost" binding:"omitempty,gte=0"`
+	MarkupPercent   *float64 `json:"markup_percent" binding:"omitempty,gte=0"`
+	UnitPrice       *Money   `json:"unit_price" binding:"omitempty,gte=0"`
+	DiscountType    string   `json:"discount_type" binding:"omitempty,oneof=fixed percent"` // Por defecto fixed
+	DiscountPercent float64  `json:"discount_percent" binding:"gte=0,lte=100"`
+	Discount        Money    `json:"discount" binding:"gte=0"`
+	TaxCode         string   `json:"tax_code"`
+	Notes           string   `json:"notes"`
+}
+
+type UpdateQuoteItemRequest struct {
+	Description     *string  `json:"description" binding:"omitempty,min=1"`
+	Quantity        *float64 `json:"quantity" binding:"omitempty,gt=0"`
+	Unit            *string  `json:"unit"`
+	UnitCost        *Money   `json:"unit_cost" binding:"omitempty,gte=0"`
+	MarkupPercent   *float64 `json:"markup_percent" binding:"omitempty,gte=0"`
+	UnitPrice       *Money   `json:"unit_price" binding:"omitempty,gte=0"`
+	DiscountType    *string  `json:"discount_type" binding:"omitempty,oneof=fixed percent"`
+	DiscountPercent *float64 `json:"discount_percent" binding:"omitempty,gte=0,lte=100"`
+	Discount        *Money   `json:"discount" binding:"omitempty,gte=0"`
+	TaxCode         *string  `json:"tax_code"`
+	Notes           *string  `json:"notes"`
+}
+
+type ReorderQuoteItemsRequest struct {
+	ItemIDs []uint `json:"item_ids" binding:"required,min=1"`
+}
+
+type ChangeQuoteStatusRequest struct {
+	Status string `json:"status" binding:"required,oneof=draft sent accepted rejected expired"`
+	Reason string `json:"reason"` // Obligatorio al rechazar
+}
+
+// DuplicateQuoteRequest permite asignar la copia a otro cliente y/o proyecto
+type DuplicateQuoteRequest struct {
+	ClientID  *uint `json:"client_id"`
+	ProjectID *uint `json:"project_id"`
 }
 
 type UpdateQuoteRequest struct {
-	Title       *string    `json:"title"`
-	Description *string    `json:"description"`
-	Status      *string    `json:"status"`
-	ValidUntil  *time.Time `json:"valid_until"`
-	TaxRate     *float64   `json:"tax_rate"`
-	Discount    *float64   `json:"discount"`
-	Notes       *string    `json:"notes"`
-	Terms       *string    `json:"terms"`
+	Title           *string    `json:"title"`
+	Description     *string    `json:"description"`
+	ValidUntil      *time.Time `json:"valid_until"`
+	Currency        *string    `json:"currency" binding:"omitempty,len=3,alpha"`
+	TaxRate         *float64   `json:"tax_rate"`
+	TaxCode         *string    `json:"tax_code"`
+	DiscountType    *string    `json:"discount_type" binding:"omitempty,oneof=fixed percent"`
+	DiscountPercent *float64   `json:"discount_percent" binding:"omitempty,gte=0,lte=100"`
+	Discount        *Money     `json:"discount" binding:"omitempty,gte=0"`
+	MarkupPercent   *float64   `json:"markup_percent" binding:"omitempty,gte=0"` // Solo aplica a los items que se agreguen después
+	Notes           *string    `json:"notes"`
+	Terms           *string    `json:"terms"`
 }
 
 type QuoteStats struct {
-	TotalQuotes      int64   `json:"total_quotes"`
-	DraftQuotes      int64   `json:"draft_quotes"`
-	SentQuotes       int64   `json:"sent_quotes"`
-	AcceptedQuotes   int64   `json:"accepted_quotes"`
-	RejectedQuotes   int64   `json:"rejected_quotes"`
-	TotalValue       float64 `json:"total_value"`
-	AcceptedValue    float64 `json:"accepted_value"`
-	ThisMonthQuotes  int64   `json:"this_month_quotes"`
-}
\ No newline at end of file
+	TotalQuotes     int64 `json:"total_quotes"`
+	DraftQuotes     int64 `json:"draft_quotes"`
+	SentQuotes      int64 `json:"sent_quotes"`
+	AcceptedQuotes  int64 `json:"accepted_quotes"`
+	RejectedQuotes  int64 `json:"rejected_quotes"`
+	TotalValue      Money `json:"total_value"`
+	AcceptedValue   Money `json:"accepted_value"`
+	ThisMonthQuotes int64 `json:"this_month_quotes"`
+}
+
+// CalculateMargin actualiza el margen bruto de la cotización a partir del subtotal (neto de
+// descuentos de línea), el descuento del documento y el costo total de sus items
+func (q *Quote) CalculateMargin() {
+	net := q.Subtotal - q.Discount
+	q.GrossMargin = net - q.CostTotal
+	q.MarginPercent = 0
+	if net != 0 {
+		q.MarginPercent = math.Round(float64(q.GrossMargin)/float64(net)*10000) / 100
+	}
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// QuoteApproval registra la decisión de un cliente sobre una cotización, tomada desde
+// el enlace público de aprobación
+type QuoteApproval struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	QuoteID     uint      `json:"quote_id" gorm:"not null;index"`
+	Revision    int       `json:"revision" gorm:"not null"`
+	Decision    string    `json:"decision" gorm:"not null"` // accepted, rejected
+	SignerName  string    `json:"signer_name" gorm:"not null"`
+	SignerEmail string    `json:"signer_email"`
+	Reason      string    `json:"reason" gorm:"type:text"`
+	IPAddress   string    `json:"ip_address" gorm:"not null"`
+	UserAgent   string    `json:"user_agent"`
+	SignedAt    time.Time `json:"signed_at" gorm:"not null"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type CreateQuoteApprovalLinkRequest struct {
+	ExpiresInDays *int `json:"expires_in_days" binding:"omitempty,min=1,max=90"`
+}
+
+type AcceptQuoteRequest struct {
+	SignerName  string `json:"signer_name" binding:"required"`
+	SignerEmail string `json:"signer_email" binding:"omitempty,email"`
+}
+
+type RejectQuoteRequest struct {
+	SignerName  string `json:"signer_name" binding:"required"`
+	SignerEmail string `json:"signer_email" binding:"omitempty,email"`
+	Reason      string `json:"reason" binding:"required"`
+}
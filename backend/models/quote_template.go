@@ -0,0 +1,141 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QuoteTemplate es una cotización modelo para trabajos que se cotizan seguido. Las
+// cantidades de sus items son fórmulas de los parámetros (por ejemplo, el área en m2)
+type QuoteTemplate struct {
+	ID            uint                    `json:"id" gorm:"primaryKey"`
+	Name          string                  `json:"name" gorm:"uniqueIndex;not null"`
+	Description   string                  `json:"description" gorm:"type:text"`
+	Title         string                  `json:"title" gorm:"not null"`
+	TaxRate       float64                 `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	TaxCode       string                  `json:"tax_code"`
+	MarkupPercent float64                 `json:"markup_percent" gorm:"type:decimal(7,2);default:0"`
+	ValidDays     int                     `json:"valid_days" gorm:"default:0"` // Días de validez de las cotizaciones generadas (0 = sin fecha)
+	Notes         string                  `json:"notes" gorm:"type:text"`
+	Terms         string                  `json:"terms" gorm:"type:text"`
+	Parameters    QuoteTemplateParameters `json:"parameters" gorm:"type:jsonb;not null"`
+	IsActive      bool                    `json:"is_active" gorm:"default:true"`
+	CreatedAt     time.Time               `json:"created_at"`
+	UpdatedAt     time.Time               `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt          `json:"-" gorm:"index"`
+
+	// Relaciones
+	Items []QuoteTemplateItem `json:"items,omitempty" gorm:"foreignKey:TemplateID;constraint:OnDelete:CASCADE"`
+}
+
+// QuoteTemplateItem es una línea de la plantilla. Los datos de catálogo y precio siguen
+// las mismas reglas que CreateQuoteItemRequest
+type QuoteTemplateItem struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	TemplateID      uint      `json:"template_id" gorm:"not null;index"`
+	Position        int       `json:"position" gorm:"default:0"`
+	MaterialID      *uint     `json:"material_id"`
+	Material        *Material `json:"material,omitempty" gorm:"foreignKey:MaterialID"`
+	WorkType        string    `json:"work_type"`
+	Description     string    `json:"description"`
+	Unit            string    `json:"unit"`
+	QuantityFormula string    `json:"quantity_formula" gorm:"not null"` // ceil(area * 1.1 / 25)
+	UnitCost        *Money    `json:"unit_cost" gorm:"type:decimal(15,2)"`
+	MarkupPercent   *float64  `json:"markup_percent" gorm:"type:decimal(12,2)"`
+	UnitPrice       *Money    `json:"unit_price" gorm:"type:decimal(15,2)"`
+	TaxCode         string    `json:"tax_code"`
+	Notes           string    `json:"notes"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// QuoteTemplateParameter es un dato que se pide al usar la plantilla
+type QuoteTemplateParameter struct {
+	Name    string   `json:"name" binding:"required"` // Nombre usado en las fórmulas: area, altura
+	Label   string   `json:"label"`
+	Unit    string   `json:"unit"`
+	Default *float64 `json:"default"`
+}
+
+type QuoteTemplateParameters []QuoteTemplateParameter
+
+// Value guarda los parámetros como JSON
+func (p QuoteTemplateParameters) Value() (driver.Value, error) {
+	if p == nil {
+		p = QuoteTemplateParameters{}
+	}
+	data, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan lee los parámetros desde una columna JSON
+func (p *QuoteTemplateParameters) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, p)
+	case string:
+		return json.Unmarshal([]byte(v), p)
+	case nil:
+		*p = QuoteTemplateParameters{}
+		return nil
+	}
+	return errors.New("tipo no soportado para QuoteTemplateParameters")
+}
+
+type CreateQuoteTemplateRequest struct {
+	Name          string                     `json:"name" binding:"required"`
+	Description   string                     `json:"description"`
+	Title         string                     `json:"title" binding:"required"`
+	TaxRate       float64                    `json:"tax_rate"`
+	TaxCode       string                     `json:"tax_code"`
+	MarkupPercent float64                    `json:"markup_percent" binding:"gte=0"`
+	ValidDays     int                        `json:"valid_days" binding:"gte=0"`
+	Notes         string                     `json:"notes"`
+	Terms         string                     `json:"terms"`
+	Parameters    []QuoteTemplateParameter   `json:"parameters" binding:"dive"`
+	Items         []QuoteTemplateItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+type QuoteTemplateItemRequest struct {
+	MaterialID      *uint    `json:"material_id"`
+	WorkType        string   `json:"work_type"`
+	Description     string   `json:"description"`
+	Unit            string   `json:"unit"`
+	QuantityFormula string   `json:"quantity_formula" binding:"required"`
+	UnitCost        *Money   `json:"unit_cost" binding:"omitempty,gte=0"`
+	MarkupPercent   *float64 `json:"markup_percent" binding:"omitempty,gte=0"`
+	UnitPrice       *Money   `json:"unit_price" binding:"omitempty,gte=0"`
+	TaxCode         string   `json:"tax_code"`
+	Notes           string   `json:"notes"`
+}
+
+// UpdateQuoteTemplateRequest actualiza la plantilla; si se envían items reemplazan a los actuales
+type UpdateQuoteTemplateRequest struct {
+	Name          *string                     `json:"name" binding:"omitempty,min=1"`
+	Description   *string                     `json:"description"`
+	Title         *string                     `json:"title" binding:"omitempty,min=1"`
+	TaxRate       *float64                    `json:"tax_rate"`
+	TaxCode       *string                     `json:"tax_code"`
+	MarkupPercent *float64                    `json:"markup_percent" binding:"omitempty,gte=0"`
+	ValidDays     *int                        `json:"valid_days" binding:"omitempty,gte=0"`
+	Notes         *string                     `json:"notes"`
+	Terms         *string                     `json:"terms"`
+	IsActive      *bool                       `json:"is_active"`
+	Parameters    *[]QuoteTemplateParameter   `json:"parameters" binding:"omitempty,dive"`
+	Items         *[]QuoteTemplateItemRequest `json:"items" binding:"omitempty,min=1,dive"`
+}
+
+type CreateQuoteFromTemplateRequest struct {
+	ClientID   uint               `json:"client_id" binding:"required"`
+	ProjectID  *uint              `json:"project_id"`
+	Title      string             `json:"title"` // Por defecto el título de la plantilla
+	ValidUntil *time.Time         `json:"valid_until"`
+	Parameters map[string]float64 `json:"parameters"`
+}
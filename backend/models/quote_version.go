@@ -0,0 +1,113 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// QuoteVersion es una revisión inmutable de una cotización (encabezado e items)
+type QuoteVersion struct {
+	ID          uint          `json:"id" gorm:"primaryKey"`
+	QuoteID     uint          `json:"quote_id" gorm:"not null;uniqueIndex:idx_quote_revision"`
+	Revision    int           `json:"revision" gorm:"not null;uniqueIndex:idx_quote_revision"`
+	Label       string        `json:"label" gorm:"not null"` // COT-202610-0004 rev 3
+	Note        string        `json:"note"`
+	Snapshot    QuoteSnapshot `json:"snapshot" gorm:"type:jsonb;not null"`
+	CreatedByID *uint         `json:"created_by_id"`
+	CreatedBy   *User         `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
+	CreatedAt   time.Time     `json:"created_at"`
+}
+
+// QuoteSnapshot es el contenido de una cotización en una revisión
+type QuoteSnapshot struct {
+	Title             string              `json:"title"`
+	Description       string              `json:"description"`
+	ValidUntil        *time.Time          `json:"valid_until"`
+	Currency          string              `json:"currency,omitempty"`
+	TaxRate           float64             `json:"tax_rate"`
+	TaxCode           string              `json:"tax_code,omitempty"`
+	DiscountType      string              `json:"discount_type,omitempty"`
+	DiscountPercent   float64             `json:"discount_percent,omitempty"`
+	Discount          Money               `json:"discount"`
+	DiscountFixed     Money               `json:"discount_fixed,omitempty"`
+	MarkupPercent     float64             `json:"markup_percent"`
+	Subtotal          Money               `json:"subtotal"`
+	TaxAmount         Money               `json:"tax_amount"`
+	WithholdingAmount Money               `json:"withholding_amount"`
+	Total             Money               `json:"total"`
+	CostTotal         Money               `json:"cost_total"`
+	Notes             string              `json:"notes"`
+	Terms             string              `json:"terms"`
+	Items             []QuoteSnapshotItem `json:"items"`
+}
+
+type QuoteSnapshotItem struct {
+	MaterialID      *uint   `json:"material_id,omitempty"`
+	WorkType        string  `json:"work_type,omitempty"`
+	Description     string  `json:"description"`
+	Quantity        float64 `json:"quantity"`
+	Unit            string  `json:"unit"`
+	UnitCost        Money   `json:"unit_cost"`
+	MarkupPercent   float64 `json:"markup_percent"`
+	UnitPrice       Money   `json:"unit_price"`
+	CostTotal       Money   `json:"cost_total"`
+	DiscountType    string  `json:"discount_type,omitempty"`
+	DiscountPercent float64 `json:"discount_percent,omitempty"`
+	Discount        Money   `json:"discount,omitempty"`
+	DiscountFixed   Money   `json:"discount_fixed,omitempty"`
+	Total           Money   `json:"total"`
+	TaxCode         string  `json:"tax_code,omitempty"`
+	Notes           string  `json:"notes"`
+}
+
+// Value guarda la instantánea como JSON
+func (s QuoteSnapshot) Value() (driver.Value, error) {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan lee la instantánea desde una columna JSON
+func (s *QuoteSnapshot) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	case nil:
+		*s = QuoteSnapshot{}
+		return nil
+	}
+	return errors.New("tipo no soportado para QuoteSnapshot")
+}
+
+// QuoteFieldChange describe el cambio de un campo entre dos revisiones
+type QuoteFieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// QuoteItemChange describe el cambio de una línea entre dos revisiones
+type QuoteItemChange struct {
+	Line    int                `json:"line"`
+	Change  string             `json:"change"` // added, removed, modified
+	From    *QuoteSnapshotItem `json:"from,omitempty"`
+	To      *QuoteSnapshotItem `json:"to,omitempty"`
+	Changes []QuoteFieldChange `json:"changes,omitempty"`
+}
+
+type QuoteVersionDiff struct {
+	FromRevision int                `json:"from_revision"`
+	ToRevision   int                `json:"to_revision"`
+	Fields       []QuoteFieldChange `json:"fields"`
+	Items        []QuoteItemChange  `json:"items"`
+}
+
+type RestoreQuoteVersionRequest struct {
+	Note string `json:"note"`
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecurringInvoice es una plantilla de factura que se materializa periódicamente
+// en facturas en borrador (contratos de mantenimiento, igualas, etc.)
+type RecurringInvoice struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	ClientID        uint           `json:"client_id" gorm:"not null"`
+	Client          Client         `json:"client" gorm:"foreignKey:ClientID"`
+	ProjectID       *uint          `json:"project_id"`
+	Project         *Project       `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
+	Title           string         `json:"title" gorm:"not null"`
+	Description     string         `json:"description" gorm:"type:text"`
+	Frequency       string         `json:"frequency" gorm:"not null;default:'monthly'"` // monthly, quarterly, yearly
+	DayOfMonth      int            `json:"day_of_month" gorm:"not null;default:1"`      // 1-31, se ajusta al último día en meses cortos
+	StartDate       time.Time      `json:"start_date" gorm:"not null"`
+	EndDate         *time.Time     `json:"end_date"`
+	NextRunDate     time.Time      `json:"next_run_date" gorm:"not null;index"`
+	LastRunDate     *time.Time     `json:"last_run_date"`
+	PaymentTermDays int            `json:"payment_term_days" gorm:"default:30"`
+	Currency        string         `json:"currency" gorm:"size:3;not null;default:'MXN'"` // Código ISO 4217 de los importes
+	TaxRate         float64        `json:"tax_rate" gorm:"type:decimal(5,2);default:0"`
+	TaxCode         string         `json:"tax_code"`                                      // Código de impuesto de las facturas generadas
+	DiscountType    string         `json:"discount_type" gorm:"not null;default:'fixed'"` // fixed, percent
+	DiscountPercent float64        `json:"discount_percent" gorm:"type:decimal(5,2);default:0"`
+	Discount        Money          `json:"discount" gorm:"type:decimal(15,2);default:0"`
+	Notes           string         `json:"notes" gorm:"type:text"`
+	Terms           string         `json:"terms" gorm:"type:text"`
+	IsActive        bool           `json:"is_active" gorm:"default:true"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relaciones
+	Items []RecurringInvoiceItem `json:"items,omitempty" gorm:"foreignKey:RecurringInvoiceID;constraint:OnDelete:CASCADE"`
+}
+
+type RecurringInvoiceItem struct {
+	ID                 uint      `json:"id" gorm:"primaryKey"`
+	RecurringInvoiceID uint      `json:"recurring_invoice_id" gorm:"not null;index"`
+	Description        string    `json:"description" gorm:"not null"`
+	Quantity           float64   `json:"quantity" gorm:"type:decimal(10,2);not null"`
+	Unit               string    `json:"unit" gorm:"default:'pcs'"`
+	UnitPrice          Money     `json:"unit_price" gorm:"type:decimal(15,2);not null"`
+	DiscountType       string    `json:"discount_type" gorm:"not null;default:'fixed'"` // fixed, percent
+	DiscountPercent    float64   `json:"discount_percent" gorm:"type:decimal(5,2);default:0"`
+	Discount           Money     `json:"discount" gorm:"type:decimal(15,2);default:0"`
+	TaxCode            string    `json:"tax_code"`
+	Notes              string    `json:"notes"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+}
+
+type CreateRecurringInvoiceRequest struct {
+	ClientID        uint                       `json:"client_id" binding:"required"`
+	ProjectID       *uint                      `json:"project_id"`
+	Title           string                     `json:"title" binding:"required"`
+	Description     string                     `json:"description"`
+	Frequency       string                     `json:"frequency" binding:"required,oneof=monthly quarterly yearly"`
+	DayOfMonth      int                        `json:"day_of_month" binding:"required,min=1,max=31"`
+	StartDate       time.Time                  `json:"start_date" binding:"required"`
+	EndDate         *time.Time                 `json:"end_date"`
+	PaymentTermDays *int                       `json:"payment_term_days" binding:"omitempty,min=0"`
+	Currency        string                     `json:"currency" binding:"omitempty,len=3,alpha"` // Por defecto la moneda base
+	TaxRate         float64                    `json:"tax_rate"`
+	TaxCode         string                     `json:"tax_code"`
+	DiscountType    string                     `json:"discount_type" binding:"omitempty,oneof=fixed percent"` // Por defecto fixed
+	DiscountPercent float64                    `json:"discount_percent" binding:"gte=0,lte=100"`
+	Discount        Money                      `json:"discount" binding:"gte=0"`
+	Notes           string                     `json:"notes"`
+	Terms           string                     `json:"terms"`
+	Items           []CreateInvoiceItemRequest `json:"items" binding:"required,min=1,dive"`
+}
+
+type UpdateRecurringInvoiceRequest struct {
+	Title           *string                     `json:"title"`
+	Description     *string                     `json:"description"`
+	Frequency       *string                     `json:"frequency" binding:"omitempty,oneof=monthly quarterly yearly"`
+	DayOfMonth      *int                        `json:"day_of_month" binding:"omitempty,min=1,max=31"`
+	EndDate         *time.Time                  `json:"end_date"`
+	PaymentTermDays *int                        `json:"payment_term_days" binding:"omitempty,min=0"`
+	Currency        *string                     `json:"currency" binding:"omitempty,len=3,alpha"`
+	TaxRate         *float64                    `json:"tax_rate"`
+	TaxCode         *string                     `json:"tax_code"`
+	DiscountType    *string                     `json:"discount_type" binding:"omitempty,oneof=fixed percent"`
+	DiscountPercent *float64                    `json:"discount_percent" binding:"omitempty,gte=0,lte=100"`
+	Discount        *Money                      `json:"discount" binding:"omitempty,gte=0"`
+	Notes           *string                     `json:"notes"`
+	Terms           *string                     `json:"terms"`
+	IsActive        *bool                       `json:"is_active"`
+	Items           *[]CreateInvoiceItemRequest `json:"items" binding:"omitempty,min=1,dive"` // Si se envía, reemplaza los items
+}
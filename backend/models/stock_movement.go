@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// Tipos de movimiento de inventario
+const (
+	StockMovementIn       = "in"       // Entrada (compra o recepción de proveedor)
+	StockMovementOut      = "out"      // Salida que no corresponde a un proyecto
+	StockMovementAdjust   = "adjust"   // Ajuste por conteo físico o conciliación
+	StockMovementTransfer = "transfer" // Salida hacia un proyecto o devolución desde él
+)
+
+// StockMovement registra un cambio en la existencia física de un material. La cantidad
+// lleva signo (positiva si entra, negativa si sale), de modo que la suma de los
+// movimientos de un material es su stock. Las reservas no generan movimientos porque no
+// cambian la existencia
+type StockMovement struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	MaterialID uint      `json:"material_id" gorm:"not null;index"`
+	Material   *Material `json:"material,omitempty" gorm:"foreignKey:MaterialID"`
+	Type       string    `json:"type" gorm:"not null"` // in, out, adjust, transfer
+	Quantity   float64   `json:"quantity" gorm:"type:decimal(10,2);not null"`
+	UnitCost   Money     `json:"unit_cost" gorm:"type:decimal(15,2);not null;default:0"`
+	StockAfter float64   `json:"stock_after" gorm:"type:decimal(10,2);not null"` // Stock del material después del movimiento
+	Reason     string    `json:"reason" gorm:"type:text"`
+	ProjectID  *uint     `json:"project_id" gorm:"index"`
+	Project    *Project  `json:"project,omitempty" gorm:"foreignKey:ProjectID"`
+	UserID     *uint     `json:"user_id"`
+	User       *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type UpdateMaterialStockRequest struct {
+	Quantity float64 `json:"quantity" binding:"required,gt=0"`
+	Type     string  `json:"type" binding:"required,oneof=in out"`
+	UnitCost *Money  `json:"unit_cost" binding:"omitempty,gte=0"` // Por defecto el precio unitario del material
+	Reason   string  `json:"reason"`
+}
@@ -0,0 +1,111 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Tipos de impuesto de un código
+const (
+	TaxTransferred = "transferred" // Impuesto trasladado (IVA): se suma al subtotal
+	TaxWithheld    = "withheld"    // Retención (ISR, IVA retenido): se resta del total
+	TaxExempt      = "exempt"      // Exento: no genera impuesto, solo informa la base
+)
+
+// TaxCode es un código de impuestos configurable que se asigna a los items y documentos,
+// por ejemplo IVA16 o IVA16_RET (IVA 16% con retenciones de ISR e IVA)
+type TaxCode struct {
+	ID         uint          `json:"id" gorm:"primaryKey"`
+	Code       string        `json:"code" gorm:"uniqueIndex;not null"`
+	Name       string        `json:"name" gorm:"not null"`
+	Components TaxComponents `json:"components" gorm:"type:jsonb;not null"`
+	IsActive   bool          `json:"is_active" gorm:"default:true"`
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+}
+
+// TaxComponent es uno de los impuestos que aplica un código
+type TaxComponent struct {
+	Name string  `json:"name" binding:"required"`                                   // IVA, ISR retenido
+	Type string  `json:"type" binding:"required,oneof=transferred withheld exempt"` // transferred, withheld, exempt
+	Rate float64 `json:"rate" binding:"gte=0,lte=100"`                              // Porcentaje
+}
+
+type TaxComponents []TaxComponent
+
+// TaxLine es el desglose de un impuesto en un documento: base gravada e importe
+type TaxLine struct {
+	Name   string  `json:"name"`
+	Type   string  `json:"type"`
+	Rate   float64 `json:"rate"`
+	Base   Money   `json:"base"`
+	Amount Money   `json:"amount"`
+}
+
+type TaxBreakdown []TaxLine
+
+// Value guarda los componentes como JSON
+func (c TaxComponents) Value() (driver.Value, error) {
+	if c == nil {
+		c = TaxComponents{}
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan lee los componentes desde una columna JSON
+func (c *TaxComponents) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, c)
+	case string:
+		return json.Unmarshal([]byte(v), c)
+	case nil:
+		*c = TaxComponents{}
+		return nil
+	}
+	return errors.New("tipo no soportado para TaxComponents")
+}
+
+// Value guarda el desglose como JSON
+func (b TaxBreakdown) Value() (driver.Value, error) {
+	if b == nil {
+		b = TaxBreakdown{}
+	}
+	data, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan lee el desglose desde una columna JSON
+func (b *TaxBreakdown) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, b)
+	case string:
+		return json.Unmarshal([]byte(v), b)
+	case nil:
+		*b = TaxBreakdown{}
+		return nil
+	}
+	return errors.New("tipo no soportado para TaxBreakdown")
+}
+
+type CreateTaxCodeRequest struct {
+	Code       string         `json:"code" binding:"required"`
+	Name       string         `json:"name" binding:"required"`
+	Components []TaxComponent `json:"components" binding:"dive"`
+}
+
+type UpdateTaxCodeRequest struct {
+	Name       *string         `json:"name" binding:"omitempty,min=1"`
+	Components *[]TaxComponent `json:"components" binding:"omitempty,dive"`
+	IsActive   *bool           `json:"is_active"`
+}
@@ -12,10 +12,25 @@ func SetupRoutes(router *gin.Engine) {
 	authController := controllers.NewAuthController()
 	clientController := controllers.NewClientController()
 	projectController := controllers.NewProjectController()
+	projectMaterialController := controllers.NewProjectMaterialController()
 	quoteController := controllers.NewQuoteController()
+	quoteItemController := controllers.NewQuoteItemController()
+	quoteVersionController := controllers.NewQuoteVersionController()
+	quoteApprovalController := controllers.NewQuoteApprovalController()
+	quoteTemplateController := controllers.NewQuoteTemplateController()
+	invoiceController := controllers.NewInvoiceController()
+	paymentController := controllers.NewPaymentController()
+	creditNoteController := controllers.NewCreditNoteController()
+	recurringInvoiceController := controllers.NewRecurringInvoiceController()
 	materialController := controllers.NewMaterialController()
+	laborRateController := controllers.NewLaborRateController()
+	workLogController := controllers.NewWorkLogController()
+	projectExpenseController := controllers.NewProjectExpenseController()
+	taxCodeController := controllers.NewTaxCodeController()
+	exchangeRateController := controllers.NewExchangeRateController()
 	dashboardController := controllers.NewDashboardController()
 	reportController := controllers.NewReportController()
+	documentSequenceController := controllers.NewDocumentSequenceController()
 
 	// Grupo de rutas de la API
 	api := router.Group("/api/v1")
@@ -28,6 +43,15 @@ func SetupRoutes(router *gin.Engine) {
 			auth.POST("/logout", authController.Logout)
 		}
 
+		// Rutas públicas de aprobación de cotizaciones (acceso mediante enlace firmado)
+		public := api.Group("/public")
+		{
+			public.GET("/quotes/:token", quoteApprovalController.GetPublicQuote)
+			public.GET("/quotes/:token/pdf", quoteApprovalController.GetPublicQuotePDF)
+			public.POST("/quotes/:token/accept", quoteApprovalController.AcceptPublicQuote)
+			public.POST("/quotes/:token/reject", quoteApprovalController.RejectPublicQuote)
+		}
+
 		// Rutas protegidas que requieren autenticación
 		protected := api.Group("/")
 		protected.Use(middleware.AuthMiddleware())
@@ -59,7 +83,17 @@ func SetupRoutes(router *gin.Engine) {
 				projects.GET("/stats", projectController.GetProjectStats)
 				projects.GET("/:id", projectController.GetProject)
 				projects.GET("/:id/materials", projectController.GetProjectMaterials)
+				projects.POST("/:id/materials", projectMaterialController.AddProjectMaterial)
+				projects.PUT("/:id/materials/:materialId", projectMaterialController.UpdateProjectMaterial)
+				projects.PATCH("/:id/materials/:materialId/status", projectMaterialController.ChangeProjectMaterialStatus)
+				projects.DELETE("/:id/materials/:materialId", projectMaterialController.DeleteProjectMaterial)
+				projects.GET("/:id/costs", projectController.GetProjectCosts)
+				projects.GET("/:id/expenses", projectExpenseController.GetProjectExpenses)
+				projects.POST("/:id/expenses", projectExpenseController.CreateProjectExpense)
+				projects.PUT("/:id/expenses/:expenseId", projectExpenseController.UpdateProjectExpense)
+				projects.DELETE("/:id/expenses/:expenseId", projectExpenseController.DeleteProjectExpense)
 				projects.POST("", projectController.CreateProject)
+				projects.POST("/:id/duplicate", projectController.DuplicateProject)
 				projects.PUT("/:id", projectController.UpdateProject)
 				projects.DELETE("/:id", projectController.DeleteProject)
 			}
@@ -70,19 +104,70 @@ func SetupRoutes(router *gin.Engine) {
 				quotes.GET("", quoteController.GetQuotes)
 				quotes.GET("/stats", quoteController.GetQuoteStats)
 				quotes.GET("/:id", quoteController.GetQuote)
+				quotes.GET("/:id/pdf", quoteController.GetQuotePDF)
 				quotes.POST("", quoteController.CreateQuote)
+				quotes.POST("/from-template/:id", quoteController.CreateQuoteFromTemplate)
 				quotes.PUT("/:id", quoteController.UpdateQuote)
+				quotes.POST("/:id/duplicate", quoteController.DuplicateQuote)
 				quotes.DELETE("/:id", quoteController.DeleteQuote)
 				quotes.PATCH("/:id/status", quoteController.ChangeQuoteStatus)
+				quotes.POST("/:id/approval-link", quoteApprovalController.CreateApprovalLink)
+				quotes.GET("/:id/approvals", quoteApprovalController.GetQuoteApprovals)
+				quotes.POST("/:id/items", quoteItemController.AddQuoteItem)
+				quotes.POST("/:id/items/reorder", quoteItemController.ReorderQuoteItems)
+				quotes.PUT("/:id/items/:itemId", quoteItemController.UpdateQuoteItem)
+				quotes.DELETE("/:id/items/:itemId", quoteItemController.DeleteQuoteItem)
+				quotes.GET("/:id/versions", quoteVersionController.GetQuoteVersions)
+				quotes.GET("/:id/versions/diff", quoteVersionController.DiffQuoteVersions)
+				quotes.GET("/:id/versions/:revision", quoteVersionController.GetQuoteVersion)
+				quotes.POST("/:id/versions/:revision/restore", quoteVersionController.RestoreQuoteVersion)
+				quotes.POST("/:id/invoice", invoiceController.CreateInvoiceFromQuote)
+			}
+
+			// Rutas de plantillas de cotización
+			quoteTemplates := protected.Group("/quote-templates")
+			{
+				quoteTemplates.GET("", quoteTemplateController.GetQuoteTemplates)
+				quoteTemplates.GET("/:id", quoteTemplateController.GetQuoteTemplate)
+				quoteTemplates.POST("", quoteTemplateController.CreateQuoteTemplate)
+				quoteTemplates.PUT("/:id", quoteTemplateController.UpdateQuoteTemplate)
+				quoteTemplates.DELETE("/:id", quoteTemplateController.DeleteQuoteTemplate)
 			}
 
-			// Rutas de facturas (placeholder para futuras implementaciones)
+			// Rutas de facturas
 			invoices := protected.Group("/invoices")
 			{
-				// TODO: Implementar controlador de facturas
-				invoices.GET("", func(c *gin.Context) {
-					c.JSON(200, gin.H{"message": "Invoices endpoint - Coming soon"})
-				})
+				invoices.GET("", invoiceController.GetInvoices)
+				invoices.GET("/stats", invoiceController.GetInvoiceStats)
+				invoices.GET("/:id", invoiceController.GetInvoice)
+				invoices.GET("/:id/pdf", invoiceController.GetInvoicePDF)
+				invoices.POST("", invoiceController.CreateInvoice)
+				invoices.PUT("/:id", invoiceController.UpdateInvoice)
+				invoices.POST("/:id/duplicate", invoiceController.DuplicateInvoice)
+				invoices.DELETE("/:id", invoiceController.DeleteInvoice)
+				invoices.GET("/:id/payments", paymentController.GetInvoicePayments)
+				invoices.POST("/:id/payments", paymentController.CreatePayment)
+				invoices.POST("/:id/payments/:paymentId/void", paymentController.VoidPayment)
+				invoices.GET("/:id/credit-notes", creditNoteController.GetInvoiceCreditNotes)
+				invoices.POST("/:id/credit-notes", creditNoteController.CreateCreditNote)
+				invoices.POST("/:id/cancel", creditNoteController.CancelInvoice)
+			}
+
+			// Rutas de notas de crédito
+			creditNotes := protected.Group("/credit-notes")
+			{
+				creditNotes.GET("", creditNoteController.GetCreditNotes)
+				creditNotes.GET("/:id", creditNoteController.GetCreditNote)
+			}
+
+			// Rutas de facturas recurrentes
+			recurringInvoices := protected.Group("/recurring-invoices")
+			{
+				recurringInvoices.GET("", recurringInvoiceController.GetRecurringInvoices)
+				recurringInvoices.GET("/:id", recurringInvoiceController.GetRecurringInvoice)
+				recurringInvoices.POST("", recurringInvoiceController.CreateRecurringInvoice)
+				recurringInvoices.PUT("/:id", recurringInvoiceController.UpdateRecurringInvoice)
+				recurringInvoices.DELETE("/:id", recurringInvoiceController.DeleteRecurringInvoice)
 			}
 
 			// Rutas de materiales
@@ -97,6 +182,42 @@ func SetupRoutes(router *gin.Engine) {
 				materials.PUT("/:id", materialController.UpdateMaterial)
 				materials.DELETE("/:id", materialController.DeleteMaterial)
 				materials.PATCH("/:id/stock", materialController.UpdateMaterialStock)
+				materials.GET("/:id/movements", materialController.GetMaterialMovements)
+				materials.POST("/:id/movements/reconcile", materialController.ReconcileMaterialStock)
+			}
+
+			// Rutas de tarifas de mano de obra
+			laborRates := protected.Group("/labor-rates")
+			{
+				laborRates.GET("", laborRateController.GetLaborRates)
+				laborRates.POST("", laborRateController.CreateLaborRate)
+				laborRates.PUT("/:id", laborRateController.UpdateLaborRate)
+				laborRates.DELETE("/:id", laborRateController.DeleteLaborRate)
+			}
+
+			// Rutas de registros de horas
+			workLogs := protected.Group("/worklogs")
+			{
+				workLogs.GET("", workLogController.GetWorkLogs)
+				workLogs.GET("/timesheet", workLogController.GetWeeklyTimesheet)
+				workLogs.GET("/:id", workLogController.GetWorkLog)
+				workLogs.POST("", workLogController.CreateWorkLog)
+				workLogs.PUT("/:id", workLogController.UpdateWorkLog)
+				workLogs.DELETE("/:id", workLogController.DeleteWorkLog)
+			}
+
+			// Códigos de impuesto (la configuración se gestiona en /admin/tax-codes)
+			protected.GET("/tax-codes", taxCodeController.GetTaxCodes)
+
+			// Rutas de tipos de cambio
+			exchangeRates := protected.Group("/exchange-rates")
+			{
+				exchangeRates.GET("", exchangeRateController.GetExchangeRates)
+				exchangeRates.GET("/effective", exchangeRateController.GetEffectiveRate)
+				exchangeRates.POST("", exchangeRateController.CreateExchangeRate)
+				exchangeRates.POST("/import", exchangeRateController.ImportExchangeRates)
+				exchangeRates.PUT("/:id", exchangeRateController.UpdateExchangeRate)
+				exchangeRates.DELETE("/:id", exchangeRateController.DeleteExchangeRate)
 			}
 
 			// Rutas de reportes
@@ -107,6 +228,8 @@ func SetupRoutes(router *gin.Engine) {
 				reports.GET("/quotes", reportController.GetQuotesReport)
 				reports.GET("/materials", reportController.GetMaterialsReport)
 				reports.GET("/financial", reportController.GetFinancialReport)
+				reports.GET("/receivables-aging", reportController.GetReceivablesAging)
+				reports.GET("/taxes", reportController.GetTaxReport)
 			}
 
 			// Rutas de dashboard
@@ -135,6 +258,21 @@ func SetupRoutes(router *gin.Engine) {
 					users.POST("/:id/deactivate", deactivateUser)
 				}
 
+				// Series de numeración de documentos
+				documentSequences := admin.Group("/document-sequences")
+				{
+					documentSequences.GET("", documentSequenceController.GetDocumentSequences)
+					documentSequences.PUT("/:code", documentSequenceController.UpdateDocumentSequence)
+				}
+
+				// Configuración de impuestos
+				taxCodes := admin.Group("/tax-codes")
+				{
+					taxCodes.GET("", taxCodeController.GetTaxCodes)
+					taxCodes.POST("", taxCodeController.CreateTaxCode)
+					taxCodes.PUT("/:id", taxCodeController.UpdateTaxCode)
+				}
+
 				// Configuración del sistema
 				system := admin.Group("/system")
 				{
@@ -161,14 +299,22 @@ func SetupRoutes(router *gin.Engine) {
 			"version":     "1.0.0",
 			"description": "API para el sistema CRM de construcción Raborimet",
 			"endpoints": gin.H{
-				"auth":      "/api/v1/auth",
-				"clients":   "/api/v1/clients",
-				"projects":  "/api/v1/projects",
-				"quotes":    "/api/v1/quotes",
-				"invoices":  "/api/v1/invoices",
-				"materials": "/api/v1/materials",
-				"reports":   "/api/v1/reports",
-				"dashboard": "/api/v1/dashboard",
+				"auth":           "/api/v1/auth",
+				"clients":        "/api/v1/clients",
+				"projects":       "/api/v1/projects",
+				"quotes":         "/api/v1/quotes",
+				"templates":      "/api/v1/quote-templates",
+				"public":         "/api/v1/public",
+				"invoices":       "/api/v1/invoices",
+				"credit_notes":   "/api/v1/credit-notes",
+				"recurring":      "/api/v1/recurring-invoices",
+				"materials":      "/api/v1/materials",
+				"labor_rates":    "/api/v1/labor-rates",
+				"worklogs":       "/api/v1/worklogs",
+				"tax_codes":      "/api/v1/tax-codes",
+				"exchange_rates": "/api/v1/exchange-rates",
+				"reports":        "/api/v1/reports",
+				"dashboard":      "/api/v1/dashboard",
 			},
 		})
 	})
@@ -241,4 +387,4 @@ func getHealthCheck(c *gin.Context) {
 		"database": "connected",
 		"message":  "System health check - Coming soon",
 	})
-}
\ No newline at end of file
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"raborimet-crm/backend/models"
+)
+
+// Formatos de fecha aceptados en la importación de tipos de cambio
+var exchangeRateDateLayouts = []string{"2006-01-02", "02/01/2006"}
+
+type ExchangeRateService struct {
+	baseCurrency string
+}
+
+func NewExchangeRateService() *ExchangeRateService {
+	return &ExchangeRateService{
+		baseCurrency: BaseCurrency(),
+	}
+}
+
+// BaseCurrency devuelve la moneda base de la empresa (BASE_CURRENCY, MXN por defecto),
+// en la que se consolidan los reportes
+func BaseCurrency() string {
+	return strings.ToUpper(getEnv("BASE_CURRENCY", "MXN"))
+}
+
+// NormalizeCurrency pasa el código a mayúsculas; un código vacío es la moneda base
+func NormalizeCurrency(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return BaseCurrency()
+	}
+	return currency
+}
+
+// RateOn devuelve el tipo de cambio de la moneda a la moneda base vigente en la fecha:
+// el último registrado en o antes de ese día. La moneda base siempre vale 1
+func (s *ExchangeRateService) RateOn(db *gorm.DB, currency string, date time.Time) (float64, error) {
+	currency = NormalizeCurrency(currency)
+	if currency == s.baseCurrency {
+		return 1, nil
+	}
+
+	var rate models.ExchangeRate
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+	if err := db.Where("currency = ? AND date <= ?", currency, day).Order("date DESC").First(&rate).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, fmt.Errorf("No hay tipo de cambio de %s al %s", currency, date.Format("2006-01-02"))
+		}
+		return 0, err
+	}
+	return rate.Rate, nil
+}
+
+// NewConverter crea un convertidor a la moneda base que reutiliza los tipos de cambio consultados
+func (s *ExchangeRateService) NewConverter(db *gorm.DB) *CurrencyConverter {
+	return &CurrencyConverter{
+		db:      db,
+		service: s,
+		rates:   map[string]float64{},
+		missing: map[string]bool{},
+	}
+}
+
+// ImportCSV registra los tipos de cambio de un archivo CSV con las columnas fecha, moneda
+// y tipo de cambio (la primera fila puede ser un encabezado). Si ya existe un tipo de
+// cambio para la moneda y la fecha se actualiza. Las filas inválidas se informan y se
+// omiten; el resto se guarda en una sola transacción
+func (s *ExchangeRateService) ImportCSV(db *gorm.DB, file io.Reader) (models.ExchangeRateImportResult, error) {
+	result := models.ExchangeRateImportResult{Errors: []string{}}
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return result, fmt.Errorf("Archivo CSV inválido: %s", err.Error())
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		for i, record := range records {
+			line := i + 1
+			if len(record) < 3 {
+				result.Errors = append(result.Errors, fmt.Sprintf("Línea %d: se esperan fecha, moneda y tipo de cambio", line))
+				continue
+			}
+
+			date, ok := parseExchangeRateDate(record[0])
+			if !ok {
+				// La primera fila puede ser el encabezado
+				if i > 0 {
+					result.Errors = append(result.Errors, fmt.Sprintf("Línea %d: fecha inválida '%s'", line, record[0]))
+				}
+				continue
+			}
+
+			currency := NormalizeCurrency(record[1])
+			if len(currency) != 3 || currency == s.baseCurrency {
+				result.Errors = append(result.Errors, fmt.Sprintf("Línea %d: moneda inválida '%s'", line, record[1]))
+				continue
+			}
+
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[2]), 64)
+			if err != nil || value <= 0 {
+				result.Errors = append(result.Errors, fmt.Sprintf("Línea %d: tipo de cambio inválido '%s'", line, record[2]))
+				continue
+			}
+
+			var rate models.ExchangeRate
+			err = tx.Where("currency = ? AND date = ?", currency, date).First(&rate).Error
+			switch {
+			case err == nil:
+				rate.Rate = value
+				rate.Source = "csv"
+				if err := tx.Save(&rate).Error; err != nil {
+					return err
+				}
+				result.Updated++
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				rate = models.ExchangeRate{Currency: currency, Date: date, Rate: value, Source: "csv"}
+				if err := tx.Create(&rate).Error; err != nil {
+					return err
+				}
+				result.Created++
+			default:
+				return err
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func parseExchangeRateDate(value string) (time.Time, bool) {
+	for _, layout := range exchangeRateDateLayouts {
+		if date, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// CurrencyConverter convierte importes de documentos a la moneda base con el tipo de
+// cambio vigente en la fecha de cada documento
+type CurrencyConverter struct {
+	db      *gorm.DB
+	service *ExchangeRateService
+	rates   map[string]float64
+	missing map[string]bool
+}
+
+// ToBase convierte el importe a la moneda base. Si no hay tipo de cambio para la moneda
+// en esa fecha devuelve false y lo registra en Missing
+func (c *CurrencyConverter) ToBase(amount models.Money, currency string, date time.Time) (models.Money, bool) {
+	currency = NormalizeCurrency(currency)
+	key := currency + "|" + date.Format("2006-01-02")
+
+	if c.missing[key] {
+		return 0, false
+	}
+	rate, ok := c.rates[key]
+	if !ok {
+		var err error
+		rate, err = c.service.RateOn(c.db, currency, date)
+		if err != nil {
+			c.missing[key] = true
+			return 0, false
+		}
+		c.rates[key] = rate
+	}
+	return amount.MulFloat(rate), true
+}
+
+// Missing lista las monedas y fechas sin tipo de cambio ("USD 2026-01-15")
+func (c *CurrencyConverter) Missing() []string {
+	missing := make([]string, 0, len(c.missing))
+	for key := range c.missing {
+		missing = append(missing, strings.Replace(key, "|", " ", 1))
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,360 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Formula es una expresión aritmética ya validada, por ejemplo "ceil(area * 1.1 / 25)".
+// Admite números, parámetros, + - * / ^, paréntesis y las funciones ceil, floor,
+// round(x[, decimales]), abs, min y max
+type Formula struct {
+	source    string
+	eval      func(vars map[string]float64) (float64, error)
+	variables map[string]bool
+}
+
+// ParseFormula analiza una expresión y devuelve un error descriptivo si no es válida
+func ParseFormula(source string) (*Formula, error) {
+	tokens, err := tokenizeFormula(source)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("La fórmula está vacía")
+	}
+
+	p := &formulaParser{tokens: tokens, variables: map[string]bool{}}
+	eval, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Símbolo inesperado %q en la fórmula", p.tokens[p.pos].text)
+	}
+
+	return &Formula{source: source, eval: eval, variables: p.variables}, nil
+}
+
+// Variables devuelve los parámetros usados en la fórmula, ordenados
+func (f *Formula) Variables() []string {
+	names := make([]string, 0, len(f.variables))
+	for name := range f.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Eval calcula la fórmula con los valores de los parámetros
+func (f *Formula) Eval(vars map[string]float64) (float64, error) {
+	value, err := f.eval(vars)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("La fórmula %q no da un resultado válido", f.source)
+	}
+	return value, nil
+}
+
+type formulaTokenKind int
+
+const (
+	tokenNumber formulaTokenKind = iota
+	tokenIdent
+	tokenSymbol
+)
+
+type formulaToken struct {
+	kind  formulaTokenKind
+	text  string
+	value float64
+}
+
+func tokenizeFormula(source string) ([]formulaToken, error) {
+	var tokens []formulaToken
+	runes := []rune(source)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.':
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("Número inválido %q en la fórmula", text)
+			}
+			tokens = append(tokens, formulaToken{kind: tokenNumber, text: text, value: value})
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, formulaToken{kind: tokenIdent, text: string(runes[start:i])})
+		case strings.ContainsRune("+-*/^(),", r):
+			tokens = append(tokens, formulaToken{kind: tokenSymbol, text: string(r)})
+			i++
+		default:
+			return nil, fmt.Errorf("Carácter inválido %q en la fórmula", string(r))
+		}
+	}
+	return tokens, nil
+}
+
+type formulaEval func(vars map[string]float64) (float64, error)
+
+type formulaParser struct {
+	tokens    []formulaToken
+	pos       int
+	variables map[string]bool
+}
+
+func (p *formulaParser) peekSymbol(symbols string) (string, bool) {
+	if p.pos >= len(p.tokens) {
+		return "", false
+	}
+	token := p.tokens[p.pos]
+	if token.kind == tokenSymbol && strings.Contains(symbols, token.text) {
+		return token.text, true
+	}
+	return "", false
+}
+
+func (p *formulaParser) expectSymbol(symbol string) error {
+	if _, ok := p.peekSymbol(symbol); !ok {
+		return fmt.Errorf("Se esperaba %q en la fórmula", symbol)
+	}
+	p.pos++
+	return nil
+}
+
+// expression := term (('+' | '-') term)*
+func (p *formulaParser) parseExpression() (formulaEval, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekSymbol("+-")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryFormula(op, left, right)
+	}
+}
+
+// term := unary (('*' | '/') unary)*
+func (p *formulaParser) parseTerm() (formulaEval, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.peekSymbol("*/")
+		if !ok {
+			return left, nil
+		}
+		p.pos++
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryFormula(op, left, right)
+	}
+}
+
+// unary := ('+' | '-') unary | power
+func (p *formulaParser) parseUnary() (formulaEval, error) {
+	if op, ok := p.peekSymbol("+-"); ok {
+		p.pos++
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return operand, nil
+		}
+		return func(vars map[string]float64) (float64, error) {
+			value, err := operand(vars)
+			return -value, err
+		}, nil
+	}
+	return p.parsePower()
+}
+
+// power := primary ('^' unary)?
+func (p *formulaParser) parsePower() (formulaEval, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.peekSymbol("^"); !ok {
+		return base, nil
+	}
+	p.pos++
+	exponent, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return binaryFormula("^", base, exponent), nil
+}
+
+// primary := número | parámetro | función '(' argumentos ')' | '(' expression ')'
+func (p *formulaParser) parsePrimary() (formulaEval, error) {
+	if p.pos >= len(p.tokens) {
+		return nil, errors.New("La fórmula está incompleta")
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+
+	switch token.kind {
+	case tokenNumber:
+		value := token.value
+		return func(map[string]float64) (float64, error) { return value, nil }, nil
+	case tokenIdent:
+		if _, ok := p.peekSymbol("("); ok {
+			return p.parseCall(token.text)
+		}
+		name := token.text
+		p.variables[name] = true
+		return func(vars map[string]float64) (float64, error) {
+			value, ok := vars[name]
+			if !ok {
+				return 0, fmt.Errorf("Falta el parámetro %s", name)
+			}
+			return value, nil
+		}, nil
+	}
+
+	if token.text == "(" {
+		inner, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectSymbol(")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	}
+	return nil, fmt.Errorf("Símbolo inesperado %q en la fórmula", token.text)
+}
+
+func (p *formulaParser) parseCall(name string) (formulaEval, error) {
+	p.pos++ // (
+	var args []formulaEval
+	if _, ok := p.peekSymbol(")"); !ok {
+		for {
+			arg, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.peekSymbol(","); !ok {
+				break
+			}
+			p.pos++
+		}
+	}
+	if err := p.expectSymbol(")"); err != nil {
+		return nil, err
+	}
+
+	var apply func(values []float64) float64
+	minArgs, maxArgs := 1, 1
+	switch strings.ToLower(name) {
+	case "ceil":
+		apply = func(v []float64) float64 { return math.Ceil(v[0]) }
+	case "floor":
+		apply = func(v []float64) float64 { return math.Floor(v[0]) }
+	case "abs":
+		apply = func(v []float64) float64 { return math.Abs(v[0]) }
+	case "round":
+		maxArgs = 2
+		apply = func(v []float64) float64 {
+			if len(v) == 1 {
+				return math.Round(v[0])
+			}
+			factor := math.Pow(10, math.Round(v[1]))
+			return math.Round(v[0]*factor) / factor
+		}
+	case "min":
+		maxArgs = -1
+		apply = func(v []float64) float64 {
+			result := v[0]
+			for _, value := range v[1:] {
+				result = math.Min(result, value)
+			}
+			return result
+		}
+	case "max":
+		maxArgs = -1
+		apply = func(v []float64) float64 {
+			result := v[0]
+			for _, value := range v[1:] {
+				result = math.Max(result, value)
+			}
+			return result
+		}
+	default:
+		return nil, fmt.Errorf("Función desconocida %s en la fórmula", name)
+	}
+	if len(args) < minArgs || (maxArgs >= 0 && len(args) > maxArgs) {
+		return nil, fmt.Errorf("Cantidad de argumentos inválida para %s", name)
+	}
+
+	return func(vars map[string]float64) (float64, error) {
+		values := make([]float64, len(args))
+		for i, arg := range args {
+			value, err := arg(vars)
+			if err != nil {
+				return 0, err
+			}
+			values[i] = value
+		}
+		return apply(values), nil
+	}, nil
+}
+
+func binaryFormula(op string, left, right formulaEval) formulaEval {
+	return func(vars map[string]float64) (float64, error) {
+		a, err := left(vars)
+		if err != nil {
+			return 0, err
+		}
+		b, err := right(vars)
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case "+":
+			return a + b, nil
+		case "-":
+			return a - b, nil
+		case "*":
+			return a * b, nil
+		case "/":
+			if b == 0 {
+				return 0, errors.New("División por cero en la fórmula")
+			}
+			return a / b, nil
+		default:
+			return math.Pow(a, b), nil
+		}
+	}
+}
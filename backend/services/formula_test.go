@@ -0,0 +1,140 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFormulaEval(t *testing.T) {
+	vars := map[string]float64{"area": 100, "largo": 4, "ancho": 2.5, "piezas_caja": 12}
+	tests := []struct {
+		source string
+		want   float64
+	}{
+		// Precedencia y asociatividad
+		{"1 + 2 * 3", 7},
+		{"(1 + 2) * 3", 9},
+		{"10 - 4 - 3", 3},
+		{"16 / 4 / 2", 2},
+		{"2 * 3 ^ 2", 18},
+		{"2 ^ 3 ^ 2", 512},
+		{"1 + 6 / 3 * 2", 5},
+		// Menos unario
+		{"-3 + 5", 2},
+		{"-2 ^ 2", -4},
+		{"(-2) ^ 2", 4},
+		{"2 ^ -1", 0.5},
+		{"3 * -2", -6},
+		{"--4", 4},
+		{"+4", 4},
+		// Funciones
+		{"ceil(4.1)", 5},
+		{"ceil(-4.1)", -4},
+		{"floor(4.9)", 4},
+		{"floor(-4.1)", -5},
+		{"round(2.5)", 3},
+		{"round(-2.5)", -3},
+		{"round(3.14159, 2)", 3.14},
+		{"round(1234, -2)", 1200},
+		{"abs(-7)", 7},
+		{"min(3, 1, 2)", 1},
+		{"max(3, 1, 2)", 3},
+		{"max(5)", 5},
+		{"CEIL(0.2)", 1},
+		// Parámetros
+		{"ceil(area * 1.1 / 25)", 5},
+		{"largo * ancho", 10},
+		{"ceil(largo * ancho / piezas_caja)", 1},
+		{"max(largo, ancho) - min(largo, ancho)", 1.5},
+	}
+	for _, tt := range tests {
+		formula, err := ParseFormula(tt.source)
+		if err != nil {
+			t.Errorf("ParseFormula(%q) error = %v", tt.source, err)
+			continue
+		}
+		got, err := formula.Eval(vars)
+		if err != nil {
+			t.Errorf("Eval(%q) error = %v", tt.source, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%q) = %v, want %v", tt.source, got, tt.want)
+		}
+	}
+}
+
+func TestParseFormulaErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{"", "vacía"},
+		{"   ", "vacía"},
+		{"1 +", "incompleta"},
+		{"(1 + 2", "Se esperaba"},
+		{"1 + 2)", "Símbolo inesperado"},
+		{"2 3", "Símbolo inesperado"},
+		{"* 2", "Símbolo inesperado"},
+		{"1.2.3", "Número inválido"},
+		{"area % 2", "Carácter inválido"},
+		{"sqrt(4)", "Función desconocida sqrt"},
+		{"ceil()", "Cantidad de argumentos"},
+		{"ceil(1, 2)", "Cantidad de argumentos"},
+		{"round(1, 2, 3)", "Cantidad de argumentos"},
+		{"min()", "Cantidad de argumentos"},
+	}
+	for _, tt := range tests {
+		_, err := ParseFormula(tt.source)
+		if err == nil {
+			t.Errorf("ParseFormula(%q) error = nil, want %q", tt.source, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("ParseFormula(%q) error = %q, want %q", tt.source, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormulaEvalErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		vars    map[string]float64
+		wantErr string
+	}{
+		{"area * 2", nil, "Falta el parámetro area"},
+		{"largo * ancho", map[string]float64{"largo": 2}, "Falta el parámetro ancho"},
+		{"1 / 0", nil, "División por cero"},
+		{"area / (largo - 4)", map[string]float64{"area": 10, "largo": 4}, "División por cero"},
+		{"ceil(1 / area)", map[string]float64{"area": 0}, "División por cero"},
+		{"0 ^ -1", nil, "no da un resultado válido"},
+		{"(-8) ^ 0.5", nil, "no da un resultado válido"},
+	}
+	for _, tt := range tests {
+		formula, err := ParseFormula(tt.source)
+		if err != nil {
+			t.Errorf("ParseFormula(%q) error = %v", tt.source, err)
+			continue
+		}
+		_, err = formula.Eval(tt.vars)
+		if err == nil {
+			t.Errorf("Eval(%q) error = nil, want %q", tt.source, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("Eval(%q) error = %q, want %q", tt.source, err, tt.wantErr)
+		}
+	}
+}
+
+func TestFormulaVariables(t *testing.T) {
+	formula, err := ParseFormula("ceil(largo * ancho / rendimiento) + largo + max(merma, 0)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ancho", "largo", "merma", "rendimiento"}
+	if got := formula.Variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+package services
+
+import (
+	"time"
+
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+)
+
+// invoiceTransitions define los cambios de estado que se pueden hacer a mano. Pagada y
+// vencida se derivan de los pagos y de la fecha de vencimiento, y la cancelación se hace
+// con una nota de crédito, así que a mano solo se emite un borrador
+var invoiceTransitions = map[string][]string{
+	"draft":     {"sent"},
+	"sent":      {},
+	"overdue":   {},
+	"paid":      {},
+	"cancelled": {},
+}
+
+// CanTransitionInvoice indica si una factura puede pasar a mano del estado from al estado to
+func CanTransitionInvoice(from, to string) bool {
+	for _, allowed := range invoiceTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+type InvoiceService struct{}
+
+func NewInvoiceService() *InvoiceService {
+	return &InvoiceService{}
+}
+
+// MarkOverdueInvoices marca como vencidas las facturas enviadas con saldo pendiente
+// cuya fecha de vencimiento ya pasó. Devuelve la cantidad de facturas actualizadas
+func (s *InvoiceService) MarkOverdueInvoices() (int64, error) {
+	result := config.DB.Model(&models.Invoice{}).
+		Where("status = ? AND due_date < ? AND balance > 0", "sent", time.Now()).
+		Update("status", "overdue")
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+// Series de numeración conocidas
+const (
+	SeriesQuote      = "quote"
+	SeriesProject    = "project"
+	SeriesInvoice    = "invoice"
+	SeriesCreditNote = "credit_note"
+)
+
+// seriesDefaults describe la configuración inicial de una serie y dónde viven los
+// documentos que numera (para continuar la numeración existente al crear la serie)
+type seriesDefaults struct {
+	sequence models.DocumentSequence
+	table    string
+	column   string
+}
+
+var defaultSeries = map[string]seriesDefaults{
+	SeriesQuote: {
+		sequence: models.DocumentSequence{Code: SeriesQuote, Name: "Cotizaciones", Prefix: "COT", Format: "{PREFIX}-{YYYY}{MM}-{SEQ}", Padding: 4, ResetPeriod: "monthly"},
+		table:    "quotes",
+		column:   "quote_number",
+	},
+	SeriesProject: {
+		sequence: models.DocumentSequence{Code: SeriesProject, Name: "Proyectos", Prefix: "PRJ", Format: "{PREFIX}-{YYYY}-{SEQ}", Padding: 4, ResetPeriod: "yearly"},
+		table:    "projects",
+		column:   "code",
+	},
+	SeriesInvoice: {
+		sequence: models.DocumentSequence{Code: SeriesInvoice, Name: "Facturas", Prefix: "FAC", Format: "{PREFIX}-{YYYY}{MM}-{SEQ}", Padding: 4, ResetPeriod: "monthly"},
+		table:    "invoices",
+		column:   "invoice_number",
+	},
+	SeriesCreditNote: {
+		sequence: models.DocumentSequence{Code: SeriesCreditNote, Name: "Notas de crédito", Prefix: "NC", Format: "{PREFIX}-{SEQ}", Padding: 6, ResetPeriod: "never"},
+		table:    "credit_notes",
+		column:   "credit_note_number",
+	},
+}
+
+type NumberingService struct{}
+
+func NewNumberingService() *NumberingService {
+	return &NumberingService{}
+}
+
+// Next reserva el siguiente número de la serie dentro de la transacción tx.
+// La fila del contador queda bloqueada hasta que tx termine, así que dos creaciones
+// concurrentes nunca obtienen el mismo número, y si tx se revierte el número no se
+// consume (numeración sin huecos). El documento debe crearse en la misma transacción
+func (s *NumberingService) Next(tx *gorm.DB, code string) (string, error) {
+	defaults, known := defaultSeries[code]
+
+	// Crear la serie con su configuración por defecto si aún no existe
+	if known {
+		if err := createDefaultSeries(tx, defaults); err != nil {
+			return "", err
+		}
+	}
+
+	var sequence models.DocumentSequence
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("code = ?", code).First(&sequence).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", fmt.Errorf("serie de numeración desconocida: %s", code)
+		}
+		return "", err
+	}
+
+	now := time.Now()
+	period := periodKey(sequence.ResetPeriod, now)
+	if sequence.CurrentPeriod != period {
+		sequence.CurrentPeriod = period
+		sequence.LastNumber = 0
+		// Continuar desde el mayor número ya emitido con el mismo formato, para no
+		// chocar con documentos numerados antes de existir la serie
+		if known {
+			sequence.LastNumber = s.highestExisting(tx, &sequence, defaults, now)
+		}
+	}
+
+	sequence.LastNumber++
+	if err := tx.Model(&sequence).Select("current_period", "last_number").Updates(&sequence).Error; err != nil {
+		return "", err
+	}
+
+	return FormatDocumentNumber(&sequence, now, sequence.LastNumber), nil
+}
+
+// EnsureDefaultSeries crea las series conocidas que aún no existen, para que puedan
+// configurarse antes de emitir el primer documento
+func (s *NumberingService) EnsureDefaultSeries(db *gorm.DB) error {
+	for _, defaults := range defaultSeries {
+		if err := createDefaultSeries(db, defaults); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func createDefaultSeries(db *gorm.DB, defaults seriesDefaults) error {
+	initial := defaults.sequence
+	return db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).Create(&initial).Error
+}
+
+// FormatDocumentNumber arma el número de documento según el formato de la serie
+func FormatDocumentNumber(sequence *models.DocumentSequence, date time.Time, number int64) string {
+	padding := sequence.Padding
+	if padding <= 0 {
+		padding = 1
+	}
+
+	replacer := strings.NewReplacer(
+		"{PREFIX}", sequence.Prefix,
+		"{YYYY}", fmt.Sprintf("%04d", date.Year()),
+		"{YY}", fmt.Sprintf("%02d", date.Year()%100),
+		"{MM}", fmt.Sprintf("%02d", int(date.Month())),
+		"{SEQ}", fmt.Sprintf("%0*d", padding, number),
+	)
+	return replacer.Replace(sequence.Format)
+}
+
+// ValidateDocumentFormat verifica que el formato incluya un único correlativo al final
+// (para poder continuar desde los números ya emitidos) y que distinga el periodo de
+// reinicio: si el correlativo vuelve a 1 cada mes o cada año, el número debe incluir ese
+// mes o ese año para no repetir documentos anteriores
+func ValidateDocumentFormat(format, resetPeriod string) error {
+	if strings.Count(format, "{SEQ}") != 1 {
+		return errors.New("el formato debe incluir exactamente un {SEQ}")
+	}
+	if !strings.HasSuffix(format, "{SEQ}") {
+		return errors.New("el formato debe terminar en {SEQ}")
+	}
+
+	hasYear := strings.Contains(format, "{YYYY}") || strings.Contains(format, "{YY}")
+	switch resetPeriod {
+	case "monthly":
+		if !hasYear || !strings.Contains(format, "{MM}") {
+			return errors.New("una serie con reinicio mensual debe incluir {MM} y {YYYY} o {YY} en el formato")
+		}
+	case "yearly":
+		if !hasYear {
+			return errors.New("una serie con reinicio anual debe incluir {YYYY} o {YY} en el formato")
+		}
+	}
+	return nil
+}
+
+// highestExisting busca el mayor correlativo ya usado en la tabla de documentos para el
+// periodo actual. Solo aplica cuando {SEQ} está al final del formato
+func (s *NumberingService) highestExisting(tx *gorm.DB, sequence *models.DocumentSequence, defaults seriesDefaults, now time.Time) int64 {
+	if !strings.HasSuffix(sequence.Format, "{SEQ}") {
+		return 0
+	}
+
+	prefix := FormatDocumentNumber(&models.DocumentSequence{
+		Prefix: sequence.Prefix,
+		Format: strings.TrimSuffix(sequence.Format, "{SEQ}"),
+	}, now, 0)
+
+	var numbers []string
+	tx.Table(defaults.table).Where(defaults.column+" LIKE ?", escapeLike(prefix)+"%").Pluck(defaults.column, &numbers)
+
+	var highest int64
+	for _, number := range numbers {
+		if n, err := strconv.ParseInt(strings.TrimPrefix(number, prefix), 10, 64); err == nil && n > highest {
+			highest = n
+		}
+	}
+	return highest
+}
+
+// periodKey devuelve la clave del periodo de reinicio de la serie
+func periodKey(resetPeriod string, date time.Time) string {
+	switch resetPeriod {
+	case "monthly":
+		return date.Format("200601")
+	case "yearly":
+		return date.Format("2006")
+	default:
+		return "all"
+	}
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -0,0 +1,437 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"raborimet-crm/backend/models"
+)
+
+// CompanyInfo contiene los datos de la empresa que aparecen en el encabezado de los PDF
+type CompanyInfo struct {
+	Name     string
+	TaxID    string
+	Address  string
+	Phone    string
+	Email    string
+	LogoPath string
+}
+
+// LoadCompanyInfo lee los datos de la empresa desde las variables de entorno COMPANY_*
+func LoadCompanyInfo() CompanyInfo {
+	return CompanyInfo{
+		Name:     getEnv("COMPANY_NAME", getEnv("APP_NAME", "Raborimet")),
+		TaxID:    os.Getenv("COMPANY_TAX_ID"),
+		Address:  os.Getenv("COMPANY_ADDRESS"),
+		Phone:    os.Getenv("COMPANY_PHONE"),
+		Email:    os.Getenv("COMPANY_EMAIL"),
+		LogoPath: os.Getenv("COMPANY_LOGO_PATH"),
+	}
+}
+
+type PDFService struct {
+	company CompanyInfo
+}
+
+func NewPDFService() *PDFService {
+	return &PDFService{
+		company: LoadCompanyInfo(),
+	}
+}
+
+// pdfLine es una fila de la tabla de conceptos
+type pdfLine struct {
+	Description string
+	Quantity    float64
+	Unit        string
+	UnitPrice   models.Money
+	Total       models.Money
+}
+
+// pdfField es un par etiqueta/valor (datos del documento o filas de totales)
+type pdfField struct {
+	Label string
+	Value string
+}
+
+// pdfDocument es la representación común de una cotización o factura para imprimir
+type pdfDocument struct {
+	Title       string
+	Number      string
+	Subject     string
+	Description string
+	Client      models.Client
+	Details     []pdfField
+	Lines       []pdfLine
+	Totals      []pdfField
+	Notes       string
+	Terms       string
+}
+
+// RenderQuote genera el PDF de una cotización (con cliente e items cargados)
+func (s *PDFService) RenderQuote(quote *models.Quote) ([]byte, error) {
+	doc := pdfDocument{
+		Title:       "COTIZACIÓN",
+		Number:      quote.QuoteNumber,
+		Subject:     quote.Title,
+		Description: quote.Description,
+		Client:      quote.Client,
+		Details: []pdfField{
+			{Label: "Fecha", Value: formatDate(quote.CreatedAt)},
+			{Label: "Moneda", Value: quote.Currency},
+		},
+		Notes: quote.Notes,
+		Terms: quote.Terms,
+	}
+	if quote.ValidUntil != nil {
+		doc.Details = append(doc.Details, pdfField{Label: "Válida hasta", Value: formatDate(*quote.ValidUntil)})
+	}
+
+	for _, item := range quote.Items {
+		doc.Lines = append(doc.Lines, pdfLine{
+			Description: lineDescription(item.Description, item.DiscountType, item.DiscountPercent, item.Discount),
+			Quantity:    item.Quantity,
+			Unit:        item.Unit,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+
+	doc.Totals = documentTotals(quote.Subtotal, discountLabel(quote.DiscountType, quote.DiscountPercent), quote.Discount, quote.TaxRate, quote.TaxAmount, quote.TaxBreakdown, quote.Total)
+
+	return s.render(&doc)
+}
+
+// RenderInvoice genera el PDF de una factura (con cliente e items cargados)
+func (s *PDFService) RenderInvoice(invoice *models.Invoice) ([]byte, error) {
+	doc := pdfDocument{
+		Title:       "FACTURA",
+		Number:      invoice.InvoiceNumber,
+		Subject:     invoice.Title,
+		Description: invoice.Description,
+		Client:      invoice.Client,
+		Details: []pdfField{
+			{Label: "Fecha de emisión", Value: formatDate(invoice.IssueDate)},
+			{Label: "Fecha de vencimiento", Value: formatDate(invoice.DueDate)},
+			{Label: "Moneda", Value: invoice.Currency},
+		},
+		Notes: invoice.Notes,
+		Terms: invoice.Terms,
+	}
+	if invoice.Status == "cancelled" {
+		doc.Details = append(doc.Details, pdfField{Label: "Estado", Value: "CANCELADA"})
+	}
+
+	for _, item := range invoice.Items {
+		doc.Lines = append(doc.Lines, pdfLine{
+			Description: lineDescription(item.Description, item.DiscountType, item.DiscountPercent, item.Discount),
+			Quantity:    item.Quantity,
+			Unit:        item.Unit,
+			UnitPrice:   item.UnitPrice,
+			Total:       item.Total,
+		})
+	}
+
+	doc.Totals = documentTotals(invoice.Subtotal, discountLabel(invoice.DiscountType, invoice.DiscountPercent), invoice.Discount, invoice.TaxRate, invoice.TaxAmount, invoice.TaxBreakdown, invoice.Total)
+	if invoice.CreditedAmount != 0 {
+		doc.Totals = append(doc.Totals, pdfField{Label: "Notas de crédito", Value: "-" + formatMoney(invoice.CreditedAmount)})
+	}
+	if invoice.PaidAmount != 0 {
+		doc.Totals = append(doc.Totals, pdfField{Label: "Pagado", Value: "-" + formatMoney(invoice.PaidAmount)})
+	}
+	if invoice.CreditedAmount != 0 || invoice.PaidAmount != 0 {
+		doc.Totals = append(doc.Totals, pdfField{Label: "Saldo", Value: formatMoney(invoice.Balance)})
+	}
+
+	return s.render(&doc)
+}
+
+// render dibuja el documento: encabezado de la empresa, datos del cliente, conceptos,
+// totales, notas y términos
+func (s *PDFService) render(doc *pdfDocument) ([]byte, error) {
+	pdf := fpdf.New("P", "mm", "Letter", "")
+	pdf.SetMargins(15, 15, 15)
+	pdf.SetAutoPageBreak(true, 20)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-15)
+		pdf.SetFont("Helvetica", "I", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 10, tr(fmt.Sprintf("%s %s - Página %d", doc.Title, doc.Number, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pageWidth, _ := pdf.GetPageSize()
+	left, _, right, _ := pdf.GetMargins()
+	contentWidth := pageWidth - left - right
+
+	// Encabezado de la empresa
+	headerX := left
+	if s.hasLogo() {
+		pdf.ImageOptions(s.company.LogoPath, left, 15, 30, 0, false, fpdf.ImageOptions{ReadDpi: true}, 0, "")
+		headerX = left + 35
+	}
+	pdf.SetXY(headerX, 15)
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(100, 7, tr(s.company.Name), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range s.companyLines() {
+		pdf.CellFormat(100, 4.5, tr(line), "", 2, "L", false, 0, "")
+	}
+
+	// Título y número del documento
+	pdf.SetXY(pageWidth-right-70, 15)
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(70, 8, tr(doc.Title), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", 10)
+	pdf.CellFormat(70, 6, tr(doc.Number), "", 2, "R", false, 0, "")
+	for _, detail := range doc.Details {
+		pdf.CellFormat(70, 5, tr(detail.Label+": "+detail.Value), "", 2, "R", false, 0, "")
+	}
+
+	pdf.SetY(52)
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.Line(left, pdf.GetY(), pageWidth-right, pdf.GetY())
+	pdf.Ln(4)
+
+	// Datos del cliente
+	pdf.SetFont("Helvetica", "B", 10)
+	pdf.CellFormat(contentWidth, 6, tr("Cliente"), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", 9)
+	for _, line := range clientLines(doc.Client) {
+		pdf.CellFormat(contentWidth, 4.5, tr(line), "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(4)
+
+	// Asunto del documento
+	if doc.Subject != "" {
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.MultiCell(contentWidth, 6, tr(doc.Subject), "", "L", false)
+	}
+	if doc.Description != "" {
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(contentWidth, 4.5, tr(doc.Description), "", "L", false)
+	}
+	pdf.Ln(3)
+
+	// Tabla de conceptos
+	widths := []float64{contentWidth - 110, 20, 20, 35, 35}
+	headers := []string{"Descripción", "Cantidad", "Unidad", "Precio unitario", "Importe"}
+	aligns := []string{"L", "R", "C", "R", "R"}
+
+	drawHeader := func() {
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(235, 235, 235)
+		for i, header := range headers {
+			pdf.CellFormat(widths[i], 7, tr(header), "1", 0, aligns[i], true, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+	drawHeader()
+
+	pdf.SetFont("Helvetica", "", 9)
+	_, pageHeight := pdf.GetPageSize()
+	for _, line := range doc.Lines {
+		descLines := pdf.SplitLines([]byte(tr(line.Description)), widths[0]-2)
+		rowHeight := float64(len(descLines)) * 5
+		if rowHeight < 6 {
+			rowHeight = 6
+		}
+		if pdf.GetY()+rowHeight > pageHeight-25 {
+			pdf.AddPage()
+			drawHeader()
+			pdf.SetFont("Helvetica", "", 9)
+		}
+
+		x, y := pdf.GetXY()
+		pdf.MultiCell(widths[0], 5, tr(line.Description), "", "L", false)
+		pdf.Rect(x, y, widths[0], rowHeight, "D")
+		pdf.SetXY(x+widths[0], y)
+		values := []string{formatQuantity(line.Quantity), line.Unit, formatMoney(line.UnitPrice), formatMoney(line.Total)}
+		for i, value := range values {
+			pdf.CellFormat(widths[i+1], rowHeight, tr(value), "1", 0, aligns[i+1], false, 0, "")
+		}
+		pdf.SetXY(x, y+rowHeight)
+	}
+	pdf.Ln(4)
+
+	// Totales
+	if pdf.GetY()+float64(len(doc.Totals))*6 > pageHeight-25 {
+		pdf.AddPage()
+	}
+	for i, total := range doc.Totals {
+		style := ""
+		if total.Label == "Total" || total.Label == "Saldo" {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, 10)
+		pdf.SetX(pageWidth - right - 90)
+		pdf.CellFormat(55, 6, tr(total.Label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(35, 6, tr(total.Value), "", 1, "R", false, 0, "")
+		if i == len(doc.Totals)-1 {
+			pdf.Ln(4)
+		}
+	}
+
+	// Notas y términos
+	for _, section := range []pdfField{{Label: "Notas", Value: doc.Notes}, {Label: "Términos y condiciones", Value: doc.Terms}} {
+		if strings.TrimSpace(section.Value) == "" {
+			continue
+		}
+		pdf.SetFont("Helvetica", "B", 10)
+		pdf.CellFormat(contentWidth, 6, tr(section.Label), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 9)
+		pdf.MultiCell(contentWidth, 4.5, tr(section.Value), "", "L", false)
+		pdf.Ln(3)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// hasLogo indica si el logo configurado existe y es de un formato soportado
+func (s *PDFService) hasLogo() bool {
+	if s.company.LogoPath == "" {
+		return false
+	}
+	switch strings.ToLower(filepath.Ext(s.company.LogoPath)) {
+	case ".png", ".jpg", ".jpeg", ".gif":
+	default:
+		return false
+	}
+	info, err := os.Stat(s.company.LogoPath)
+	return err == nil && !info.IsDir()
+}
+
+func (s *PDFService) companyLines() []string {
+	var lines []string
+	if s.company.TaxID != "" {
+		lines = append(lines, "RFC: "+s.company.TaxID)
+	}
+	if s.company.Address != "" {
+		lines = append(lines, s.company.Address)
+	}
+	contact := strings.TrimSpace(strings.Join(nonEmpty(s.company.Phone, s.company.Email), " | "))
+	if contact != "" {
+		lines = append(lines, contact)
+	}
+	return lines
+}
+
+func clientLines(client models.Client) []string {
+	lines := []string{client.Name}
+	if client.Company != "" && client.Company != client.Name {
+		lines = append(lines, client.Company)
+	}
+	if client.TaxID != "" {
+		lines = append(lines, "RFC: "+client.TaxID)
+	}
+	address := strings.Join(nonEmpty(client.Address, client.City, client.State, client.ZipCode), ", ")
+	if address != "" {
+		lines = append(lines, address)
+	}
+	contact := strings.Join(nonEmpty(client.Phone, client.Email), " | ")
+	if contact != "" {
+		lines = append(lines, contact)
+	}
+	return lines
+}
+
+// documentTotals arma las filas de totales comunes a cotizaciones y facturas. El descuento
+// va antes de los impuestos, que se calculan sobre la base descontada. Con desglose de
+// impuestos se muestra una fila por impuesto (las retenciones en negativo); los documentos
+// sin desglose muestran la tasa única
+func documentTotals(subtotal models.Money, discountLabel string, discount models.Money, taxRate float64, taxAmount models.Money, breakdown models.TaxBreakdown, total models.Money) []pdfField {
+	totals := []pdfField{{Label: "Subtotal", Value: formatMoney(subtotal)}}
+	if discount != 0 {
+		totals = append(totals, pdfField{Label: discountLabel, Value: "-" + formatMoney(discount)})
+	}
+	if len(breakdown) == 0 {
+		totals = append(totals, pdfField{Label: fmt.Sprintf("Impuesto (%s%%)", formatQuantity(taxRate)), Value: formatMoney(taxAmount)})
+	}
+	for _, line := range breakdown {
+		switch line.Type {
+		case models.TaxTransferred:
+			totals = append(totals, pdfField{Label: TaxLineLabel(line), Value: formatMoney(line.Amount)})
+		case models.TaxWithheld:
+			totals = append(totals, pdfField{Label: TaxLineLabel(line), Value: "-" + formatMoney(line.Amount)})
+		}
+	}
+	totals = append(totals, pdfField{Label: "Total", Value: formatMoney(total)})
+	return totals
+}
+
+// discountLabel arma la etiqueta del descuento del documento: "Descuento (10%)"
+func discountLabel(discountType string, percent float64) string {
+	if discountType == models.DiscountTypePercent {
+		return fmt.Sprintf("Descuento (%s%%)", formatQuantity(percent))
+	}
+	return "Descuento"
+}
+
+// lineDescription agrega a la descripción el descuento de la línea, ya restado del importe
+func lineDescription(description, discountType string, percent float64, discount models.Money) string {
+	if discount == 0 {
+		return description
+	}
+	if discountType == models.DiscountTypePercent {
+		return fmt.Sprintf("%s (descuento %s%%)", description, formatQuantity(percent))
+	}
+	return fmt.Sprintf("%s (descuento %s)", description, formatMoney(discount))
+}
+
+// formatMoney formatea un importe con separador de miles: $1,234.56
+func formatMoney(amount models.Money) string {
+	text := amount.String()
+	sign := ""
+	if strings.HasPrefix(text, "-") {
+		sign = "-"
+		text = text[1:]
+	}
+	integer, decimals, _ := strings.Cut(text, ".")
+	var grouped strings.Builder
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			grouped.WriteByte(',')
+		}
+		grouped.WriteRune(digit)
+	}
+	return sign + "$" + grouped.String() + "." + decimals
+}
+
+// formatQuantity muestra cantidades sin ceros decimales innecesarios
+func formatQuantity(quantity float64) string {
+	text := fmt.Sprintf("%.2f", quantity)
+	text = strings.TrimRight(text, "0")
+	return strings.TrimSuffix(text, ".")
+}
+
+func formatDate(date time.Time) string {
+	return date.Format("02/01/2006")
+}
+
+func nonEmpty(values ...string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
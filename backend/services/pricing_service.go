@@ -0,0 +1,217 @@
+package services
+
+import (
+	"gorm.io/gorm"
+	"raborimet-crm/backend/models"
+)
+
+// DocumentDiscount es el descuento general de un documento
+type DocumentDiscount struct {
+	Type    string       // fixed, percent
+	Percent float64      // Con Type percent
+	Amount  models.Money // Con Type fixed
+}
+
+// PricingResult son los importes de un documento
+type PricingResult struct {
+	Subtotal          models.Money // Suma de las líneas, ya netas de su descuento
+	Discount          models.Money // Descuento del documento
+	TaxAmount         models.Money
+	WithholdingAmount models.Money
+	Total             models.Money
+	Breakdown         models.TaxBreakdown
+}
+
+// PricingService concentra el cálculo de importes de cotizaciones, facturas y notas de
+// crédito: descuentos de línea, descuento del documento e impuestos. Los descuentos se
+// aplican antes de impuestos, de modo que cada impuesto se calcula sobre la base ya
+// descontada
+type PricingService struct {
+	taxService *TaxService
+}
+
+func NewPricingService() *PricingService {
+	return &PricingService{
+		taxService: NewTaxService(),
+	}
+}
+
+// NormalizeDiscountType devuelve el modo de descuento; vacío equivale a importe fijo
+func NormalizeDiscountType(discountType string) string {
+	if discountType == models.DiscountTypePercent {
+		return models.DiscountTypePercent
+	}
+	return models.DiscountTypeFixed
+}
+
+// DiscountAmount calcula el descuento sobre un importe: el porcentaje indicado o el
+// importe fijo, sin superar el importe. El importe fijo pedido se guarda aparte del
+// aplicado (DiscountFixed) para no perderlo si el importe baja temporalmente
+func (s *PricingService) DiscountAmount(amount models.Money, discountType string, percent float64, fixed models.Money) models.Money {
+	discount := fixed
+	if NormalizeDiscountType(discountType) == models.DiscountTypePercent {
+		discount = amount.Percent(percent)
+	}
+	if discount > amount {
+		discount = amount
+	}
+	if discount < 0 {
+		discount = 0
+	}
+	return discount
+}
+
+// PriceQuoteItem recalcula el descuento, el importe neto y el costo de una línea de cotización
+func (s *PricingService) PriceQuoteItem(item *models.QuoteItem) {
+	gross := models.LineTotal(item.Quantity, item.UnitPrice)
+	item.DiscountType = NormalizeDiscountType(item.DiscountType)
+	item.Discount = s.DiscountAmount(gross, item.DiscountType, item.DiscountPercent, item.DiscountFixed)
+	item.Total = gross - item.Discount
+	item.CostTotal = models.LineTotal(item.Quantity, item.UnitCost)
+}
+
+// PriceInvoiceItem recalcula el descuento y el importe neto de una línea de factura
+func (s *PricingService) PriceInvoiceItem(item *models.InvoiceItem) {
+	gross := models.LineTotal(item.Quantity, item.UnitPrice)
+	item.DiscountType = NormalizeDiscountType(item.DiscountType)
+	item.Discount = s.DiscountAmount(gross, item.DiscountType, item.DiscountPercent, item.DiscountFixed)
+	item.Total = gross - item.Discount
+}
+
+// CreditedLine calcula el importe neto de devolver parte de una línea de factura: el
+// descuento de la línea se prorratea según la cantidad devuelta
+func (s *PricingService) CreditedLine(item models.InvoiceItem, quantity float64) models.Money {
+	gross := models.LineTotal(quantity, item.UnitPrice)
+	discount := item.Discount.MulRatio(gross, models.LineTotal(item.Quantity, item.UnitPrice))
+	return gross - discount
+}
+
+// CreditTaxes prorratea el desglose de impuestos guardado en la factura para las líneas
+// acreditadas (credited es el importe neto acreditado por item), de modo que la nota de
+// crédito devuelva los impuestos facturados aunque el código haya cambiado después. Cada
+// impuesto se prorratea según lo acreditado de los items cuyo código lo incluye; si ningún
+// código actual lo incluye, según lo acreditado de toda la factura
+func (s *PricingService) CreditTaxes(db *gorm.DB, invoice *models.Invoice, breakdown models.TaxBreakdown, credited map[uint]models.Money) models.TaxBreakdown {
+	codes := map[string]models.TaxComponents{}
+	keys := make([]map[string]bool, len(invoice.Items))
+	for i, item := range invoice.Items {
+		// Un código que ya no existe no aporta a ningún impuesto en particular
+		components, _ := s.taxService.componentsFor(db, codes, item.TaxCode, invoice.TaxCode, invoice.TaxRate)
+		keys[i] = map[string]bool{}
+		for _, component := range components {
+			keys[i][taxKey(component.Type, component.Name, component.Rate)] = true
+		}
+	}
+
+	result := make(models.TaxBreakdown, 0, len(breakdown))
+	for _, line := range breakdown {
+		key := taxKey(line.Type, line.Name, line.Rate)
+		var invoiceBase, creditedBase, allInvoice, allCredited models.Money
+		for i, item := range invoice.Items {
+			allInvoice += item.Total
+			allCredited += credited[item.ID]
+			if keys[i][key] {
+				invoiceBase += item.Total
+				creditedBase += credited[item.ID]
+			}
+		}
+		if invoiceBase == 0 {
+			invoiceBase, creditedBase = allInvoice, allCredited
+		}
+		if creditedBase == 0 {
+			continue
+		}
+
+		line.Base = line.Base.MulRatio(creditedBase, invoiceBase)
+		line.Amount = line.Amount.MulRatio(creditedBase, invoiceBase)
+		result = append(result, line)
+	}
+	return result
+}
+
+// PriceDocument calcula los importes de un documento a partir de sus líneas netas. El
+// descuento del documento se reparte entre las líneas en proporción a su importe (el
+// redondeo se ajusta en la mayor) y los impuestos se calculan sobre las bases descontadas.
+// El total es subtotal - descuento + trasladados - retenciones
+func (s *PricingService) PriceDocument(db *gorm.DB, lines []TaxableLine, discount DocumentDiscount, taxCode string, taxRate float64) (PricingResult, error) {
+	var result PricingResult
+	for _, line := range lines {
+		result.Subtotal += line.Amount
+	}
+	result.Discount = s.DiscountAmount(result.Subtotal, discount.Type, discount.Percent, discount.Amount)
+
+	taxes, err := s.taxService.Calculate(db, allocateDiscount(lines, result.Subtotal, result.Discount), taxCode, taxRate)
+	if err != nil {
+		return result, err
+	}
+	result.TaxAmount = taxes.TaxAmount
+	result.WithholdingAmount = taxes.WithholdingAmount
+	result.Breakdown = taxes.Breakdown
+	result.Total = taxes.Total
+	return result, nil
+}
+
+// allocateDiscount reparte el descuento del documento entre las líneas en proporción a su
+// importe; la diferencia de redondeo se descuenta de la línea mayor
+func allocateDiscount(lines []TaxableLine, subtotal, discount models.Money) []TaxableLine {
+	taxable := make([]TaxableLine, len(lines))
+	copy(taxable, lines)
+	if discount == 0 || len(lines) == 0 {
+		return taxable
+	}
+
+	var allocated models.Money
+	largest := 0
+	for i, line := range lines {
+		share := discount.MulRatio(line.Amount, subtotal)
+		taxable[i].Amount -= share
+		allocated += share
+		if line.Amount > lines[largest].Amount {
+			largest = i
+		}
+	}
+	taxable[largest].Amount -= discount - allocated
+	return taxable
+}
+
+// PriceQuote recalcula subtotal, descuento, impuestos, retenciones, desglose y total de la
+// cotización a partir de sus items (ya valorizados). No guarda los cambios
+func (s *PricingService) PriceQuote(db *gorm.DB, quote *models.Quote, items []models.QuoteItem) error {
+	lines := make([]TaxableLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, TaxableLine{Amount: item.Total, TaxCode: item.TaxCode})
+	}
+	quote.DiscountType = NormalizeDiscountType(quote.DiscountType)
+	result, err := s.PriceDocument(db, lines, DocumentDiscount{Type: quote.DiscountType, Percent: quote.DiscountPercent, Amount: quote.DiscountFixed}, quote.TaxCode, quote.TaxRate)
+	if err != nil {
+		return err
+	}
+	quote.Subtotal = result.Subtotal
+	quote.Discount = result.Discount
+	quote.TaxAmount = result.TaxAmount
+	quote.WithholdingAmount = result.WithholdingAmount
+	quote.TaxBreakdown = result.Breakdown
+	quote.Total = result.Total
+	return nil
+}
+
+// PriceInvoice recalcula subtotal, descuento, impuestos, retenciones, desglose y total de
+// la factura a partir de sus items (ya valorizados). No guarda los cambios
+func (s *PricingService) PriceInvoice(db *gorm.DB, invoice *models.Invoice, items []models.InvoiceItem) error {
+	lines := make([]TaxableLine, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, TaxableLine{Amount: item.Total, TaxCode: item.TaxCode})
+	}
+	invoice.DiscountType = NormalizeDiscountType(invoice.DiscountType)
+	result, err := s.PriceDocument(db, lines, DocumentDiscount{Type: invoice.DiscountType, Percent: invoice.DiscountPercent, Amount: invoice.DiscountFixed}, invoice.TaxCode, invoice.TaxRate)
+	if err != nil {
+		return err
+	}
+	invoice.Subtotal = result.Subtotal
+	invoice.Discount = result.Discount
+	invoice.TaxAmount = result.TaxAmount
+	invoice.WithholdingAmount = result.WithholdingAmount
+	invoice.TaxBreakdown = result.Breakdown
+	invoice.Total = result.Total
+	return nil
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"testing"
+
+	"raborimet-crm/backend/models"
+)
+
+func TestAllocateDiscount(t *testing.T) {
+	tests := []struct {
+		name     string
+		amounts  []models.Money
+		discount models.Money
+		want     []models.Money
+	}{
+		{"proporcional", []models.Money{10000, 20000}, 3000, []models.Money{9000, 18000}},
+		{"redondeo a la línea mayor", []models.Money{100, 100, 100}, 100, []models.Money{66, 67, 67}},
+		{"ajuste negativo en la mayor aunque no sea la primera", []models.Money{100, 200, 100}, 103, []models.Money{74, 149, 74}},
+		{"línea chica sin descuento", []models.Money{1, 1000}, 5, []models.Money{1, 995}},
+		{"sin descuento", []models.Money{1234, 5678}, 0, []models.Money{1234, 5678}},
+		{"descuento total", []models.Money{333, 667}, 1000, []models.Money{0, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]TaxableLine, len(tt.amounts))
+			var subtotal models.Money
+			for i, amount := range tt.amounts {
+				lines[i] = TaxableLine{Amount: amount, TaxCode: "IVA16"}
+				subtotal += amount
+			}
+
+			got := allocateDiscount(lines, subtotal, tt.discount)
+
+			var allocated models.Money
+			for i := range got {
+				if got[i].Amount != tt.want[i] {
+					t.Errorf("line %d = %d, want %d", i, got[i].Amount, tt.want[i])
+				}
+				if got[i].TaxCode != "IVA16" {
+					t.Errorf("line %d lost its tax code", i)
+				}
+				if lines[i].Amount != tt.amounts[i] {
+					t.Errorf("line %d of the input was modified", i)
+				}
+				allocated += lines[i].Amount - got[i].Amount
+			}
+			if allocated != tt.discount {
+				t.Errorf("allocated %d, want %d", allocated, tt.discount)
+			}
+		})
+	}
+}
+
+func TestPriceDocument(t *testing.T) {
+	tests := []struct {
+		name         string
+		amounts      []models.Money
+		discount     DocumentDiscount
+		taxRate      float64
+		wantDiscount models.Money
+		wantTax      models.Money
+		wantTotal    models.Money
+	}{
+		{"sin descuento", []models.Money{60000, 40000}, DocumentDiscount{}, 16, 0, 16000, 116000},
+		{"importe fijo", []models.Money{60000, 40000}, DocumentDiscount{Type: models.DiscountTypeFixed, Amount: 5000}, 16, 5000, 15200, 110200},
+		{"tipo vacío es importe fijo", []models.Money{60000, 40000}, DocumentDiscount{Amount: 5000}, 16, 5000, 15200, 110200},
+		{"importe fijo mayor al subtotal", []models.Money{10000}, DocumentDiscount{Type: models.DiscountTypeFixed, Amount: 15000}, 16, 10000, 0, 0},
+		{"importe fijo negativo", []models.Money{10000}, DocumentDiscount{Type: models.DiscountTypeFixed, Amount: -500}, 16, 0, 1600, 11600},
+		{"porcentaje", []models.Money{33333}, DocumentDiscount{Type: models.DiscountTypePercent, Percent: 10}, 16, 3333, 4800, 34800},
+		{"porcentaje ignora el importe fijo", []models.Money{10000}, DocumentDiscount{Type: models.DiscountTypePercent, Percent: 5, Amount: 9000}, 0, 500, 0, 9500},
+		{"sin tasa", []models.Money{100, 100, 100}, DocumentDiscount{Type: models.DiscountTypeFixed, Amount: 100}, 0, 100, 0, 200},
+	}
+	pricing := NewPricingService()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := make([]TaxableLine, len(tt.amounts))
+			var subtotal models.Money
+			for i, amount := range tt.amounts {
+				lines[i] = TaxableLine{Amount: amount}
+				subtotal += amount
+			}
+
+			// Sin códigos de impuesto se usa la tasa del documento y no se consulta la base
+			result, err := pricing.PriceDocument(nil, lines, tt.discount, "", tt.taxRate)
+			if err != nil {
+				t.Fatalf("PriceDocument error = %v", err)
+			}
+			if result.Subtotal != subtotal {
+				t.Errorf("Subtotal = %d, want %d", result.Subtotal, subtotal)
+			}
+			if result.Discount != tt.wantDiscount {
+				t.Errorf("Discount = %d, want %d", result.Discount, tt.wantDiscount)
+			}
+			if result.TaxAmount != tt.wantTax {
+				t.Errorf("TaxAmount = %d, want %d", result.TaxAmount, tt.wantTax)
+			}
+			if result.Total != tt.wantTotal {
+				t.Errorf("Total = %d, want %d", result.Total, tt.wantTotal)
+			}
+			if tt.taxRate == 0 {
+				if len(result.Breakdown) != 0 {
+					t.Errorf("Breakdown = %v, want empty", result.Breakdown)
+				}
+			} else if len(result.Breakdown) != 1 || result.Breakdown[0].Base != subtotal-tt.wantDiscount {
+				t.Errorf("Breakdown = %v, want a single line with base %d", result.Breakdown, subtotal-tt.wantDiscount)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+// ProjectCosts es el desglose del costo real de un proyecto frente a su presupuesto
+type ProjectCosts struct {
+	ProjectID       uint         `json:"project_id"`
+	Budget          models.Money `json:"budget"`
+	EstimatedCost   models.Money `json:"estimated_cost"`
+	Materials       models.Money `json:"materials"` // Costo total de los materiales usados
+	Labor           models.Money `json:"labor"`     // Registros de horas
+	LaborHours      float64      `json:"labor_hours"`
+	Expenses        models.Money `json:"expenses"` // Otros gastos del proyecto
+	ActualCost      models.Money `json:"actual_cost"`
+	Variance        models.Money `json:"variance"`         // Presupuesto - costo real; negativo si se excede
+	PercentConsumed float64      `json:"percent_consumed"` // Costo real sobre presupuesto; cero si no hay presupuesto
+}
+
+// ProjectCostService calcula el costo real de los proyectos a partir de los materiales
+// usados, las horas registradas y los otros gastos
+type ProjectCostService struct{}
+
+func NewProjectCostService() *ProjectCostService {
+	return &ProjectCostService{}
+}
+
+// Calculate arma el desglose de costos del proyecto con los datos actuales. No guarda nada
+func (s *ProjectCostService) Calculate(db *gorm.DB, project models.Project) (ProjectCosts, error) {
+	costs := ProjectCosts{
+		ProjectID:     project.ID,
+		Budget:        project.Budget,
+		EstimatedCost: project.EstimatedCost,
+	}
+
+	if err := db.Model(&models.ProjectMaterial{}).Where("project_id = ? AND status = ?", project.ID, "used").
+		Select("COALESCE(SUM(total_cost), 0)").Scan(&costs.Materials).Error; err != nil {
+		return costs, err
+	}
+
+	var labor struct {
+		Hours     float64
+		TotalCost models.Money
+	}
+	if err := db.Model(&models.WorkLog{}).Where("project_id = ?", project.ID).
+		Select("COALESCE(SUM(hours), 0) AS hours, COALESCE(SUM(total_cost), 0) AS total_cost").Scan(&labor).Error; err != nil {
+		return costs, err
+	}
+	costs.Labor = labor.TotalCost
+	costs.LaborHours = labor.Hours
+
+	if err := db.Model(&models.ProjectExpense{}).Where("project_id = ?", project.ID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&costs.Expenses).Error; err != nil {
+		return costs, err
+	}
+
+	costs.ActualCost = costs.Materials + costs.Labor + costs.Expenses
+	costs.Variance = costs.Budget - costs.ActualCost
+	if costs.Budget > 0 {
+		costs.PercentConsumed = float64(costs.ActualCost) / float64(costs.Budget) * 100
+	}
+	return costs, nil
+}
+
+// Recalculate actualiza el costo real guardado del proyecto. Bloquea el proyecto en tx para
+// que dos cambios simultáneos no dejen un total desactualizado; debe llamarse dentro de la
+// transacción que modifica materiales, horas o gastos del proyecto
+func (s *ProjectCostService) Recalculate(tx *gorm.DB, projectID uint) error {
+	var project models.Project
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&project, projectID).Error; err != nil {
+		return err
+	}
+	costs, err := s.Calculate(tx, project)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&project).UpdateColumn("actual_cost", costs.ActualCost).Error
+}
+
+// MigrateLegacyCosts reemplaza los costos reales que antes se cargaban a mano por el
+// cálculo a partir de materiales, horas y gastos. El costo cargado de un proyecto sin
+// ninguno de esos registros se conserva como un gasto "Costo histórico" para no perderlo.
+// Debe ejecutarse una sola vez (ver config.RunDataMigration)
+func (s *ProjectCostService) MigrateLegacyCosts(tx *gorm.DB) (int64, error) {
+	if err := tx.Exec(`
+		INSERT INTO project_expenses (project_id, date, category, description, amount, notes, created_at, updated_at)
+		SELECT p.id, COALESCE(p.start_date, p.created_at), ?, ?, p.actual_cost, ?, NOW(), NOW()
+		FROM projects p
+		WHERE p.deleted_at IS NULL AND p.actual_cost <> 0
+		AND NOT EXISTS (SELECT 1 FROM project_materials pm WHERE pm.project_id = p.id AND pm.status = ?)
+		AND NOT EXISTS (SELECT 1 FROM work_logs wl WHERE wl.project_id = p.id AND wl.deleted_at IS NULL)
+		AND NOT EXISTS (SELECT 1 FROM project_expenses pe WHERE pe.project_id = p.id AND pe.deleted_at IS NULL)`,
+		"other", "Costo histórico", "Costo real cargado antes del cálculo automático", "used").Error; err != nil {
+		return 0, err
+	}
+	return s.RecalculateAll(tx)
+}
+
+// RecalculateAll actualiza el costo real guardado de todos los proyectos. Devuelve la
+// cantidad de proyectos cuyo costo cambió
+func (s *ProjectCostService) RecalculateAll(db *gorm.DB) (int64, error) {
+	var projects []models.Project
+	if err := db.Select("id", "actual_cost").Find(&projects).Error; err != nil {
+		return 0, err
+	}
+
+	var changed int64
+	for _, project := range projects {
+		var actualCost models.Money
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := s.Recalculate(tx, project.ID); err != nil {
+				return err
+			}
+			return tx.Model(&models.Project{}).Where("id = ?", project.ID).Select("actual_cost").Scan(&actualCost).Error
+		})
+		if err != nil {
+			return changed, err
+		}
+		if actualCost != project.ActualCost {
+			changed++
+		}
+	}
+	return changed, nil
+}
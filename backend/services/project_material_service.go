@@ -0,0 +1,209 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+// projectMaterialTransitions define el avance de un material del proyecto. El estado solo
+// avanza (planned → ordered → delivered → used); se permite saltar pasos, por ejemplo un
+// material en existencia que se entrega sin pedirse. Mientras no se use puede cancelarse
+var projectMaterialTransitions = map[string][]string{
+	"planned":   {"ordered", "delivered", "used", "cancelled"},
+	"ordered":   {"delivered", "used", "cancelled"},
+	"delivered": {"used", "cancelled"},
+	"used":      {},
+	"cancelled": {},
+}
+
+// ProjectMaterialService gestiona el avance de los materiales de un proyecto y el
+// movimiento de inventario asociado: al pedirse (o entregarse sin pedido) se reserva la
+// cantidad planificada, al usarse se descuenta del stock y al cancelarse se libera la
+// reserva
+type ProjectMaterialService struct {
+	stockService *StockService
+}
+
+func NewProjectMaterialService() *ProjectMaterialService {
+	return &ProjectMaterialService{
+		stockService: NewStockService(),
+	}
+}
+
+// CanTransitionProjectMaterial indica si un material puede pasar del estado from al estado to
+func CanTransitionProjectMaterial(from, to string) bool {
+	for _, allowed := range projectMaterialTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStatus avanza el estado del material y mueve el inventario. Al entregarlo se
+// registra la fecha de entrega (hoy si no se indica) y al usarlo la cantidad usada (la
+// planificada si no se indica ni se había registrado). El material debe estar bloqueado
+// en tx; no guarda el material
+func (s *ProjectMaterialService) ChangeStatus(tx *gorm.DB, material *models.ProjectMaterial, req models.ChangeProjectMaterialStatusRequest, userID *uint) error {
+	if _, ok := projectMaterialTransitions[req.Status]; !ok {
+		return errors.New("Estado inválido")
+	}
+	if material.Status == req.Status {
+		return fmt.Errorf("El material ya está en estado %s", req.Status)
+	}
+	if !CanTransitionProjectMaterial(material.Status, req.Status) {
+		return fmt.Errorf("No se puede cambiar un material de %s a %s", material.Status, req.Status)
+	}
+	if req.QuantityUsed != nil && req.Status != "used" {
+		return errors.New("La cantidad usada solo se indica al marcar el material como usado")
+	}
+
+	switch req.Status {
+	case "ordered", "delivered":
+		if material.QuantityReserved == 0 {
+			if err := s.stockService.Reserve(tx, material.MaterialID, material.QuantityPlanned); err != nil {
+				return err
+			}
+			material.QuantityReserved = material.QuantityPlanned
+		}
+	case "used":
+		if req.QuantityUsed != nil {
+			material.QuantityUsed = *req.QuantityUsed
+		} else if material.QuantityUsed == 0 {
+			material.QuantityUsed = material.QuantityPlanned
+		}
+		if err := s.stockService.Consume(tx, material.MaterialID, material.QuantityUsed, material.QuantityReserved, s.transfer(material, "Material usado en el proyecto", userID)); err != nil {
+			return err
+		}
+		material.QuantityReserved = 0
+	case "cancelled":
+		if err := s.stockService.Release(tx, material.MaterialID, material.QuantityReserved); err != nil {
+			return err
+		}
+		material.QuantityReserved = 0
+	}
+
+	if req.Status == "delivered" || req.Status == "used" {
+		if req.DeliveryDate != nil {
+			material.DeliveryDate = req.DeliveryDate
+		} else if material.DeliveryDate == nil {
+			now := time.Now()
+			material.DeliveryDate = &now
+		}
+	}
+
+	material.Status = req.Status
+	s.PriceProjectMaterial(material)
+	return nil
+}
+
+// ApplyUpdate aplica los cambios de cantidades, precio, fecha de entrega y notas. La
+// cantidad usada solo puede registrarse en materiales entregados o usados; en los usados
+// la diferencia se descuenta o se devuelve al stock. Si el material tiene stock reservado,
+// un cambio en la cantidad planificada ajusta la reserva. El material debe estar bloqueado
+// en tx; no guarda el material
+func (s *ProjectMaterialService) ApplyUpdate(tx *gorm.DB, material *models.ProjectMaterial, req models.UpdateProjectMaterialRequest, userID *uint) error {
+	if req.QuantityUsed != nil {
+		switch material.Status {
+		case "delivered":
+		case "used":
+			if diff := *req.QuantityUsed - material.QuantityUsed; diff > 0 {
+				if err := s.stockService.Consume(tx, material.MaterialID, diff, 0, s.transfer(material, "Corrección de la cantidad usada en el proyecto", userID)); err != nil {
+					return err
+				}
+			} else if diff < 0 {
+				if _, err := s.stockService.Adjust(tx, material.MaterialID, -diff, s.transfer(material, "Devolución del proyecto", userID)); err != nil {
+					return err
+				}
+			}
+		default:
+			return errors.New("Solo se puede registrar la cantidad usada de materiales entregados o usados")
+		}
+		material.QuantityUsed = *req.QuantityUsed
+	}
+	if req.QuantityPlanned != nil {
+		if material.QuantityReserved != 0 {
+			if diff := *req.QuantityPlanned - material.QuantityReserved; diff > 0 {
+				if err := s.stockService.Reserve(tx, material.MaterialID, diff); err != nil {
+					return err
+				}
+			} else if diff < 0 {
+				if err := s.stockService.Release(tx, material.MaterialID, -diff); err != nil {
+					return err
+				}
+			}
+			material.QuantityReserved = *req.QuantityPlanned
+		}
+		material.QuantityPlanned = *req.QuantityPlanned
+	}
+	if req.UnitPrice != nil {
+		material.UnitPrice = *req.UnitPrice
+	}
+	if req.DeliveryDate != nil {
+		material.DeliveryDate = req.DeliveryDate
+	}
+	if req.Notes != nil {
+		material.Notes = *req.Notes
+	}
+	s.PriceProjectMaterial(material)
+	return nil
+}
+
+// ReleaseReservation libera el stock reservado por el material (al quitarlo del proyecto)
+func (s *ProjectMaterialService) ReleaseReservation(tx *gorm.DB, material *models.ProjectMaterial) error {
+	if err := s.stockService.Release(tx, material.MaterialID, material.QuantityReserved); err != nil {
+		return err
+	}
+	material.QuantityReserved = 0
+	return nil
+}
+
+// CancelProjectMaterials cancela los materiales aún no usados de un proyecto y libera su
+// stock reservado (al cancelar o eliminar el proyecto)
+func (s *ProjectMaterialService) CancelProjectMaterials(tx *gorm.DB, projectID uint) error {
+	var materials []models.ProjectMaterial
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("project_id = ? AND status IN ?", projectID, []string{"planned", "ordered", "delivered"}).
+		Find(&materials).Error; err != nil {
+		return err
+	}
+	for i := range materials {
+		if err := s.ReleaseReservation(tx, &materials[i]); err != nil {
+			return err
+		}
+		materials[i].Status = "cancelled"
+		if err := tx.Model(&materials[i]).Select("status", "quantity_reserved").Updates(&materials[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// transfer arma el movimiento de inventario entre el almacén y el proyecto, al precio
+// unitario del material en el proyecto
+func (s *ProjectMaterialService) transfer(material *models.ProjectMaterial, reason string, userID *uint) models.StockMovement {
+	projectID := material.ProjectID
+	return models.StockMovement{
+		Type:      models.StockMovementTransfer,
+		UnitCost:  material.UnitPrice,
+		Reason:    reason,
+		ProjectID: &projectID,
+		UserID:    userID,
+	}
+}
+
+// PriceProjectMaterial recalcula el costo total del material: la cantidad planificada
+// mientras no se usa y la cantidad usada una vez usado, que es lo que suma al costo real
+// del proyecto
+func (s *ProjectMaterialService) PriceProjectMaterial(material *models.ProjectMaterial) {
+	quantity := material.QuantityPlanned
+	if material.Status == "used" {
+		quantity = material.QuantityUsed
+	}
+	material.TotalCost = models.LineTotal(quantity, material.UnitPrice)
+}
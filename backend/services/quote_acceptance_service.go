@@ -0,0 +1,203 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"raborimet-crm/backend/models"
+)
+
+// QuoteAcceptanceOptions define qué acciones se ejecutan al aceptar una cotización
+type QuoteAcceptanceOptions struct {
+	CreateProject bool // Crear el proyecto si la cotización no tiene uno (QUOTE_ACCEPT_CREATE_PROJECT)
+	UpdateBudget  bool // Actualizar presupuesto y costo estimado del proyecto (QUOTE_ACCEPT_UPDATE_BUDGET)
+	StartProject  bool // Pasar el proyecto de planning a in_progress (QUOTE_ACCEPT_START_PROJECT)
+	SeedMaterials bool // Cargar los materiales de la cotización en el proyecto (QUOTE_ACCEPT_SEED_MATERIALS)
+}
+
+// LoadQuoteAcceptanceOptions lee las opciones desde variables de entorno. Todas están
+// activas por defecto
+func LoadQuoteAcceptanceOptions() QuoteAcceptanceOptions {
+	return QuoteAcceptanceOptions{
+		CreateProject: getBoolEnv("QUOTE_ACCEPT_CREATE_PROJECT", true),
+		UpdateBudget:  getBoolEnv("QUOTE_ACCEPT_UPDATE_BUDGET", true),
+		StartProject:  getBoolEnv("QUOTE_ACCEPT_START_PROJECT", true),
+		SeedMaterials: getBoolEnv("QUOTE_ACCEPT_SEED_MATERIALS", true),
+	}
+}
+
+type QuoteAcceptanceService struct {
+	numberingService       *NumberingService
+	exchangeRateService    *ExchangeRateService
+	projectMaterialService *ProjectMaterialService
+	options                QuoteAcceptanceOptions
+}
+
+func NewQuoteAcceptanceService() *QuoteAcceptanceService {
+	return &QuoteAcceptanceService{
+		numberingService:       NewNumberingService(),
+		exchangeRateService:    NewExchangeRateService(),
+		projectMaterialService: NewProjectMaterialService(),
+		options:                LoadQuoteAcceptanceOptions(),
+	}
+}
+
+// Apply ejecuta las acciones configuradas para una cotización recién aceptada, dentro
+// de la misma transacción que cambia su estado. Un proyecto terminado o cancelado no se
+// modifica
+func (s *QuoteAcceptanceService) Apply(tx *gorm.DB, quote *models.Quote) error {
+	var project models.Project
+	switch {
+	case quote.ProjectID != nil:
+		if err := tx.First(&project, *quote.ProjectID).Error; err != nil {
+			return errors.New("Proyecto de la cotización no encontrado")
+		}
+		if project.Status == "completed" || project.Status == "cancelled" {
+			return nil
+		}
+	case s.options.CreateProject:
+		if err := s.createProject(tx, quote, &project); err != nil {
+			return err
+		}
+	default:
+		return nil
+	}
+
+	if s.options.UpdateBudget {
+		if err := s.updateBudget(tx, &project); err != nil {
+			return err
+		}
+	}
+
+	if s.options.StartProject && project.Status == "planning" {
+		project.Status = "in_progress"
+		if project.StartDate == nil {
+			now := time.Now()
+			project.StartDate = &now
+		}
+		if err := tx.Model(&project).Select("status", "start_date").Updates(&project).Error; err != nil {
+			return err
+		}
+	}
+
+	if s.options.SeedMaterials {
+		if err := s.seedMaterials(tx, quote, project.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createProject crea el proyecto de la cotización y lo vincula
+func (s *QuoteAcceptanceService) createProject(tx *gorm.DB, quote *models.Quote, project *models.Project) error {
+	code, err := s.numberingService.Next(tx, SeriesProject)
+	if err != nil {
+		return err
+	}
+
+	*project = models.Project{
+		Code:        code,
+		Name:        quote.Title,
+		Description: quote.Description,
+		ClientID:    quote.ClientID,
+		Status:      "planning",
+		Notes:       fmt.Sprintf("Creado al aceptar la cotización %s", quote.QuoteNumber),
+	}
+	if err := tx.Create(project).Error; err != nil {
+		return err
+	}
+
+	quote.ProjectID = &project.ID
+	return tx.Model(quote).Update("project_id", project.ID).Error
+}
+
+// updateBudget recalcula el presupuesto del proyecto como la suma de sus cotizaciones
+// aceptadas, convertidas a la moneda base con el tipo de cambio de la fecha de cada una.
+// El costo estimado usa el costo de cada cotización cuando se conoce y, si no, su total.
+// Si falta algún tipo de cambio el presupuesto no se modifica y se deja constancia en el log
+func (s *QuoteAcceptanceService) updateBudget(tx *gorm.DB, project *models.Project) error {
+	var quotes []struct {
+		Total     models.Money
+		CostTotal models.Money
+		Currency  string
+		CreatedAt time.Time
+	}
+	if err := tx.Model(&models.Quote{}).
+		Where("project_id = ? AND status = ?", project.ID, "accepted").
+		Select("total, cost_total, currency, created_at").
+		Scan(&quotes).Error; err != nil {
+		return err
+	}
+
+	converter := s.exchangeRateService.NewConverter(tx)
+	var budget, estimatedCost models.Money
+	for _, quote := range quotes {
+		cost := quote.CostTotal
+		if cost <= 0 {
+			cost = quote.Total
+		}
+		total, _ := converter.ToBase(quote.Total, quote.Currency, quote.CreatedAt)
+		cost, _ = converter.ToBase(cost, quote.Currency, quote.CreatedAt)
+		budget += total
+		estimatedCost += cost
+	}
+	if missing := converter.Missing(); len(missing) > 0 {
+		log.Printf("Presupuesto del proyecto %s sin actualizar: no hay tipo de cambio para %s", project.Code, strings.Join(missing, ", "))
+		return nil
+	}
+
+	project.Budget = budget
+	project.EstimatedCost = estimatedCost
+	return tx.Model(project).Select("budget", "estimated_cost").Updates(project).Error
+}
+
+// seedMaterials agrega al proyecto, como planificados, los materiales de los items de
+// la cotización que provienen del catálogo. El costo es el del catálogo, en moneda base,
+// y no el de la cotización, que puede estar en otra moneda. Los materiales eliminados del
+// catálogo después de cotizarse se omiten
+func (s *QuoteAcceptanceService) seedMaterials(tx *gorm.DB, quote *models.Quote, projectID uint) error {
+	var items []models.QuoteItem
+	if err := tx.Where("quote_id = ? AND material_id IS NOT NULL", quote.ID).Order("position ASC, id ASC").Find(&items).Error; err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		var material models.Material
+		if err := tx.First(&material, *item.MaterialID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("Material %d de la cotización %s no cargado en el proyecto: ya no existe", *item.MaterialID, quote.QuoteNumber)
+				continue
+			}
+			return err
+		}
+
+		projectMaterial := models.ProjectMaterial{
+			ProjectID:       projectID,
+			MaterialID:      material.ID,
+			QuantityPlanned: item.Quantity,
+			UnitPrice:       material.UnitPrice,
+			Status:          "planned",
+			Notes:           fmt.Sprintf("Cotización %s: %s", quote.QuoteNumber, item.Description),
+		}
+		s.projectMaterialService.PriceProjectMaterial(&projectMaterial)
+		if err := tx.Create(&projectMaterial).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getBoolEnv lee una variable de entorno booleana; si falta o no es válida devuelve el valor por defecto
+func getBoolEnv(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
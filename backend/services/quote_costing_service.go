@@ -0,0 +1,196 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"raborimet-crm/backend/models"
+)
+
+type QuoteCostingService struct {
+	taxService          *TaxService
+	pricingService      *PricingService
+	exchangeRateService *ExchangeRateService
+}
+
+func NewQuoteCostingService() *QuoteCostingService {
+	return &QuoteCostingService{
+		taxService:          NewTaxService(),
+		pricingService:      NewPricingService(),
+		exchangeRateService: NewExchangeRateService(),
+	}
+}
+
+// BuildItem arma un item de cotización. Si refiere a un material o a un tipo de trabajo,
+// la descripción, la unidad y el costo se toman del catálogo salvo que la solicitud los
+// indique; el costo del catálogo, en moneda base, se convierte a la moneda de la cotización. El precio es el indicado o, si no, el costo más el margen del item (o el de la
+// cotización). El descuento de la línea se resta del importe. Los errores devueltos son de
+// validación
+func (s *QuoteCostingService) BuildItem(tx *gorm.DB, quote *models.Quote, req models.CreateQuoteItemRequest, position int) (models.QuoteItem, error) {
+	item := models.QuoteItem{
+		QuoteID:         quote.ID,
+		Description:     req.Description,
+		Quantity:        req.Quantity,
+		Unit:            req.Unit,
+		DiscountType:    req.DiscountType,
+		DiscountPercent: req.DiscountPercent,
+		DiscountFixed:   req.Discount,
+		TaxCode:         req.TaxCode,
+		Notes:           req.Notes,
+		Position:        position,
+	}
+
+	if err := s.taxService.ValidateCode(tx, req.TaxCode); err != nil {
+		return item, err
+	}
+
+	if req.MaterialID != nil && req.WorkType != "" {
+		return item, errors.New("Un item no puede ser material y mano de obra a la vez")
+	}
+
+	fromCatalog := false
+	var cost models.Money
+	switch {
+	case req.MaterialID != nil:
+		var material models.Material
+		if err := tx.First(&material, *req.MaterialID).Error; err != nil {
+			return item, fmt.Errorf("Material %d no encontrado", *req.MaterialID)
+		}
+		if !material.IsActive {
+			return item, fmt.Errorf("El material %s no está activo", material.Name)
+		}
+		item.MaterialID = &material.ID
+		item.Description = orDefault(item.Description, material.Name)
+		item.Unit = orDefault(item.Unit, material.Unit)
+		cost = material.UnitPrice
+		fromCatalog = true
+	case req.WorkType != "":
+		var rate models.LaborRate
+		if err := tx.Where("work_type = ?", req.WorkType).First(&rate).Error; err != nil {
+			return item, fmt.Errorf("No hay tarifa de mano de obra para el tipo de trabajo %s", req.WorkType)
+		}
+		if !rate.IsActive {
+			return item, fmt.Errorf("La tarifa de mano de obra %s no está activa", rate.Name)
+		}
+		item.WorkType = rate.WorkType
+		item.Description = orDefault(item.Description, rate.Name)
+		item.Unit = orDefault(item.Unit, rate.Unit)
+		cost = rate.HourlyCost
+		fromCatalog = true
+	}
+
+	if fromCatalog && req.UnitCost == nil {
+		converted, err := s.catalogCostInCurrency(tx, quote, cost)
+		if err != nil {
+			return item, err
+		}
+		cost = converted
+	}
+
+	if item.Description == "" {
+		return item, errors.New("La descripción del item es obligatoria")
+	}
+	item.Unit = orDefault(item.Unit, "pcs")
+
+	if req.UnitCost != nil {
+		cost = *req.UnitCost
+	}
+	item.UnitCost = cost
+
+	markup := quote.MarkupPercent
+	if req.MarkupPercent != nil {
+		markup = *req.MarkupPercent
+	}
+
+	switch {
+	case req.UnitPrice != nil:
+		item.UnitPrice = *req.UnitPrice
+		item.MarkupPercent = MarkupFromPrice(cost, item.UnitPrice)
+	case fromCatalog || req.UnitCost != nil:
+		item.MarkupPercent = markup
+		item.UnitPrice = PriceWithMarkup(cost, markup)
+	default:
+		return item, errors.New("Debe indicar el precio unitario o el costo del item")
+	}
+
+	s.pricingService.PriceQuoteItem(&item)
+	return item, nil
+}
+
+// ApplyItemUpdate aplica los cambios de precio de un item: un precio explícito recalcula
+// el margen, un margen nuevo recalcula el precio y un costo nuevo (sin precio ni margen)
+// conserva el precio y recalcula el margen. El importe se recalcula con el descuento vigente
+func (s *QuoteCostingService) ApplyItemUpdate(item *models.QuoteItem, req models.UpdateQuoteItemRequest) {
+	if req.UnitCost != nil {
+		item.UnitCost = *req.UnitCost
+	}
+	if req.TaxCode != nil {
+		item.TaxCode = *req.TaxCode
+	}
+	if req.DiscountType != nil {
+		item.DiscountType = *req.DiscountType
+	}
+	if req.DiscountPercent != nil {
+		item.DiscountPercent = *req.DiscountPercent
+	}
+	if req.Discount != nil {
+		item.DiscountFixed = *req.Discount
+	}
+
+	switch {
+	case req.UnitPrice != nil:
+		item.UnitPrice = *req.UnitPrice
+		item.MarkupPercent = MarkupFromPrice(item.UnitCost, item.UnitPrice)
+	case req.MarkupPercent != nil:
+		item.MarkupPercent = *req.MarkupPercent
+		item.UnitPrice = PriceWithMarkup(item.UnitCost, item.MarkupPercent)
+	case req.UnitCost != nil:
+		item.MarkupPercent = MarkupFromPrice(item.UnitCost, item.UnitPrice)
+	}
+
+	s.pricingService.PriceQuoteItem(item)
+}
+
+// catalogCostInCurrency convierte un costo del catálogo (en moneda base) a la moneda de la
+// cotización con el tipo de cambio vigente en su fecha
+func (s *QuoteCostingService) catalogCostInCurrency(tx *gorm.DB, quote *models.Quote, cost models.Money) (models.Money, error) {
+	date := quote.CreatedAt
+	if date.IsZero() {
+		date = time.Now()
+	}
+	rate, err := s.exchangeRateService.RateOn(tx, quote.Currency, date)
+	if err != nil {
+		return 0, err
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("El tipo de cambio de %s no es válido", NormalizeCurrency(quote.Currency))
+	}
+	if rate == 1 {
+		return cost, nil
+	}
+	return cost.MulFloat(1 / rate), nil
+}
+
+// PriceWithMarkup calcula el precio de venta aplicando el margen (en porcentaje) sobre el costo
+func PriceWithMarkup(cost models.Money, markup float64) models.Money {
+	return cost + cost.Percent(markup)
+}
+
+// MarkupFromPrice calcula el margen sobre el costo que representa un precio de venta.
+// Sin costo conocido el margen es 0
+func MarkupFromPrice(cost, price models.Money) float64 {
+	if cost == 0 {
+		return 0
+	}
+	return math.Round(float64(price-cost)/float64(cost)*10000) / 100
+}
+
+func orDefault(value, fallback string) string {
+	if value == "" {
+		return fallback
+	}
+	return value
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"raborimet-crm/backend/models"
+)
+
+const quoteApprovalPurpose = "quote_approval"
+
+// QuoteApprovalClaims son los datos firmados en el enlace público de una cotización.
+// El enlace queda ligado a la revisión enviada: si la cotización se revisa, deja de valer
+type QuoteApprovalClaims struct {
+	QuoteID  uint   `json:"quote_id"`
+	Revision int    `json:"revision"`
+	Purpose  string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+type QuoteLinkService struct{}
+
+func NewQuoteLinkService() *QuoteLinkService {
+	return &QuoteLinkService{}
+}
+
+// GenerateApprovalToken firma un enlace para la revisión vigente de la cotización. El
+// enlace vence a los ttl o al terminar el día de validez de la cotización, lo que ocurra antes
+func (s *QuoteLinkService) GenerateApprovalToken(quote *models.Quote, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	if quote.ValidUntil != nil {
+		endOfValidity := startOfDay(*quote.ValidUntil).AddDate(0, 0, 1)
+		if endOfValidity.Before(expiresAt) {
+			expiresAt = endOfValidity
+		}
+	}
+	if !expiresAt.After(now) {
+		return "", time.Time{}, errors.New("La cotización ya no está vigente")
+	}
+
+	secret, err := s.secret()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	claims := QuoteApprovalClaims{
+		QuoteID:  quote.ID,
+		Revision: quote.Revision,
+		Purpose:  quoteApprovalPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   quote.QuoteNumber,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(secret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ParseApprovalToken valida la firma, el vencimiento y el propósito del enlace
+func (s *QuoteLinkService) ParseApprovalToken(token string) (*QuoteApprovalClaims, error) {
+	secret, err := s.secret()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &QuoteApprovalClaims{}
+	parsed, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		return secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil || !parsed.Valid {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, errors.New("El enlace ha vencido")
+		}
+		return nil, errors.New("Enlace inválido")
+	}
+	if claims.Purpose != quoteApprovalPurpose || claims.QuoteID == 0 {
+		return nil, errors.New("Enlace inválido")
+	}
+	return claims, nil
+}
+
+// ApprovalURL arma la URL pública que se envía al cliente
+func (s *QuoteLinkService) ApprovalURL(token string) string {
+	base := strings.TrimRight(getEnv("QUOTE_APPROVAL_URL", "http://localhost:4200/cotizaciones/aprobar"), "/")
+	return fmt.Sprintf("%s/%s", base, token)
+}
+
+// DefaultTTL devuelve la vigencia por defecto de los enlaces (QUOTE_LINK_TTL)
+func (s *QuoteLinkService) DefaultTTL() time.Duration {
+	return GetDurationEnv("QUOTE_LINK_TTL", 14*24*time.Hour)
+}
+
+// CheckConfigured indica si hay una clave dedicada para firmar los enlaces. Sin ella no
+// se emiten ni se aceptan enlaces de aprobación
+func (s *QuoteLinkService) CheckConfigured() error {
+	_, err := s.secret()
+	return err
+}
+
+// secret usa una clave distinta de la de sesión (QUOTE_LINK_SECRET) para que un enlace
+// público nunca sirva como token de autenticación (ni al revés). No hay clave por defecto
+func (s *QuoteLinkService) secret() ([]byte, error) {
+	secret := os.Getenv("QUOTE_LINK_SECRET")
+	if secret == "" {
+		return nil, errors.New("Los enlaces de aprobación no están habilitados: falta configurar QUOTE_LINK_SECRET")
+	}
+	if secret == os.Getenv("JWT_SECRET") {
+		return nil, errors.New("Los enlaces de aprobación no están habilitados: QUOTE_LINK_SECRET debe ser distinta de JWT_SECRET")
+	}
+	return []byte(secret), nil
+}
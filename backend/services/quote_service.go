@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+)
+
+// quoteTransitions define los cambios de estado permitidos para una cotización.
+// Una cotización aceptada es definitiva; las enviadas, rechazadas o vencidas pueden
+// volver a borrador para revisarlas y reenviarlas
+var quoteTransitions = map[string][]string{
+	"draft":    {"sent"},
+	"sent":     {"accepted", "rejected", "expired", "draft"},
+	"rejected": {"draft"},
+	"expired":  {"draft"},
+	"accepted": {},
+}
+
+type QuoteService struct {
+	acceptanceService *QuoteAcceptanceService
+}
+
+func NewQuoteService() *QuoteService {
+	return &QuoteService{
+		acceptanceService: NewQuoteAcceptanceService(),
+	}
+}
+
+// IsValidQuoteStatus indica si el estado existe
+func IsValidQuoteStatus(status string) bool {
+	_, ok := quoteTransitions[status]
+	return ok
+}
+
+// CanTransitionQuote indica si una cotización puede pasar del estado from al estado to
+func CanTransitionQuote(from, to string) bool {
+	for _, allowed := range quoteTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangeStatus aplica un cambio de estado validando la transición y registrando la
+// fecha correspondiente; al volver a borrador se limpian las fechas del envío anterior. Al aceptar se ejecutan además las acciones sobre el proyecto
+// (ver QuoteAcceptanceService). El cambio se guarda con tx
+func (s *QuoteService) ChangeStatus(tx *gorm.DB, quote *models.Quote, status, reason string) error {
+	if !IsValidQuoteStatus(status) {
+		return errors.New("Estado inválido")
+	}
+	if quote.Status == status {
+		return fmt.Errorf("La cotización ya está en estado %s", status)
+	}
+	if !CanTransitionQuote(quote.Status, status) {
+		return fmt.Errorf("No se puede cambiar una cotización de %s a %s", quote.Status, status)
+	}
+
+	now := time.Now()
+	switch status {
+	case "draft":
+		quote.SentAt = nil
+		quote.RejectedAt = nil
+		quote.RejectionReason = ""
+		quote.ExpiredAt = nil
+	case "sent":
+		if quote.ValidUntil != nil && quote.ValidUntil.Before(startOfDay(now)) {
+			return errors.New("La fecha de validez ya pasó; actualícela antes de enviar la cotización")
+		}
+		quote.SentAt = &now
+	case "accepted":
+		quote.AcceptedAt = &now
+	case "rejected":
+		if reason == "" {
+			return errors.New("Debe indicar el motivo del rechazo")
+		}
+		quote.RejectedAt = &now
+		quote.RejectionReason = reason
+	case "expired":
+		quote.ExpiredAt = &now
+	}
+	quote.Status = status
+
+	if err := tx.Model(quote).
+		Select("status", "sent_at", "accepted_at", "rejected_at", "rejection_reason", "expired_at").
+		Updates(quote).Error; err != nil {
+		return err
+	}
+
+	if status == "accepted" {
+		return s.acceptanceService.Apply(tx, quote)
+	}
+	return nil
+}
+
+// ExpireQuotes marca como vencidas las cotizaciones enviadas cuya fecha de validez
+// ya pasó (la cotización es válida durante todo el día indicado). Devuelve la cantidad
+// de cotizaciones actualizadas
+func (s *QuoteService) ExpireQuotes(now time.Time) (int64, error) {
+	result := config.DB.Model(&models.Quote{}).
+		Where("status = ? AND valid_until IS NOT NULL AND valid_until < ?", "sent", startOfDay(now)).
+		Updates(map[string]interface{}{"status": "expired", "expired_at": now})
+	return result.RowsAffected, result.Error
+}
+
+func startOfDay(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+}
@@ -0,0 +1,156 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"raborimet-crm/backend/models"
+)
+
+var parameterNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+type QuoteTemplateService struct{}
+
+func NewQuoteTemplateService() *QuoteTemplateService {
+	return &QuoteTemplateService{}
+}
+
+// Validate revisa que los parámetros tengan nombres válidos y únicos, y que cada fórmula
+// de cantidad sea correcta y use solo parámetros declarados
+func (s *QuoteTemplateService) Validate(parameters []models.QuoteTemplateParameter, items []models.QuoteTemplateItemRequest) error {
+	declared := make(map[string]bool, len(parameters))
+	for _, parameter := range parameters {
+		if !parameterNamePattern.MatchString(parameter.Name) {
+			return fmt.Errorf("Nombre de parámetro inválido: %s (use letras, números y _)", parameter.Name)
+		}
+		if declared[parameter.Name] {
+			return fmt.Errorf("El parámetro %s está repetido", parameter.Name)
+		}
+		declared[parameter.Name] = true
+	}
+
+	for i, item := range items {
+		formula, err := ParseFormula(item.QuantityFormula)
+		if err != nil {
+			return fmt.Errorf("Item %d: %s", i+1, err.Error())
+		}
+		for _, name := range formula.Variables() {
+			if !declared[name] {
+				return fmt.Errorf("Item %d: la fórmula usa el parámetro %s, que no está declarado", i+1, name)
+			}
+		}
+
+		fromCatalog := item.MaterialID != nil || item.WorkType != ""
+		if item.MaterialID != nil && item.WorkType != "" {
+			return fmt.Errorf("Item %d: no puede ser material y mano de obra a la vez", i+1)
+		}
+		if !fromCatalog && item.Description == "" {
+			return fmt.Errorf("Item %d: la descripción es obligatoria", i+1)
+		}
+		if !fromCatalog && item.UnitPrice == nil && item.UnitCost == nil {
+			return fmt.Errorf("Item %d: debe indicar el precio unitario o el costo", i+1)
+		}
+	}
+	return nil
+}
+
+// ResolveParameters combina los valores recibidos con los valores por defecto de la
+// plantilla. Rechaza parámetros desconocidos y avisa de los que faltan
+func (s *QuoteTemplateService) ResolveParameters(template *models.QuoteTemplate, values map[string]float64) (map[string]float64, error) {
+	resolved := make(map[string]float64, len(template.Parameters))
+	declared := make(map[string]bool, len(template.Parameters))
+	var missing []string
+
+	for _, parameter := range template.Parameters {
+		declared[parameter.Name] = true
+		if value, ok := values[parameter.Name]; ok {
+			resolved[parameter.Name] = value
+		} else if parameter.Default != nil {
+			resolved[parameter.Name] = *parameter.Default
+		} else {
+			missing = append(missing, parameter.Name)
+		}
+	}
+
+	var unknown []string
+	for name := range values {
+		if !declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("Parámetros desconocidos para la plantilla: %s", strings.Join(unknown, ", "))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Faltan parámetros: %s", strings.Join(missing, ", "))
+	}
+	return resolved, nil
+}
+
+// BuildItemRequests calcula la cantidad de cada item con los parámetros. Las cantidades
+// se redondean a dos decimales; los items que dan cantidad 0 se omiten (items opcionales)
+func (s *QuoteTemplateService) BuildItemRequests(template *models.QuoteTemplate, parameters map[string]float64) ([]models.CreateQuoteItemRequest, error) {
+	requests := make([]models.CreateQuoteItemRequest, 0, len(template.Items))
+	for i, item := range template.Items {
+		formula, err := ParseFormula(item.QuantityFormula)
+		if err != nil {
+			return nil, fmt.Errorf("Item %d: %s", i+1, err.Error())
+		}
+		quantity, err := formula.Eval(parameters)
+		if err != nil {
+			return nil, fmt.Errorf("Item %d: %s", i+1, err.Error())
+		}
+		quantity = math.Round(quantity*100) / 100
+		if quantity < 0 {
+			return nil, fmt.Errorf("Item %d: la cantidad calculada es negativa (%g)", i+1, quantity)
+		}
+		if quantity == 0 {
+			continue
+		}
+
+		requests = append(requests, models.CreateQuoteItemRequest{
+			MaterialID:    item.MaterialID,
+			WorkType:      item.WorkType,
+			Description:   item.Description,
+			Quantity:      quantity,
+			Unit:          item.Unit,
+			UnitCost:      item.UnitCost,
+			MarkupPercent: item.MarkupPercent,
+			UnitPrice:     item.UnitPrice,
+			TaxCode:       item.TaxCode,
+			Notes:         item.Notes,
+		})
+	}
+
+	if len(requests) == 0 {
+		return nil, errors.New("Con estos parámetros la cotización no tendría items")
+	}
+	return requests, nil
+}
+
+// BuildItems convierte los items de la solicitud en items de la plantilla
+func (s *QuoteTemplateService) BuildItems(templateID uint, items []models.QuoteTemplateItemRequest) []models.QuoteTemplateItem {
+	result := make([]models.QuoteTemplateItem, 0, len(items))
+	for i, item := range items {
+		result = append(result, models.QuoteTemplateItem{
+			TemplateID:      templateID,
+			Position:        i + 1,
+			MaterialID:      item.MaterialID,
+			WorkType:        item.WorkType,
+			Description:     item.Description,
+			Unit:            item.Unit,
+			QuantityFormula: strings.TrimSpace(item.QuantityFormula),
+			UnitCost:        item.UnitCost,
+			MarkupPercent:   item.MarkupPercent,
+			UnitPrice:       item.UnitPrice,
+			TaxCode:         item.TaxCode,
+			Notes:           item.Notes,
+		})
+	}
+	return result
+}
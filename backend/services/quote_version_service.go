@@ -0,0 +1,321 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+type QuoteVersionService struct {
+	pricingService *PricingService
+}
+
+func NewQuoteVersionService() *QuoteVersionService {
+	return &QuoteVersionService{
+		pricingService: NewPricingService(),
+	}
+}
+
+// EnsureBaseline guarda la revisión 1 con el contenido actual si la cotización aún no
+// tiene versiones (cotizaciones creadas antes de existir el historial). Debe llamarse
+// antes de modificar la cotización para no perder su contenido original
+func (s *QuoteVersionService) EnsureBaseline(tx *gorm.DB, quoteID uint, userID *uint) error {
+	var count int64
+	if err := tx.Model(&models.QuoteVersion{}).Where("quote_id = ?", quoteID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err := s.Record(tx, quoteID, userID, "Versión inicial")
+	return err
+}
+
+// Record guarda el contenido actual de la cotización como una nueva revisión inmutable,
+// dentro de la transacción tx. Si el contenido no cambió respecto de la última revisión
+// no se crea una nueva y se devuelve nil
+func (s *QuoteVersionService) Record(tx *gorm.DB, quoteID uint, userID *uint, note string) (*models.QuoteVersion, error) {
+	var quote models.Quote
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, id ASC") }).
+		First(&quote, quoteID).Error; err != nil {
+		return nil, err
+	}
+
+	snapshot := BuildQuoteSnapshot(&quote)
+
+	var latest models.QuoteVersion
+	err := tx.Where("quote_id = ?", quoteID).Order("revision DESC").First(&latest).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	revision := 1
+	if err == nil {
+		if sameSnapshot(latest.Snapshot, snapshot) {
+			return nil, nil
+		}
+		revision = latest.Revision + 1
+	}
+
+	version := models.QuoteVersion{
+		QuoteID:     quote.ID,
+		Revision:    revision,
+		Label:       QuoteRevisionLabel(quote.QuoteNumber, revision),
+		Note:        note,
+		Snapshot:    snapshot,
+		CreatedByID: userID,
+	}
+	if err := tx.Create(&version).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&quote).Update("revision", revision).Error; err != nil {
+		return nil, err
+	}
+
+	return &version, nil
+}
+
+// Restore reemplaza el contenido de la cotización por el de una revisión anterior. La
+// cotización ya debe estar en borrador (ver QuoteService.ChangeStatus). La restauración
+// queda registrada como una nueva revisión, con la nota indicada si la hay
+func (s *QuoteVersionService) Restore(tx *gorm.DB, quote *models.Quote, version *models.QuoteVersion, userID *uint, note string) (*models.QuoteVersion, error) {
+	if err := s.EnsureBaseline(tx, quote.ID, userID); err != nil {
+		return nil, err
+	}
+
+	snapshot := version.Snapshot
+	quote.Title = snapshot.Title
+	quote.Description = snapshot.Description
+	quote.ValidUntil = snapshot.ValidUntil
+	if snapshot.Currency != "" {
+		quote.Currency = snapshot.Currency
+	}
+	quote.TaxRate = snapshot.TaxRate
+	quote.TaxCode = snapshot.TaxCode
+	quote.DiscountType = NormalizeDiscountType(snapshot.DiscountType)
+	quote.DiscountPercent = snapshot.DiscountPercent
+	quote.Discount = snapshot.Discount
+	quote.DiscountFixed = snapshotDiscountFixed(snapshot.DiscountType, snapshot.DiscountFixed, snapshot.Discount)
+	quote.MarkupPercent = snapshot.MarkupPercent
+	quote.Notes = snapshot.Notes
+	quote.Terms = snapshot.Terms
+
+	if err := tx.Where("quote_id = ?", quote.ID).Delete(&models.QuoteItem{}).Error; err != nil {
+		return nil, err
+	}
+
+	var costTotal models.Money
+	items := make([]models.QuoteItem, 0, len(snapshot.Items))
+	for i, item := range snapshot.Items {
+		quoteItem := models.QuoteItem{
+			QuoteID:         quote.ID,
+			MaterialID:      item.MaterialID,
+			WorkType:        item.WorkType,
+			Description:     item.Description,
+			Quantity:        item.Quantity,
+			Unit:            item.Unit,
+			UnitCost:        item.UnitCost,
+			MarkupPercent:   item.MarkupPercent,
+			UnitPrice:       item.UnitPrice,
+			CostTotal:       item.CostTotal,
+			DiscountType:    NormalizeDiscountType(item.DiscountType),
+			DiscountPercent: item.DiscountPercent,
+			Discount:        item.Discount,
+			DiscountFixed:   snapshotDiscountFixed(item.DiscountType, item.DiscountFixed, item.Discount),
+			Total:           item.Total,
+			TaxCode:         item.TaxCode,
+			Notes:           item.Notes,
+			Position:        i + 1,
+		}
+		if err := tx.Create(&quoteItem).Error; err != nil {
+			return nil, err
+		}
+		items = append(items, quoteItem)
+		costTotal += item.CostTotal
+	}
+
+	quote.CostTotal = costTotal
+	if err := s.pricingService.PriceQuote(tx, quote, items); err != nil {
+		return nil, err
+	}
+	quote.CalculateMargin()
+
+	if err := tx.Model(quote).
+		Select("title", "description", "valid_until", "currency", "tax_rate", "tax_code", "discount_type", "discount_percent", "discount", "discount_fixed", "markup_percent", "notes", "terms",
+			"subtotal", "tax_amount", "withholding_amount", "tax_breakdown", "total", "cost_total", "gross_margin", "margin_percent").
+		Updates(quote).Error; err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Restaurada desde la rev %d", version.Revision)
+	if note != "" {
+		message += ": " + note
+	}
+	return s.Record(tx, quote.ID, userID, message)
+}
+
+// snapshotDiscountFixed devuelve el descuento fijo pedido de una instantánea. Las anteriores
+// a guardarlo aparte solo tienen el aplicado, que entonces era el mismo
+func snapshotDiscountFixed(discountType string, fixed, applied models.Money) models.Money {
+	if fixed == 0 && NormalizeDiscountType(discountType) == models.DiscountTypeFixed {
+		return applied
+	}
+	return fixed
+}
+
+// BuildQuoteSnapshot arma la instantánea de una cotización con sus items ya cargados
+func BuildQuoteSnapshot(quote *models.Quote) models.QuoteSnapshot {
+	snapshot := models.QuoteSnapshot{
+		Title:             quote.Title,
+		Description:       quote.Description,
+		Currency:          quote.Currency,
+		TaxRate:           quote.TaxRate,
+		TaxCode:           quote.TaxCode,
+		DiscountType:      quote.DiscountType,
+		DiscountPercent:   quote.DiscountPercent,
+		Discount:          quote.Discount,
+		DiscountFixed:     quote.DiscountFixed,
+		MarkupPercent:     quote.MarkupPercent,
+		Subtotal:          quote.Subtotal,
+		TaxAmount:         quote.TaxAmount,
+		WithholdingAmount: quote.WithholdingAmount,
+		Total:             quote.Total,
+		CostTotal:         quote.CostTotal,
+		Notes:             quote.Notes,
+		Terms:             quote.Terms,
+		Items:             make([]models.QuoteSnapshotItem, 0, len(quote.Items)),
+	}
+	if quote.ValidUntil != nil {
+		validUntil := quote.ValidUntil.UTC()
+		snapshot.ValidUntil = &validUntil
+	}
+	for _, item := range quote.Items {
+		snapshot.Items = append(snapshot.Items, models.QuoteSnapshotItem{
+			MaterialID:      item.MaterialID,
+			WorkType:        item.WorkType,
+			Description:     item.Description,
+			Quantity:        item.Quantity,
+			Unit:            item.Unit,
+			UnitCost:        item.UnitCost,
+			MarkupPercent:   item.MarkupPercent,
+			UnitPrice:       item.UnitPrice,
+			CostTotal:       item.CostTotal,
+			DiscountType:    item.DiscountType,
+			DiscountPercent: item.DiscountPercent,
+			Discount:        item.Discount,
+			DiscountFixed:   item.DiscountFixed,
+			Total:           item.Total,
+			TaxCode:         item.TaxCode,
+			Notes:           item.Notes,
+		})
+	}
+	return snapshot
+}
+
+// DiffQuoteSnapshots compara dos revisiones campo por campo y línea por línea
+func DiffQuoteSnapshots(fromRevision int, from models.QuoteSnapshot, toRevision int, to models.QuoteSnapshot) models.QuoteVersionDiff {
+	diff := models.QuoteVersionDiff{
+		FromRevision: fromRevision,
+		ToRevision:   toRevision,
+		Fields:       []models.QuoteFieldChange{},
+		Items:        []models.QuoteItemChange{},
+	}
+
+	fromDate, toDate := "", ""
+	if from.ValidUntil != nil {
+		fromDate = from.ValidUntil.Format("2006-01-02")
+	}
+	if to.ValidUntil != nil {
+		toDate = to.ValidUntil.Format("2006-01-02")
+	}
+
+	diff.Fields = appendChanges(diff.Fields,
+		fieldPair{"title", from.Title, to.Title},
+		fieldPair{"description", from.Description, to.Description},
+		fieldPair{"valid_until", fromDate, toDate},
+		fieldPair{"currency", from.Currency, to.Currency},
+		fieldPair{"tax_rate", from.TaxRate, to.TaxRate},
+		fieldPair{"tax_code", from.TaxCode, to.TaxCode},
+		fieldPair{"discount_type", NormalizeDiscountType(from.DiscountType), NormalizeDiscountType(to.DiscountType)},
+		fieldPair{"discount_percent", from.DiscountPercent, to.DiscountPercent},
+		fieldPair{"discount", from.Discount, to.Discount},
+		fieldPair{"markup_percent", from.MarkupPercent, to.MarkupPercent},
+		fieldPair{"subtotal", from.Subtotal, to.Subtotal},
+		fieldPair{"tax_amount", from.TaxAmount, to.TaxAmount},
+		fieldPair{"withholding_amount", from.WithholdingAmount, to.WithholdingAmount},
+		fieldPair{"total", from.Total, to.Total},
+		fieldPair{"cost_total", from.CostTotal, to.CostTotal},
+		fieldPair{"notes", from.Notes, to.Notes},
+		fieldPair{"terms", from.Terms, to.Terms},
+	)
+
+	// Las líneas se comparan por posición
+	lines := len(from.Items)
+	if len(to.Items) > lines {
+		lines = len(to.Items)
+	}
+	for i := 0; i < lines; i++ {
+		switch {
+		case i >= len(from.Items):
+			item := to.Items[i]
+			diff.Items = append(diff.Items, models.QuoteItemChange{Line: i + 1, Change: "added", To: &item})
+		case i >= len(to.Items):
+			item := from.Items[i]
+			diff.Items = append(diff.Items, models.QuoteItemChange{Line: i + 1, Change: "removed", From: &item})
+		default:
+			fromItem, toItem := from.Items[i], to.Items[i]
+			changes := appendChanges(nil,
+				fieldPair{"description", fromItem.Description, toItem.Description},
+				fieldPair{"quantity", fromItem.Quantity, toItem.Quantity},
+				fieldPair{"unit", fromItem.Unit, toItem.Unit},
+				fieldPair{"unit_cost", fromItem.UnitCost, toItem.UnitCost},
+				fieldPair{"markup_percent", fromItem.MarkupPercent, toItem.MarkupPercent},
+				fieldPair{"unit_price", fromItem.UnitPrice, toItem.UnitPrice},
+				fieldPair{"discount_type", NormalizeDiscountType(fromItem.DiscountType), NormalizeDiscountType(toItem.DiscountType)},
+				fieldPair{"discount_percent", fromItem.DiscountPercent, toItem.DiscountPercent},
+				fieldPair{"discount", fromItem.Discount, toItem.Discount},
+				fieldPair{"total", fromItem.Total, toItem.Total},
+				fieldPair{"tax_code", fromItem.TaxCode, toItem.TaxCode},
+				fieldPair{"notes", fromItem.Notes, toItem.Notes},
+			)
+			if len(changes) > 0 {
+				diff.Items = append(diff.Items, models.QuoteItemChange{Line: i + 1, Change: "modified", From: &fromItem, To: &toItem, Changes: changes})
+			}
+		}
+	}
+
+	return diff
+}
+
+// QuoteRevisionLabel arma la etiqueta visible de una revisión: COT-202610-0004 rev 3
+func QuoteRevisionLabel(quoteNumber string, revision int) string {
+	return fmt.Sprintf("%s rev %d", quoteNumber, revision)
+}
+
+type fieldPair struct {
+	field string
+	from  interface{}
+	to    interface{}
+}
+
+func appendChanges(changes []models.QuoteFieldChange, pairs ...fieldPair) []models.QuoteFieldChange {
+	for _, pair := range pairs {
+		if pair.from != pair.to {
+			changes = append(changes, models.QuoteFieldChange{Field: pair.field, From: pair.from, To: pair.to})
+		}
+	}
+	return changes
+}
+
+func sameSnapshot(a, b models.QuoteSnapshot) bool {
+	first, errA := json.Marshal(a)
+	second, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(first, second)
+}
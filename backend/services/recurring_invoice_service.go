@@ -0,0 +1,214 @@
+package services
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/config"
+	"raborimet-crm/backend/models"
+)
+
+type RecurringInvoiceService struct {
+	numberingService *NumberingService
+	pricingService   *PricingService
+}
+
+func NewRecurringInvoiceService() *RecurringInvoiceService {
+	return &RecurringInvoiceService{
+		numberingService: NewNumberingService(),
+		pricingService:   NewPricingService(),
+	}
+}
+
+// GenerateDueInvoices materializa en facturas en borrador todos los periodos vencidos
+// de las facturas recurrentes activas. Es idempotente: cada periodo se genera una sola
+// vez aunque el proceso se reinicie o haya varias instancias corriendo
+func (s *RecurringInvoiceService) GenerateDueInvoices(now time.Time) (int, error) {
+	var ids []uint
+	if err := config.DB.Model(&models.RecurringInvoice{}).
+		Where("is_active = ? AND next_run_date <= ?", true, now).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	generated := 0
+	for _, id := range ids {
+		count, err := s.generateForTemplate(id, now)
+		if err != nil {
+			log.Printf("Error al generar facturas de la recurrencia %d: %v", id, err)
+			continue
+		}
+		generated += count
+	}
+	return generated, nil
+}
+
+// generateForTemplate genera, dentro de una transacción con la plantilla bloqueada,
+// las facturas de todos los periodos pendientes hasta la fecha indicada
+func (s *RecurringInvoiceService) generateForTemplate(id uint, now time.Time) (int, error) {
+	generated := 0
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var template models.RecurringInvoice
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Items").First(&template, id).Error; err != nil {
+			return err
+		}
+
+		for template.IsActive && !template.NextRunDate.After(now) {
+			if template.EndDate != nil && template.NextRunDate.After(*template.EndDate) {
+				template.IsActive = false
+				break
+			}
+
+			period := template.NextRunDate.Format("2006-01")
+
+			// La plantilla está bloqueada, pero se verifica igualmente por si el periodo
+			// ya se generó antes (el índice único es la última barrera)
+			var existing int64
+			if err := tx.Unscoped().Model(&models.Invoice{}).Where("recurring_invoice_id = ? AND recurrence_period = ?", template.ID, period).Count(&existing).Error; err != nil {
+				return err
+			}
+			if existing == 0 {
+				invoice, err := s.buildInvoice(tx, &template, period)
+				if err != nil {
+					return err
+				}
+				number, err := s.numberingService.Next(tx, SeriesInvoice)
+				if err != nil {
+					return err
+				}
+				invoice.InvoiceNumber = number
+				if err := tx.Create(&invoice).Error; err != nil {
+					return err
+				}
+				generated++
+			}
+
+			runDate := template.NextRunDate
+			template.LastRunDate = &runDate
+			template.NextRunDate = NextRecurrenceDate(template.NextRunDate, template.Frequency, template.DayOfMonth)
+		}
+
+		if template.EndDate != nil && template.NextRunDate.After(*template.EndDate) {
+			template.IsActive = false
+		}
+
+		return tx.Model(&template).Select("next_run_date", "last_run_date", "is_active").Updates(&template).Error
+	})
+
+	return generated, err
+}
+
+// buildInvoice arma la factura en borrador correspondiente a un periodo de la plantilla
+func (s *RecurringInvoiceService) buildInvoice(tx *gorm.DB, template *models.RecurringInvoice, period string) (models.Invoice, error) {
+	items := make([]models.InvoiceItem, 0, len(template.Items))
+	for _, item := range template.Items {
+		invoiceItem := models.InvoiceItem{
+			Description:     item.Description,
+			Quantity:        item.Quantity,
+			Unit:            item.Unit,
+			UnitPrice:       item.UnitPrice,
+			DiscountType:    item.DiscountType,
+			DiscountPercent: item.DiscountPercent,
+			DiscountFixed:   item.Discount,
+			TaxCode:         item.TaxCode,
+			Notes:           item.Notes,
+		}
+		s.pricingService.PriceInvoiceItem(&invoiceItem)
+		items = append(items, invoiceItem)
+	}
+
+	templateID := template.ID
+	issueDate := template.NextRunDate
+
+	invoice := models.Invoice{
+		ClientID:           template.ClientID,
+		ProjectID:          template.ProjectID,
+		RecurringInvoiceID: &templateID,
+		RecurrencePeriod:   period,
+		Title:              template.Title + " - " + period,
+		Description:        template.Description,
+		Status:             "draft",
+		IssueDate:          issueDate,
+		DueDate:            issueDate.AddDate(0, 0, template.PaymentTermDays),
+		Currency:           template.Currency,
+		TaxRate:            template.TaxRate,
+		TaxCode:            template.TaxCode,
+		DiscountType:       template.DiscountType,
+		DiscountPercent:    template.DiscountPercent,
+		DiscountFixed:      template.Discount,
+		Notes:              template.Notes,
+		Terms:              template.Terms,
+		Items:              items,
+	}
+	if err := s.pricingService.PriceInvoice(tx, &invoice, items); err != nil {
+		return invoice, err
+	}
+	invoice.Balance = invoice.Total
+	return invoice, nil
+}
+
+// FirstRecurrenceDate calcula la primera fecha de emisión en o después de la fecha de inicio
+func FirstRecurrenceDate(start time.Time, dayOfMonth int) time.Time {
+	candidate := dateWithDay(start.Year(), start.Month(), dayOfMonth, start.Location())
+	startDay := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	if candidate.Before(startDay) {
+		next := time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+		candidate = dateWithDay(next.Year(), next.Month(), dayOfMonth, start.Location())
+	}
+	return candidate
+}
+
+// NextRecurrenceDate calcula la siguiente fecha de emisión según la frecuencia
+func NextRecurrenceDate(current time.Time, frequency string, dayOfMonth int) time.Time {
+	months := 1
+	switch frequency {
+	case "quarterly":
+		months = 3
+	case "yearly":
+		months = 12
+	}
+
+	next := time.Date(current.Year(), current.Month()+time.Month(months), 1, 0, 0, 0, 0, current.Location())
+	return dateWithDay(next.Year(), next.Month(), dayOfMonth, current.Location())
+}
+
+// RescheduleRecurrence recalcula la próxima emisión de una plantilla cuya frecuencia o día
+// de emisión cambió: el día nuevo en el mes que ya estaba programado, pero nunca antes de
+// que se cumpla el periodo de la última factura emitida con la frecuencia nueva
+func RescheduleRecurrence(template *models.RecurringInvoice) time.Time {
+	scheduled := template.NextRunDate
+	next := FirstRecurrenceDate(time.Date(scheduled.Year(), scheduled.Month(), 1, 0, 0, 0, 0, scheduled.Location()), template.DayOfMonth)
+	if template.LastRunDate != nil {
+		if byFrequency := NextRecurrenceDate(*template.LastRunDate, template.Frequency, template.DayOfMonth); byFrequency.After(next) {
+			next = byFrequency
+		}
+	}
+	return next
+}
+
+// ValidateRecurrence verifica los parámetros de recurrencia
+func ValidateRecurrence(frequency string, dayOfMonth int, start time.Time, end *time.Time) error {
+	if frequency != "monthly" && frequency != "quarterly" && frequency != "yearly" {
+		return errors.New("frecuencia inválida: use monthly, quarterly o yearly")
+	}
+	if dayOfMonth < 1 || dayOfMonth > 31 {
+		return errors.New("el día del mes debe estar entre 1 y 31")
+	}
+	if end != nil && end.Before(start) {
+		return errors.New("la fecha de fin no puede ser anterior a la fecha de inicio")
+	}
+	return nil
+}
+
+// dateWithDay arma una fecha con el día indicado, ajustado al último día del mes si no existe
+func dateWithDay(year int, month time.Month, day int, loc *time.Location) time.Time {
+	lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
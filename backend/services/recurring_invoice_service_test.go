@@ -0,0 +1,105 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"raborimet-crm/backend/models"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestDateWithDay(t *testing.T) {
+	tests := []struct {
+		year  int
+		month time.Month
+		day   int
+		want  time.Time
+	}{
+		{2025, time.January, 15, date(2025, time.January, 15)},
+		{2025, time.January, 31, date(2025, time.January, 31)},
+		{2025, time.February, 31, date(2025, time.February, 28)},
+		{2024, time.February, 31, date(2024, time.February, 29)},
+		{2024, time.February, 29, date(2024, time.February, 29)},
+		{2025, time.April, 31, date(2025, time.April, 30)},
+		{2025, time.December, 31, date(2025, time.December, 31)},
+	}
+	for _, tt := range tests {
+		if got := dateWithDay(tt.year, tt.month, tt.day, time.UTC); !got.Equal(tt.want) {
+			t.Errorf("dateWithDay(%d, %s, %d) = %s, want %s", tt.year, tt.month, tt.day, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestNextRecurrenceDate(t *testing.T) {
+	tests := []struct {
+		current    time.Time
+		frequency  string
+		dayOfMonth int
+		want       time.Time
+	}{
+		{date(2025, time.January, 15), "monthly", 15, date(2025, time.February, 15)},
+		{date(2025, time.January, 31), "monthly", 31, date(2025, time.February, 28)},
+		// Tras un mes corto se vuelve al día configurado
+		{date(2025, time.February, 28), "monthly", 31, date(2025, time.March, 31)},
+		{date(2024, time.January, 30), "monthly", 30, date(2024, time.February, 29)},
+		{date(2025, time.December, 10), "monthly", 10, date(2026, time.January, 10)},
+		{date(2025, time.November, 30), "quarterly", 30, date(2026, time.February, 28)},
+		{date(2025, time.January, 31), "quarterly", 31, date(2025, time.April, 30)},
+		{date(2024, time.February, 29), "yearly", 29, date(2025, time.February, 28)},
+		{date(2025, time.March, 1), "yearly", 1, date(2026, time.March, 1)},
+		// Una frecuencia desconocida se trata como mensual
+		{date(2025, time.May, 5), "", 5, date(2025, time.June, 5)},
+	}
+	for _, tt := range tests {
+		got := NextRecurrenceDate(tt.current, tt.frequency, tt.dayOfMonth)
+		if !got.Equal(tt.want) {
+			t.Errorf("NextRecurrenceDate(%s, %q, %d) = %s, want %s", tt.current.Format("2006-01-02"), tt.frequency, tt.dayOfMonth, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestFirstRecurrenceDate(t *testing.T) {
+	tests := []struct {
+		start      time.Time
+		dayOfMonth int
+		want       time.Time
+	}{
+		{date(2025, time.January, 10), 15, date(2025, time.January, 15)},
+		{date(2025, time.January, 15), 15, date(2025, time.January, 15)},
+		{date(2025, time.January, 20), 15, date(2025, time.February, 15)},
+		{date(2025, time.January, 31).Add(9 * time.Hour), 31, date(2025, time.January, 31)},
+		{date(2025, time.February, 10), 31, date(2025, time.February, 28)},
+		{date(2025, time.December, 20), 5, date(2026, time.January, 5)},
+	}
+	for _, tt := range tests {
+		if got := FirstRecurrenceDate(tt.start, tt.dayOfMonth); !got.Equal(tt.want) {
+			t.Errorf("FirstRecurrenceDate(%s, %d) = %s, want %s", tt.start.Format(time.RFC3339), tt.dayOfMonth, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
+
+func TestRescheduleRecurrence(t *testing.T) {
+	lastRun := date(2025, time.February, 15)
+	tests := []struct {
+		name     string
+		template models.RecurringInvoice
+		want     time.Time
+	}{
+		{"cambio de día sin emisiones", models.RecurringInvoice{Frequency: "monthly", DayOfMonth: 10, NextRunDate: date(2025, time.March, 15)}, date(2025, time.March, 10)},
+		{"cambio de día con emisiones", models.RecurringInvoice{Frequency: "monthly", DayOfMonth: 5, NextRunDate: date(2025, time.March, 15), LastRunDate: &lastRun}, date(2025, time.March, 5)},
+		{"día que no existe en el mes", models.RecurringInvoice{Frequency: "monthly", DayOfMonth: 31, NextRunDate: date(2025, time.April, 15), LastRunDate: &lastRun}, date(2025, time.April, 30)},
+		{"de mensual a trimestral", models.RecurringInvoice{Frequency: "quarterly", DayOfMonth: 15, NextRunDate: date(2025, time.March, 15), LastRunDate: &lastRun}, date(2025, time.May, 15)},
+		{"de mensual a anual", models.RecurringInvoice{Frequency: "yearly", DayOfMonth: 15, NextRunDate: date(2025, time.March, 15), LastRunDate: &lastRun}, date(2026, time.February, 15)},
+		{"de trimestral a mensual", models.RecurringInvoice{Frequency: "monthly", DayOfMonth: 15, NextRunDate: date(2025, time.May, 15), LastRunDate: &lastRun}, date(2025, time.May, 15)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RescheduleRecurrence(&tt.template); !got.Equal(tt.want) {
+				t.Errorf("RescheduleRecurrence = %s, want %s", got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+			}
+		})
+	}
+}
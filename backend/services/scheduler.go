@@ -0,0 +1,90 @@
+package services
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Job representa una tarea periódica ejecutada por el Scheduler
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() error
+}
+
+// Scheduler ejecuta tareas periódicas en segundo plano dentro del proceso del backend
+type Scheduler struct {
+	jobs []Job
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		stop: make(chan struct{}),
+	}
+}
+
+// AddJob registra una tarea. Debe llamarse antes de Start
+func (s *Scheduler) AddJob(name string, interval time.Duration, run func() error) {
+	s.jobs = append(s.jobs, Job{Name: name, Interval: interval, Run: run})
+}
+
+// Start lanza cada tarea en su propia goroutine. Cada tarea se ejecuta una vez al
+// iniciar y luego en cada intervalo
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		s.wg.Add(1)
+		go s.runJob(job)
+		log.Printf("Tarea programada '%s' iniciada (cada %s)", job.Name, job.Interval)
+	}
+}
+
+// Stop detiene todas las tareas y espera a que terminen las ejecuciones en curso
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+func (s *Scheduler) runJob(job Job) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(job.Interval)
+	defer ticker.Stop()
+
+	s.execute(job)
+	for {
+		select {
+		case <-ticker.C:
+			s.execute(job)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) execute(job Job) {
+	// Un panic en una tarea no debe tumbar el servidor
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Tarea programada '%s' falló: %v", job.Name, r)
+		}
+	}()
+
+	if err := job.Run(); err != nil {
+		log.Printf("Error en tarea programada '%s': %v", job.Name, err)
+	}
+}
+
+// GetDurationEnv obtiene un intervalo desde una variable de entorno (formato 30m, 1h, etc.)
+func GetDurationEnv(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if duration, err := time.ParseDuration(value); err == nil && duration > 0 {
+			return duration
+		}
+		log.Printf("Valor inválido para %s: %q, usando %s", key, value, defaultValue)
+	}
+	return defaultValue
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"math"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+// stockTolerance absorbe diferencias de redondeo en cantidades con dos decimales
+const stockTolerance = 0.001
+
+// StockService mueve el inventario de los materiales. Cada operación bloquea la fila del
+// material (SELECT ... FOR UPDATE) dentro de la transacción recibida, de modo que dos
+// obras no puedan reservar o consumir el mismo stock a la vez. Todo cambio de la
+// existencia queda registrado como StockMovement; el movimiento recibido indica tipo,
+// motivo, proyecto, usuario y costo unitario (por defecto el precio del material)
+type StockService struct{}
+
+func NewStockService() *StockService {
+	return &StockService{}
+}
+
+// Reserve aparta cantidad del stock disponible del material
+func (s *StockService) Reserve(tx *gorm.DB, materialID uint, quantity float64) error {
+	material, err := s.lock(tx, materialID)
+	if err != nil {
+		return err
+	}
+	if available := material.Stock - material.ReservedStock; quantity > available+stockTolerance {
+		return fmt.Errorf("Stock insuficiente de %s: disponible %.2f %s, se requieren %.2f", material.Name, available, material.Unit, quantity)
+	}
+	material.ReservedStock = roundQuantity(material.ReservedStock + quantity)
+	return tx.Model(&material).Select("reserved_stock").Updates(&material).Error
+}
+
+// Release libera cantidad reservada del material
+func (s *StockService) Release(tx *gorm.DB, materialID uint, quantity float64) error {
+	if quantity == 0 {
+		return nil
+	}
+	material, err := s.lock(tx, materialID)
+	if err != nil {
+		return err
+	}
+	material.ReservedStock = math.Max(0, roundQuantity(material.ReservedStock-quantity))
+	return tx.Model(&material).Select("reserved_stock").Updates(&material).Error
+}
+
+// Consume descuenta del stock la cantidad usada y libera la reserva que la cubría. Si se
+// usa más de lo reservado, el excedente debe estar disponible
+func (s *StockService) Consume(tx *gorm.DB, materialID uint, quantity, reserved float64, movement models.StockMovement) error {
+	material, err := s.lock(tx, materialID)
+	if err != nil {
+		return err
+	}
+	if available := material.Stock - material.ReservedStock + reserved; quantity > available+stockTolerance {
+		return fmt.Errorf("Stock insuficiente de %s: disponible %.2f %s, se usaron %.2f", material.Name, available, material.Unit, quantity)
+	}
+	material.Stock = roundQuantity(material.Stock - quantity)
+	material.ReservedStock = math.Max(0, roundQuantity(material.ReservedStock-reserved))
+	if err := tx.Model(&material).Select("stock", "reserved_stock").Updates(&material).Error; err != nil {
+		return err
+	}
+	return s.record(tx, material, -quantity, movement)
+}
+
+// Adjust registra una entrada (cantidad positiva) o salida (negativa) de stock que no
+// corresponde a un proyecto. Una salida no puede tomar stock reservado
+func (s *StockService) Adjust(tx *gorm.DB, materialID uint, quantity float64, movement models.StockMovement) (models.Material, error) {
+	material, err := s.lock(tx, materialID)
+	if err != nil {
+		return material, err
+	}
+	if quantity < 0 && -quantity > material.Stock-material.ReservedStock+stockTolerance {
+		return material, fmt.Errorf("Stock insuficiente: disponible %.2f %s", material.Stock-material.ReservedStock, material.Unit)
+	}
+	return s.SetStock(tx, material, material.Stock+quantity, movement)
+}
+
+// SetStock fija la existencia física del material ya bloqueado (por ejemplo, tras un conteo
+// de inventario) y registra la diferencia. No puede quedar por debajo del stock reservado
+func (s *StockService) SetStock(tx *gorm.DB, material models.Material, stock float64, movement models.StockMovement) (models.Material, error) {
+	stock = roundQuantity(stock)
+	if stock < material.ReservedStock-stockTolerance {
+		return material, fmt.Errorf("El stock no puede ser menor al reservado para proyectos (%.2f %s)", material.ReservedStock, material.Unit)
+	}
+	diff := roundQuantity(stock - material.Stock)
+	if diff == 0 {
+		return material, nil
+	}
+	material.Stock = stock
+	if err := tx.Model(&material).Select("stock").Updates(&material).Error; err != nil {
+		return material, err
+	}
+	material.AvailableStock = material.Stock - material.ReservedStock
+	return material, s.record(tx, material, diff, movement)
+}
+
+// LedgerStock devuelve el stock que resulta de sumar los movimientos del material
+func (s *StockService) LedgerStock(db *gorm.DB, materialID uint) (float64, error) {
+	var stock float64
+	err := db.Model(&models.StockMovement{}).Where("material_id = ?", materialID).
+		Select("COALESCE(SUM(quantity), 0)").Scan(&stock).Error
+	return roundQuantity(stock), err
+}
+
+// Reconcile compara el stock del material con la suma de sus movimientos y, si difieren,
+// registra un ajuste por la diferencia para que el historial cuadre con la existencia.
+// Devuelve la diferencia ajustada
+func (s *StockService) Reconcile(tx *gorm.DB, materialID uint, userID *uint) (float64, error) {
+	material, err := s.lock(tx, materialID)
+	if err != nil {
+		return 0, err
+	}
+	ledger, err := s.LedgerStock(tx, materialID)
+	if err != nil {
+		return 0, err
+	}
+	diff := roundQuantity(material.Stock - ledger)
+	if diff == 0 {
+		return 0, nil
+	}
+	return diff, s.record(tx, material, diff, models.StockMovement{
+		Type:   models.StockMovementAdjust,
+		Reason: "Conciliación con el stock registrado",
+		UserID: userID,
+	})
+}
+
+// Lock bloquea el material dentro de tx hasta el fin de la transacción
+func (s *StockService) Lock(tx *gorm.DB, materialID uint) (models.Material, error) {
+	return s.lock(tx, materialID)
+}
+
+func (s *StockService) lock(tx *gorm.DB, materialID uint) (models.Material, error) {
+	var material models.Material
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&material, materialID).Error; err != nil {
+		return material, fmt.Errorf("Material %d no encontrado", materialID)
+	}
+	return material, nil
+}
+
+// record guarda el movimiento de quantity unidades del material, ya actualizado
+func (s *StockService) record(tx *gorm.DB, material models.Material, quantity float64, movement models.StockMovement) error {
+	movement.ID = 0
+	movement.MaterialID = material.ID
+	movement.Quantity = quantity
+	movement.StockAfter = material.Stock
+	if movement.Type == "" {
+		movement.Type = models.StockMovementAdjust
+	}
+	if movement.UnitCost == 0 {
+		movement.UnitCost = material.UnitPrice
+	}
+	return tx.Omit("Material", "Project", "User").Create(&movement).Error
+}
+
+// roundQuantity redondea una cantidad a dos decimales, la precisión de las columnas
+func roundQuantity(quantity float64) float64 {
+	return math.Round(quantity*100) / 100
+}
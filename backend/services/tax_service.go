@@ -0,0 +1,220 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+// defaultTaxCodes son los códigos que se crean al iniciar si no existen
+var defaultTaxCodes = []models.TaxCode{
+	{Code: "IVA16", Name: "IVA 16%", Components: models.TaxComponents{
+		{Name: "IVA", Type: models.TaxTransferred, Rate: 16},
+	}},
+	{Code: "IVA8", Name: "IVA 8% (región fronteriza)", Components: models.TaxComponents{
+		{Name: "IVA", Type: models.TaxTransferred, Rate: 8},
+	}},
+	{Code: "IVA0", Name: "IVA tasa 0%", Components: models.TaxComponents{
+		{Name: "IVA", Type: models.TaxTransferred, Rate: 0},
+	}},
+	{Code: "EXENTO", Name: "Exento de IVA", Components: models.TaxComponents{
+		{Name: "IVA", Type: models.TaxExempt, Rate: 0},
+	}},
+	{Code: "IVA16_RET", Name: "IVA 16% con retención de ISR 10% e IVA 2/3", Components: models.TaxComponents{
+		{Name: "IVA", Type: models.TaxTransferred, Rate: 16},
+		{Name: "ISR retenido", Type: models.TaxWithheld, Rate: 10},
+		{Name: "IVA retenido", Type: models.TaxWithheld, Rate: 10.6667},
+	}},
+}
+
+// TaxableLine es una línea de un documento para el cálculo de impuestos
+type TaxableLine struct {
+	Amount  models.Money
+	TaxCode string
+}
+
+// TaxResult son los importes de impuestos de un documento
+type TaxResult struct {
+	TaxAmount         models.Money // Impuestos trasladados
+	WithholdingAmount models.Money // Retenciones
+	Total             models.Money
+	Breakdown         models.TaxBreakdown
+}
+
+type TaxService struct{}
+
+func NewTaxService() *TaxService {
+	return &TaxService{}
+}
+
+// EnsureDefaultTaxCodes crea los códigos de impuesto conocidos que aún no existen
+func (s *TaxService) EnsureDefaultTaxCodes(db *gorm.DB) error {
+	for _, defaults := range defaultTaxCodes {
+		code := defaults
+		code.IsActive = true
+		if err := db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).Create(&code).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calculate calcula los impuestos de un documento. Cada línea usa su código de impuesto
+// o, si no tiene, el del documento; sin ninguno de los dos se aplica la tasa única del
+// documento como IVA (documentos anteriores a los códigos de impuesto). Cada impuesto se
+// calcula una sola vez sobre la suma de sus bases y se redondea al centavo. Los importes de
+// las líneas ya deben estar netos de descuentos (ver PricingService); el total es la suma
+// de las líneas + trasladados - retenciones. Los códigos desactivados se siguen aplicando
+// para poder recalcular documentos existentes (ver ValidateCode)
+func (s *TaxService) Calculate(db *gorm.DB, lines []TaxableLine, documentCode string, documentRate float64) (TaxResult, error) {
+	codes := map[string]models.TaxComponents{}
+	index := map[string]int{}
+	var breakdown models.TaxBreakdown
+	var subtotal models.Money
+
+	for _, line := range lines {
+		subtotal += line.Amount
+
+		components, err := s.componentsFor(db, codes, line.TaxCode, documentCode, documentRate)
+		if err != nil {
+			return TaxResult{}, err
+		}
+		for _, component := range components {
+			key := taxKey(component.Type, component.Name, component.Rate)
+			i, ok := index[key]
+			if !ok {
+				i = len(breakdown)
+				index[key] = i
+				breakdown = append(breakdown, models.TaxLine{Name: component.Name, Type: component.Type, Rate: component.Rate})
+			}
+			breakdown[i].Base += line.Amount
+		}
+	}
+
+	result := TaxResult{Breakdown: orderTaxLines(breakdown)}
+	for i := range result.Breakdown {
+		line := &result.Breakdown[i]
+		switch line.Type {
+		case models.TaxTransferred:
+			line.Amount = line.Base.Percent(line.Rate)
+			result.TaxAmount += line.Amount
+		case models.TaxWithheld:
+			line.Amount = line.Base.Percent(line.Rate)
+			result.WithholdingAmount += line.Amount
+		}
+	}
+	result.Total = subtotal + result.TaxAmount - result.WithholdingAmount
+	return result, nil
+}
+
+// ValidateCode verifica que el código exista y esté activo. Un código vacío es válido
+func (s *TaxService) ValidateCode(db *gorm.DB, code string) error {
+	if code == "" {
+		return nil
+	}
+	var taxCode models.TaxCode
+	if err := db.Where("code = ?", code).First(&taxCode).Error; err != nil {
+		return fmt.Errorf("El código de impuesto %s no existe", code)
+	}
+	if !taxCode.IsActive {
+		return fmt.Errorf("El código de impuesto %s no está activo", code)
+	}
+	return nil
+}
+
+// IsReferenced indica si algún documento, plantilla o item usa el código. Los componentes
+// de un código en uso no deben cambiar, porque los documentos se recalculan con ellos
+func (s *TaxService) IsReferenced(db *gorm.DB, code string) (bool, error) {
+	for _, model := range []interface{}{
+		&models.Quote{}, &models.QuoteItem{},
+		&models.Invoice{}, &models.InvoiceItem{},
+		&models.RecurringInvoice{}, &models.RecurringInvoiceItem{},
+		&models.QuoteTemplate{}, &models.QuoteTemplateItem{},
+	} {
+		var count int64
+		if err := db.Unscoped().Model(model).Where("tax_code = ?", code).Limit(1).Count(&count).Error; err != nil {
+			return false, err
+		}
+		if count > 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *TaxService) componentsFor(db *gorm.DB, cache map[string]models.TaxComponents, lineCode, documentCode string, documentRate float64) (models.TaxComponents, error) {
+	code := lineCode
+	if code == "" {
+		code = documentCode
+	}
+	if code == "" {
+		if documentRate == 0 {
+			return nil, nil
+		}
+		return models.TaxComponents{{Name: "IVA", Type: models.TaxTransferred, Rate: documentRate}}, nil
+	}
+
+	if components, ok := cache[code]; ok {
+		return components, nil
+	}
+	var taxCode models.TaxCode
+	if err := db.Where("code = ?", code).First(&taxCode).Error; err != nil {
+		return nil, fmt.Errorf("El código de impuesto %s no existe", code)
+	}
+	cache[code] = taxCode.Components
+	return taxCode.Components, nil
+}
+
+// SubtractTaxBreakdown resta del desglose los importes de otro desglose, impuesto por
+// impuesto (por ejemplo, lo ya acreditado en notas de crédito anteriores)
+func SubtractTaxBreakdown(breakdown, minus models.TaxBreakdown) models.TaxBreakdown {
+	result := make(models.TaxBreakdown, len(breakdown))
+	copy(result, breakdown)
+	index := make(map[string]int, len(result))
+	for i, line := range result {
+		index[taxKey(line.Type, line.Name, line.Rate)] = i
+	}
+	for _, line := range minus {
+		key := taxKey(line.Type, line.Name, line.Rate)
+		i, ok := index[key]
+		if !ok {
+			i = len(result)
+			index[key] = i
+			result = append(result, models.TaxLine{Name: line.Name, Type: line.Type, Rate: line.Rate})
+		}
+		result[i].Base -= line.Base
+		result[i].Amount -= line.Amount
+	}
+	return orderTaxLines(result)
+}
+
+// taxKey identifica un impuesto dentro de un desglose
+func taxKey(taxType, name string, rate float64) string {
+	return fmt.Sprintf("%s|%s|%g", taxType, name, rate)
+}
+
+// orderTaxLines deja primero los trasladados, luego las retenciones y al final los exentos,
+// conservando el orden de aparición dentro de cada grupo
+func orderTaxLines(lines models.TaxBreakdown) models.TaxBreakdown {
+	ordered := make(models.TaxBreakdown, 0, len(lines))
+	for _, taxType := range []string{models.TaxTransferred, models.TaxWithheld, models.TaxExempt} {
+		for _, line := range lines {
+			if line.Type == taxType {
+				ordered = append(ordered, line)
+			}
+		}
+	}
+	return ordered
+}
+
+// TaxLineLabel arma la etiqueta visible de un impuesto: "IVA 16%", "ISR retenido 10%"
+func TaxLineLabel(line models.TaxLine) string {
+	if line.Type == models.TaxExempt {
+		return line.Name + " exento"
+	}
+	rate := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.4f", line.Rate), "0"), ".")
+	return fmt.Sprintf("%s %s%%", line.Name, rate)
+}
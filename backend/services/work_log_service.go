@@ -0,0 +1,160 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"raborimet-crm/backend/models"
+)
+
+// maxWorkLogHours es la duración máxima de un registro de horas
+const maxWorkLogHours = 24
+
+// TimesheetDay son las horas de un empleado en un día de la semana
+type TimesheetDay struct {
+	Date     string           `json:"date"`
+	Hours    float64          `json:"hours"`
+	Cost     models.Money     `json:"cost"`
+	WorkLogs []models.WorkLog `json:"work_logs"`
+}
+
+// TimesheetProject son las horas de un empleado en un proyecto durante la semana
+type TimesheetProject struct {
+	ProjectID   uint         `json:"project_id"`
+	ProjectName string       `json:"project_name"`
+	Hours       float64      `json:"hours"`
+	Cost        models.Money `json:"cost"`
+}
+
+// WeeklyTimesheet es la hoja de horas semanal (de lunes a domingo) de un empleado
+type WeeklyTimesheet struct {
+	UserID     uint               `json:"user_id"`
+	WeekStart  string             `json:"week_start"`
+	WeekEnd    string             `json:"week_end"`
+	Days       []TimesheetDay     `json:"days"`
+	Projects   []TimesheetProject `json:"projects"`
+	TotalHours float64            `json:"total_hours"`
+	TotalCost  models.Money       `json:"total_cost"`
+}
+
+// WorkLogService calcula las horas y el costo de mano de obra de los registros de horas y
+// evita que un empleado registre dos trabajos en el mismo horario
+type WorkLogService struct{}
+
+func NewWorkLogService() *WorkLogService {
+	return &WorkLogService{}
+}
+
+// DefaultHourlyRate devuelve el costo por hora del tipo de trabajo según el catálogo de
+// tarifas; cero si no hay una tarifa activa
+func (s *WorkLogService) DefaultHourlyRate(db *gorm.DB, workType string) models.Money {
+	var rate models.LaborRate
+	if err := db.Where("work_type = ? AND is_active = ?", workType, true).First(&rate).Error; err != nil {
+		return 0
+	}
+	return rate.HourlyCost
+}
+
+// Price calcula el día, las horas (de StartTime a EndTime) y el costo total del registro.
+// No guarda el registro
+func (s *WorkLogService) Price(workLog *models.WorkLog) error {
+	if !workLog.EndTime.After(workLog.StartTime) {
+		return errors.New("La hora de fin debe ser posterior a la de inicio")
+	}
+	duration := workLog.EndTime.Sub(workLog.StartTime)
+	if duration > maxWorkLogHours*time.Hour {
+		return fmt.Errorf("Un registro no puede superar las %d horas", maxWorkLogHours)
+	}
+
+	start := workLog.StartTime
+	workLog.Date = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, start.Location())
+	workLog.Hours = roundQuantity(duration.Hours())
+	workLog.TotalCost = models.LineTotal(workLog.Hours, workLog.HourlyRate)
+	return nil
+}
+
+// CheckOverlap verifica que el empleado no tenga otro registro que se cruce con el horario
+// del registro. Bloquea al usuario en tx para que dos registros simultáneos no puedan
+// cruzarse; debe llamarse dentro de la transacción que guarda el registro
+func (s *WorkLogService) CheckOverlap(tx *gorm.DB, workLog *models.WorkLog) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, workLog.UserID).Error; err != nil {
+		return errors.New("Usuario no encontrado")
+	}
+
+	var overlapping models.WorkLog
+	err := tx.Preload("Project").
+		Where("user_id = ? AND id != ? AND start_time < ? AND end_time > ?", workLog.UserID, workLog.ID, workLog.EndTime, workLog.StartTime).
+		Order("start_time ASC").First(&overlapping).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("El horario se cruza con otro registro del %s de %s a %s en el proyecto %s",
+		overlapping.Date.Format("2006-01-02"), overlapping.StartTime.Format("15:04"), overlapping.EndTime.Format("15:04"), overlapping.Project.Name)
+}
+
+// WeekStart devuelve el lunes de la semana del día indicado
+func WeekStart(day time.Time) time.Time {
+	day = time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	offset := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -offset)
+}
+
+// Timesheet arma la hoja de horas del empleado para la semana que contiene el día indicado,
+// con las horas y el costo por día y por proyecto
+func (s *WorkLogService) Timesheet(db *gorm.DB, userID uint, day time.Time) (WeeklyTimesheet, error) {
+	start := WeekStart(day)
+	end := start.AddDate(0, 0, 7)
+
+	var workLogs []models.WorkLog
+	if err := db.Preload("Project").
+		Where("user_id = ? AND date >= ? AND date < ?", userID, start, end).
+		Order("start_time ASC").Find(&workLogs).Error; err != nil {
+		return WeeklyTimesheet{}, err
+	}
+
+	timesheet := WeeklyTimesheet{
+		UserID:    userID,
+		WeekStart: start.Format("2006-01-02"),
+		WeekEnd:   end.AddDate(0, 0, -1).Format("2006-01-02"),
+		Days:      make([]TimesheetDay, 7),
+		Projects:  []TimesheetProject{},
+	}
+	days := make(map[string]int)
+	for i := range timesheet.Days {
+		date := start.AddDate(0, 0, i).Format("2006-01-02")
+		timesheet.Days[i] = TimesheetDay{Date: date, WorkLogs: []models.WorkLog{}}
+		days[date] = i
+	}
+
+	projects := make(map[uint]int)
+	for _, workLog := range workLogs {
+		index, ok := days[workLog.Date.Format("2006-01-02")]
+		if !ok {
+			continue
+		}
+		daily := &timesheet.Days[index]
+		daily.Hours = roundQuantity(daily.Hours + workLog.Hours)
+		daily.Cost += workLog.TotalCost
+		daily.WorkLogs = append(daily.WorkLogs, workLog)
+
+		i, ok := projects[workLog.ProjectID]
+		if !ok {
+			i = len(timesheet.Projects)
+			projects[workLog.ProjectID] = i
+			timesheet.Projects = append(timesheet.Projects, TimesheetProject{ProjectID: workLog.ProjectID, ProjectName: workLog.Project.Name})
+		}
+		timesheet.Projects[i].Hours = roundQuantity(timesheet.Projects[i].Hours + workLog.Hours)
+		timesheet.Projects[i].Cost += workLog.TotalCost
+
+		timesheet.TotalHours = roundQuantity(timesheet.TotalHours + workLog.Hours)
+		timesheet.TotalCost += workLog.TotalCost
+	}
+	return timesheet, nil
+}